@@ -1,11 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/shaibs3/Torq/internal/app"
+	"github.com/shaibs3/Torq/internal/cli"
 	"github.com/shaibs3/Torq/internal/config"
 	"github.com/shaibs3/Torq/internal/logger"
+	"github.com/shaibs3/Torq/internal/telemetry"
 	"go.uber.org/zap"
 )
 
@@ -16,6 +23,21 @@ var (
 )
 
 func main() {
+	args := os.Args[1:]
+	if !cli.IsServe(args) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		code := cli.Run(ctx, args, cli.Streams{In: os.Stdin, Out: os.Stdout, Err: os.Stderr})
+		stop()
+		os.Exit(code)
+	}
+	if len(args) > 0 && args[0] == "serve" {
+		args = args[1:]
+	}
+	serve(args)
+}
+
+// serve starts the servers and blocks until they shut down
+func serve(args []string) {
 	// Initialize logger first (for configuration loading)
 	initialLogger, err := logger.NewLogger("production", "info")
 	if err != nil {
@@ -26,10 +48,17 @@ func main() {
 	}()
 
 	// Load configuration
-	cfg := config.Load(initialLogger)
+	cfg, err := config.Load(args, initialLogger)
+	if err != nil {
+		var cfgErr *config.Error
+		if errors.As(err, &cfgErr) {
+			initialLogger.Fatal("invalid configuration", zap.Errors("problems", cfgErr.Problems))
+		}
+		initialLogger.Fatal("failed to load configuration", zap.Error(err))
+	}
 
 	// Create application logger with proper configuration
-	appLogger, err := logger.NewLogger(cfg.Environment, cfg.LogLevel)
+	appLogger, logLevel, err := logger.NewAtomicLogger(cfg.Environment, cfg.LogLevel)
 	if err != nil {
 		initialLogger.Fatal("failed to create application logger", zap.Error(err))
 	}
@@ -44,7 +73,8 @@ func main() {
 		zap.String("date", date),
 	)
 
-	application, err := app.NewApp(cfg, appLogger)
+	build := telemetry.BuildInfo{Version: version, Commit: commit}
+	application, err := app.NewApp(cfg, build, appLogger, logLevel)
 	if err != nil {
 		appLogger.Fatal("failed to create application", zap.Error(err))
 	}
@@ -5,8 +5,8 @@
 
 set -e
 
-# Accept port as first argument, fallback to env or default
-PORT=${1:-${PORT:-8080}}
+# Accept the ops port as first argument, fallback to env or default
+PORT=${1:-${OPS_PORT:-8081}}
 HOST=${HOST:-localhost}
 TIMEOUT=${TIMEOUT:-3}
 
@@ -3,7 +3,10 @@ package app
 import (
 	"context"
 	"errors"
+	"fmt"
+	"github.com/shaibs3/Torq/internal/grpcapi"
 	"github.com/shaibs3/Torq/internal/router"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,63 +15,335 @@ import (
 
 	"github.com/shaibs3/Torq/internal/limiter"
 
+	"github.com/shaibs3/Torq/internal/auth"
+	"github.com/shaibs3/Torq/internal/certs"
 	"github.com/shaibs3/Torq/internal/config"
 	"github.com/shaibs3/Torq/internal/finder"
 	"github.com/shaibs3/Torq/internal/lookup"
+	"github.com/shaibs3/Torq/internal/override"
+	"github.com/shaibs3/Torq/internal/quota"
 	"github.com/shaibs3/Torq/internal/telemetry"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 // App represents the main application
 type App struct {
-	config    *config.Config
-	logger    *zap.Logger
-	telemetry *telemetry.Telemetry
-	server    *http.Server
+	config     *config.Config
+	logger     *zap.Logger
+	telemetry  *telemetry.Telemetry
+	server     *http.Server
+	ops        *http.Server
+	admin      *http.Server
+	grpc       *grpcapi.Server
+	limiters   *limiter.Factory
+	quotas     *quota.Manager
+	auth       *auth.Authenticator
+	jwt        *auth.JWTValidator
+	tls        *certs.Reloader
+	provider   *lookup.SwappableProvider
+	controller *controller
 }
 
-func NewApp(cfg *config.Config, logger *zap.Logger) (*App, error) {
+// NewApp wires the servers from cfg. logLevel is the level of logger, which a
+// configuration reload may change.
+func NewApp(cfg *config.Config, build telemetry.BuildInfo, logger *zap.Logger, logLevel zap.AtomicLevel) (*App, error) {
 	// Initialize telemetry
-	tel, err := telemetry.NewTelemetry(logger)
+	metricsExporters, err := telemetry.ParseMetricsExporters(cfg.MetricsExporters)
+	if err != nil {
+		return nil, fmt.Errorf("invalid METRICS_EXPORTERS: %w", err)
+	}
+	metricsInterval, err := time.ParseDuration(cfg.MetricsInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid METRICS_EXPORT_INTERVAL: %w", err)
+	}
+	tel, err := telemetry.NewTelemetry(logger, telemetry.Config{
+		Build:       build,
+		Environment: cfg.Environment,
+		InstanceID:  cfg.InstanceID,
+		Metrics: telemetry.MetricsConfig{
+			Exporters: metricsExporters,
+			Endpoint:  cfg.MetricsEndpoint,
+			Insecure:  cfg.MetricsInsecure,
+			Interval:  metricsInterval,
+		},
+		Tracing: telemetry.TracingConfig{
+			Exporter:    telemetry.TraceExporter(cfg.TracingExporter),
+			Endpoint:    cfg.TracingEndpoint,
+			Insecure:    cfg.TracingInsecure,
+			SampleRatio: cfg.TracingSampling,
+		},
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	// Initialize IP DB provider
 	dbProviderFactory := lookup.NewDbProviderFactory(logger, tel)
-	dbProvider, err := dbProviderFactory.CreateProvider(cfg.IPDBConfig)
+	initialProvider, err := dbProviderFactory.CreateProvider(cfg.ProviderJSON())
 	if err != nil {
 		return nil, err
 	}
+	// Reloads swap in a new provider without restarting the servers
+	dbProvider := lookup.NewSwappableProvider(initialProvider)
 	logger.Info("database provider initialized")
 
+	// Initialize the lookup cache (optional)
+	var lookupProvider lookup.DbProvider = dbProvider
+	var lookupCache *lookup.CachedProvider
+	if cfg.CacheSize > 0 {
+		cacheTTL, err := time.ParseDuration(cfg.CacheTTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LOOKUP_CACHE_TTL: %w", err)
+		}
+		lookupCache = lookup.NewCachedProvider(dbProvider, cfg.CacheSize, cacheTTL)
+		lookupProvider = lookupCache
+		logger.Info("lookup cache enabled", zap.Int("size", cfg.CacheSize), zap.Duration("ttl", cacheTTL))
+	}
+
+	// Initialize local overrides (optional); they are consulted before the cache
+	var overrides *override.Provider
+	if cfg.OverridesFile != "" {
+		overrides, err = override.NewProvider(lookupProvider, cfg.OverridesFile, cfg.OverridesAuditFile, logger)
+		if err != nil {
+			return nil, err
+		}
+		lookupProvider = overrides
+	}
+
 	// Initialize router
-	rateLimiter := limiter.NewBurstRateLimiter(cfg.RPSLimit, cfg.RPSBurst, logger)
-	ipFinder := finder.NewIpFinder(dbProvider)
-	appRouter := router.NewRouter(rateLimiter, tel, logger)
+	limiterFactory, err := limiter.NewFactory(limiter.FactoryConfig{
+		Backend:       limiter.Backend(cfg.RateLimitBackend),
+		RedisAddr:     cfg.RedisAddr,
+		RedisPassword: cfg.RedisPassword.Value(),
+		RedisDB:       cfg.RedisDB,
+		KeyPrefix:     cfg.RedisKeyPrefix,
+		FailureMode:   limiter.FailureMode(cfg.RateLimitFailMode),
+	}, logger)
+	if err != nil {
+		return nil, err
+	}
+	window, err := time.ParseDuration(cfg.RateLimitWindow)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_WINDOW: %w", err)
+	}
+	// The sliding window algorithms admit RPS_LIMIT requests per RATE_LIMIT_WINDOW
+	defaultSpec := limiter.LimitSpec{
+		Name:      limiter.DefaultPolicyName,
+		Algorithm: limiter.Algorithm(cfg.RateLimitAlgo),
+		RPS:       cfg.RPSLimit,
+		Burst:     cfg.RPSBurst,
+		Limit:     cfg.RPSLimit,
+		Window:    window,
+	}
+	rateLimiter, err := limiterFactory.New(defaultSpec)
+	if err != nil {
+		return nil, err
+	}
+	policyConfigs, err := limiter.ParsePolicyConfigs(cfg.RateLimitPolicies)
+	if err != nil {
+		return nil, err
+	}
+	policies, err := limiter.BuildPolicies(policyConfigs, router.CostFuncs(), limiterFactory, logger)
+	if err != nil {
+		return nil, err
+	}
+	policySet := limiter.NewPolicySet(append(policies, limiter.NewDefaultPolicy(rateLimiter))...)
+
+	accessLogFormat := router.AccessLogFormat(cfg.AccessLogFormat)
+	if !accessLogFormat.IsValid() {
+		return nil, fmt.Errorf("invalid ACCESS_LOG_FORMAT %q: must be structured, combined or off", cfg.AccessLogFormat)
+	}
+	controller := newController(cfg, logLevel, defaultSpec, rateLimiter, policySet, dbProvider, lookupCache, dbProviderFactory, tel.Meter, logger)
+	routerOpts := []router.Option{
+		router.WithAccessLog(accessLogFormat),
+		router.WithController(controller),
+	}
+	if overrides != nil {
+		routerOpts = append(routerOpts, router.WithOverrides(overrides))
+	}
+
+	// Initialize quotas (optional)
+	quotaConfig, err := quota.ParseConfig(cfg.QuotaConfig)
+	if err != nil {
+		return nil, err
+	}
+	var quotas *quota.Manager
+	if quotaConfig != nil {
+		quotaStore, err := quota.NewStore(quotaConfig.Store, logger)
+		if err != nil {
+			return nil, err
+		}
+		quotas = quota.NewManager(*quotaConfig, quotaStore, logger)
+		routerOpts = append(routerOpts, router.WithQuotas(quotas))
+		logger.Info("quotas enabled",
+			zap.String("store", quotaConfig.Store.Type),
+			zap.Int64("default_daily", quotaConfig.Default.Daily),
+			zap.Int64("default_monthly", quotaConfig.Default.Monthly),
+			zap.Int("overrides", len(quotaConfig.Overrides)))
+	}
+
+	// Initialize API key and JWT authentication (optional)
+	authConfig, err := auth.ParseConfig(cfg.AuthConfig)
+	if err != nil {
+		return nil, err
+	}
+	var authenticator *auth.Authenticator
+	var jwtValidator *auth.JWTValidator
+	if authConfig != nil && authConfig.APIKeysEnabled() {
+		reloadInterval, err := authConfig.Interval()
+		if err != nil {
+			return nil, err
+		}
+		keyStore, err := auth.NewKeyStore(authConfig.Store, logger)
+		if err != nil {
+			return nil, err
+		}
+		authenticator, err = auth.NewAuthenticator(keyStore, logger)
+		if err != nil {
+			return nil, err
+		}
+		authenticator.Start(reloadInterval)
+		routerOpts = append(routerOpts, router.WithAuth(authenticator))
+		logger.Info("API key authentication enabled",
+			zap.String("store", authConfig.Store.Type),
+			zap.Duration("reload_interval", reloadInterval))
+	}
+	if authConfig != nil && authConfig.JWT != nil {
+		jwtValidator, err = auth.NewJWTValidator(*authConfig.JWT, logger)
+		if err != nil {
+			return nil, err
+		}
+		routerOpts = append(routerOpts, router.WithJWT(jwtValidator))
+		logger.Info("JWT authentication enabled")
+	}
+
+	ipFinder := finder.NewIpFinder(lookupProvider)
+	appRouter := router.NewRouter(policySet, tel, logger, routerOpts...)
 	server := appRouter.CreateServer(":"+cfg.Port, ipFinder)
+	opsServer := appRouter.CreateOpsServer(":" + cfg.OpsPort)
+	adminServer := appRouter.CreateAdminServer(":" + cfg.AdminPort)
+
+	// Initialize TLS (optional), shared by the HTTP and gRPC servers
+	var grpcOpts []grpc.ServerOption
+	tlsReloader, err := newTLSReloader(cfg, tel, logger)
+	if err != nil {
+		return nil, err
+	}
+	if tlsReloader != nil {
+		// The ops server stays plain HTTP for probes and scrapers inside the cluster
+		server.TLSConfig = tlsReloader.TLSConfig()
+		adminServer.TLSConfig = tlsReloader.TLSConfig()
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(tlsReloader.TLSConfig())))
+		tlsReloader.Start()
+	}
+
+	// Initialize gRPC server, guarded by the same authentication, rate limit policies and quotas as HTTP
+	grpcServer := grpcapi.NewServer(ipFinder, grpcapi.Access{Policies: policySet, Auth: authenticator, JWT: jwtValidator, Quotas: quotas}, tel, logger, grpcOpts...)
 
 	return &App{
-		config:    cfg,
-		logger:    logger,
-		telemetry: tel,
-		server:    server,
+		config:     cfg,
+		logger:     logger,
+		telemetry:  tel,
+		server:     server,
+		ops:        opsServer,
+		admin:      adminServer,
+		grpc:       grpcServer,
+		limiters:   limiterFactory,
+		quotas:     quotas,
+		auth:       authenticator,
+		jwt:        jwtValidator,
+		tls:        tlsReloader,
+		provider:   dbProvider,
+		controller: controller,
 	}, nil
 }
 
-// Start starts the application server
+// newTLSReloader loads the configured server certificate, or returns nil when TLS is disabled
+func newTLSReloader(cfg *config.Config, tel *telemetry.Telemetry, logger *zap.Logger) (*certs.Reloader, error) {
+	tlsConfig := certs.Config{
+		CertFile:     cfg.TLSCertFile,
+		KeyFile:      cfg.TLSKeyFile,
+		ClientCAFile: cfg.TLSClientCAFile,
+		ClientAuth:   certs.ClientAuth(cfg.TLSClientAuth),
+	}
+	if !tlsConfig.Enabled() {
+		if tlsConfig.ClientCAFile != "" {
+			return nil, fmt.Errorf("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		return nil, nil
+	}
+	interval, err := time.ParseDuration(cfg.TLSReloadInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS_RELOAD_INTERVAL: %w", err)
+	}
+	tlsConfig.ReloadInterval = interval
+
+	reloader, err := certs.NewReloader(tlsConfig, tel, logger)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("TLS enabled",
+		zap.String("cert_file", tlsConfig.CertFile),
+		zap.String("client_auth", string(reloader.ClientAuth())),
+		zap.Duration("reload_interval", interval))
+	return reloader, nil
+}
+
+// Start starts the public, ops, admin and gRPC servers
 func (app *App) start() error {
 	app.logger.Info("starting server", zap.String("port", app.config.Port))
+	if err := app.serveHTTP("public", app.server); err != nil {
+		return err
+	}
+	app.logger.Info("starting ops server", zap.String("port", app.config.OpsPort))
+	if err := app.serveHTTP("ops", app.ops); err != nil {
+		return err
+	}
+	app.logger.Info("starting admin server", zap.String("port", app.config.AdminPort))
+	if err := app.serveHTTP("admin", app.admin); err != nil {
+		return err
+	}
+
+	app.logger.Info("starting gRPC server", zap.String("port", app.config.GRPCPort))
+	lis, err := net.Listen("tcp", ":"+app.config.GRPCPort)
+	if err != nil {
+		return err
+	}
 
 	go func() {
-		if err := app.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			app.logger.Fatal("server failed to start", zap.Error(err))
+		if err := app.grpc.Serve(lis); err != nil {
+			app.logger.Fatal("gRPC server failed to start", zap.Error(err))
 		}
 	}()
 
 	return nil
 }
 
+// serveHTTP binds the server's address and serves it in the background, over TLS
+// when the server has a TLS configuration
+func (app *App) serveHTTP(name string, srv *http.Server) error {
+	lis, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for %s server: %w", name, err)
+	}
+
+	go func() {
+		var err error
+		if srv.TLSConfig != nil {
+			// Certificates come from TLSConfig, so no files are passed here
+			err = srv.ServeTLS(lis, "", "")
+		} else {
+			err = srv.Serve(lis)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			app.logger.Fatal("server failed to start", zap.String("server", name), zap.Error(err))
+		}
+	}()
+	return nil
+}
+
 // Stop gracefully shuts down the application
 func (app *App) stop() error {
 	app.logger.Info("shutting down server...")
@@ -77,16 +352,67 @@ func (app *App) stop() error {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := app.server.Shutdown(shutdownCtx); err != nil {
-		app.logger.Error("server forced to shutdown", zap.Error(err))
-		return err
+	if err := app.grpc.Stop(shutdownCtx); err != nil {
+		app.logger.Error("gRPC server forced to shutdown", zap.Error(err))
+	}
+
+	// The ops server goes last so health checks and scrapes work while the others drain
+	var shutdownErr error
+	for _, srv := range []struct {
+		name   string
+		server *http.Server
+	}{{"public", app.server}, {"admin", app.admin}, {"ops", app.ops}} {
+		if err := srv.server.Shutdown(shutdownCtx); err != nil {
+			app.logger.Error("server forced to shutdown", zap.String("server", srv.name), zap.Error(err))
+			if shutdownErr == nil {
+				shutdownErr = err
+			}
+		}
+	}
+
+	if app.quotas != nil {
+		if err := app.quotas.Close(); err != nil {
+			app.logger.Error("failed to persist quota usage", zap.Error(err))
+		}
+	}
+
+	if app.auth != nil {
+		if err := app.auth.Close(); err != nil {
+			app.logger.Warn("failed to close API key store", zap.Error(err))
+		}
+	}
+
+	if app.jwt != nil {
+		_ = app.jwt.Close()
+	}
+
+	if app.tls != nil {
+		_ = app.tls.Close()
+	}
+
+	if err := app.provider.Close(); err != nil {
+		app.logger.Warn("failed to close database provider", zap.Error(err))
+	}
+
+	if err := app.limiters.Close(); err != nil {
+		app.logger.Warn("failed to close rate limit store", zap.Error(err))
+	}
+
+	// Metrics and spans from the drained requests are flushed last
+	if err := app.telemetry.Shutdown(shutdownCtx); err != nil {
+		app.logger.Warn("failed to flush telemetry", zap.Error(err))
+	}
+
+	if shutdownErr != nil {
+		return shutdownErr
 	}
 
 	app.logger.Info("server exited gracefully")
 	return nil
 }
 
-// Run starts the application and waits for shutdown signals
+// Run starts the application, reloads the configuration on SIGHUP and waits for
+// shutdown signals
 func (app *App) Run() error {
 	// Start the server
 	if err := app.start(); err != nil {
@@ -97,9 +423,18 @@ func (app *App) Run() error {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	// Wait for shutdown signal
-	<-ctx.Done()
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
 
-	// Stop the application
-	return app.stop()
+	for {
+		select {
+		case <-hangup:
+			// Failures are logged and counted; the previous configuration stays in effect
+			_, _ = app.controller.reload(ctx, reloadTriggerSignal)
+		case <-ctx.Done():
+			// Stop the application
+			return app.stop()
+		}
+	}
 }
@@ -0,0 +1,300 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shaibs3/Torq/internal/config"
+	"github.com/shaibs3/Torq/internal/limiter"
+	"github.com/shaibs3/Torq/internal/lookup"
+	"github.com/shaibs3/Torq/internal/router"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Reload triggers, recorded on config_reloads_total
+const (
+	reloadTriggerSignal = "signal"
+	reloadTriggerAdmin  = "admin"
+)
+
+// reloadable lists the settings applied without a restart
+var reloadable = map[string]bool{
+	"LOG_LEVEL":                       true,
+	"RPS_LIMIT":                       true,
+	"RPS_BURST":                       true,
+	"RATE_LIMIT_WINDOW":               true,
+	"PROVIDER_TYPE":                   true,
+	"PROVIDER_CSV_FILE_PATH":          true,
+	"PROVIDER_POSTGRES_CONN_STR":      true,
+	"PROVIDER_POSTGRES_CONN_STR_FILE": true,
+	"IP_DB_CONFIG":                    true,
+}
+
+// policiesSetting is applied when only the rates of its policies changed, and needs a
+// restart otherwise
+const policiesSetting = "RATE_LIMIT_POLICIES"
+
+// noConfigFileNote tells a reload without a config file that little could change: the
+// environment and flags are those the process started with
+const noConfigFileNote = "no config file is in use; only *_FILE secrets were re-read"
+
+// controller changes the running instance on SIGHUP and on behalf of the admin API.
+// A reload re-reads the configuration and applies what can change safely: the log
+// level, the rates of the default limit and of RATE_LIMIT_POLICIES, and the IP
+// database provider.
+type controller struct {
+	// mu serialises changes and guards applied and rateLimit
+	mu sync.Mutex
+	// started is the configuration the process started with; applied is the last one reloaded
+	started   *config.Config
+	applied   *config.Config
+	rateLimit limiter.LimitSpec
+
+	level       zap.AtomicLevel
+	rateLimiter limiter.RateLimiter
+	policies    *limiter.PolicySet
+	provider    *lookup.SwappableProvider
+	// cache is nil when the lookup cache is disabled
+	cache   *lookup.CachedProvider
+	factory lookup.ProviderFactory
+	reloads metric.Int64Counter
+	logger  *zap.Logger
+}
+
+func newController(cfg *config.Config, level zap.AtomicLevel, rateLimit limiter.LimitSpec, rateLimiter limiter.RateLimiter,
+	policies *limiter.PolicySet, provider *lookup.SwappableProvider, cache *lookup.CachedProvider, factory lookup.ProviderFactory, meter metric.Meter, logger *zap.Logger) *controller {
+	logger = logger.Named("controller")
+	reloads, err := meter.Int64Counter(
+		"config_reloads_total",
+		metric.WithDescription("Total number of configuration reloads by trigger and result"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		logger.Error("failed to create config reloads metric", zap.Error(err))
+	}
+	return &controller{
+		started:     cfg,
+		applied:     cfg,
+		rateLimit:   rateLimit,
+		level:       level,
+		rateLimiter: rateLimiter,
+		policies:    policies,
+		provider:    provider,
+		cache:       cache,
+		factory:     factory,
+		reloads:     reloads,
+		logger:      logger,
+	}
+}
+
+// Reload serves the admin API; SIGHUP calls reload directly with the signal trigger
+func (c *controller) Reload(ctx context.Context) (*router.ReloadResult, error) {
+	return c.reload(ctx, reloadTriggerAdmin)
+}
+
+// reload applies the current configuration. On failure nothing changes.
+func (c *controller) reload(ctx context.Context, trigger string) (*router.ReloadResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	start := time.Now()
+	c.logger.Info("reloading configuration", zap.String("trigger", trigger))
+	result, err := c.apply()
+
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+	if c.reloads != nil {
+		c.reloads.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("trigger", trigger),
+			attribute.String("result", outcome)))
+	}
+
+	if err != nil {
+		c.logger.Error("configuration reload failed, keeping the previous configuration",
+			zap.String("trigger", trigger),
+			zap.Duration("duration", time.Since(start)),
+			zap.Error(err))
+		return nil, err
+	}
+	c.logger.Info("configuration reloaded",
+		zap.String("trigger", trigger),
+		zap.Duration("duration", time.Since(start)),
+		zap.Strings("applied", result.Applied),
+		zap.Strings("restart_required", result.RestartRequired))
+	return result, nil
+}
+
+func (c *controller) apply() (*router.ReloadResult, error) {
+	next, err := c.applied.Reload(c.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	level, err := zapcore.ParseLevel(next.LogLevel)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOG_LEVEL: %w", err)
+	}
+	window, err := time.ParseDuration(next.RateLimitWindow)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_WINDOW: %w", err)
+	}
+	// The algorithm cannot change in place, so the limiter keeps the one it started with
+	spec := c.rateLimit
+	spec.RPS = next.RPSLimit
+	spec.Burst = next.RPSBurst
+	spec.Limit = next.RPSLimit
+	spec.Window = window
+	if err := limiter.CheckReconfigure(c.rateLimiter, spec); err != nil {
+		return nil, fmt.Errorf("invalid default rate limit: %w", err)
+	}
+	policyConfigs, err := limiter.ParsePolicyConfigs(next.RateLimitPolicies)
+	if err != nil {
+		return nil, err
+	}
+	// Policies whose rates alone changed are reconfigured in place; added, removed or
+	// otherwise changed policies keep running as they are until a restart
+	applyPolicies, err := c.policies.PlanReconfigure(policyConfigs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate limit policies: %w", err)
+	}
+
+	// The provider is rebuilt even when its settings are unchanged, which picks up new
+	// data and rotated credentials. Providers load their data or ping their database when
+	// created, so the new one is ready before it receives lookups.
+	provider, err := c.factory.CreateProvider(next.ProviderJSON())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+
+	// Everything has been checked, so from here on the changes are applied together
+	if applyPolicies != nil {
+		if err := applyPolicies(); err != nil {
+			c.logger.Error("failed to apply rate limit policies", zap.Error(err))
+		}
+	}
+	if err := limiter.Reconfigure(c.rateLimiter, spec); err != nil {
+		c.logger.Error("failed to apply default rate limit", zap.Error(err))
+	}
+	c.rateLimit = spec
+	// Swap returns once the lookups in flight on the previous provider have finished
+	if err := lookup.CloseProvider(c.provider.Swap(provider)); err != nil {
+		c.logger.Warn("failed to close the previous provider", zap.Error(err))
+	}
+	// Cached results may come from the previous data
+	if c.cache != nil {
+		c.cache.Purge()
+	}
+	c.level.SetLevel(level)
+
+	result := &router.ReloadResult{ConfigFile: next.ConfigFile, Applied: []string{}, RestartRequired: []string{}}
+	if next.ConfigFile == "" {
+		result.Note = noConfigFileNote
+	}
+	for _, name := range config.Changed(c.applied, next) {
+		if reloadable[name] || (name == policiesSetting && applyPolicies != nil) {
+			result.Applied = append(result.Applied, name)
+		}
+	}
+	for _, name := range config.Changed(c.started, next) {
+		if !reloadable[name] && (name != policiesSetting || applyPolicies == nil) {
+			result.RestartRequired = append(result.RestartRequired, name)
+		}
+	}
+	c.applied = next
+	return result, nil
+}
+
+func (c *controller) Dataset(ctx context.Context) (lookup.Dataset, error) {
+	return lookup.DescribeDataset(ctx, c.provider)
+}
+
+func (c *controller) PurgeCache() router.CachePurgeResult {
+	if c.cache == nil {
+		return router.CachePurgeResult{}
+	}
+	return router.CachePurgeResult{Enabled: true, Purged: c.cache.Purge()}
+}
+
+// EffectiveConfig is the last loaded configuration with the runtime changes made
+// through the admin API
+func (c *controller) EffectiveConfig() map[string]any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	effective := c.applied.Effective()
+	effective["LOG_LEVEL"] = c.level.Level().String()
+	effective["RPS_LIMIT"] = c.rateLimit.RPS
+	effective["RPS_BURST"] = c.rateLimit.Burst
+	effective["RATE_LIMIT_WINDOW"] = c.rateLimit.Window.String()
+	return effective
+}
+
+func (c *controller) LogLevel() string {
+	return c.level.Level().String()
+}
+
+// SetLogLevel changes the log level until the next reload or restart
+func (c *controller) SetLogLevel(level string) error {
+	parsed, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("%w: unknown log level %q", router.ErrInvalidSetting, level)
+	}
+	previous := c.level.Level()
+	c.level.SetLevel(parsed)
+	c.logger.Info("log level changed", zap.Stringer("from", previous), zap.Stringer("to", parsed))
+	return nil
+}
+
+func (c *controller) RateLimit() router.RateLimitParams {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	algorithm := c.rateLimit.Algorithm
+	if algorithm == "" {
+		algorithm = limiter.AlgorithmTokenBucket
+	}
+	return router.RateLimitParams{
+		Algorithm: string(algorithm),
+		RPS:       c.rateLimit.RPS,
+		Burst:     c.rateLimit.Burst,
+		Limit:     c.rateLimit.Limit,
+		Window:    c.rateLimit.Window.String(),
+	}
+}
+
+// SetRateLimit changes the default rate limit in place until the next reload or restart
+func (c *controller) SetRateLimit(params router.RateLimitParams) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	window, err := time.ParseDuration(params.Window)
+	if err != nil {
+		return fmt.Errorf("%w: invalid window %q", router.ErrInvalidSetting, params.Window)
+	}
+	spec := limiter.LimitSpec{
+		Name:      c.rateLimit.Name,
+		Algorithm: limiter.Algorithm(params.Algorithm),
+		RPS:       params.RPS,
+		Burst:     params.Burst,
+		Limit:     params.Limit,
+		Window:    window,
+	}
+	if err := limiter.Reconfigure(c.rateLimiter, spec); err != nil {
+		return fmt.Errorf("%w: %v", router.ErrInvalidSetting, err)
+	}
+	c.rateLimit = spec
+	c.logger.Info("default rate limit changed",
+		zap.String("algorithm", params.Algorithm),
+		zap.Int("rps", spec.RPS),
+		zap.Int("burst", spec.Burst),
+		zap.Int("limit", spec.Limit),
+		zap.Duration("window", spec.Window))
+	return nil
+}
@@ -0,0 +1,263 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shaibs3/Torq/internal/config"
+	"github.com/shaibs3/Torq/internal/limiter"
+	"github.com/shaibs3/Torq/internal/lookup"
+	"github.com/shaibs3/Torq/internal/router"
+	"github.com/shaibs3/Torq/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/metric/noop"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestController_Reload(t *testing.T) {
+	dir := t.TempDir()
+	data := filepath.Join(dir, "ip_data.csv")
+	writeFile(t, data, "1.2.3.4,Paris,France\n")
+	file := filepath.Join(dir, "torq.yaml")
+	batchPolicy := func(burst int) string {
+		return fmt.Sprintf("policies: [{name: batch, path_prefix: /v1/find-country/batch, rps: 1, burst: %d, per_client: true}]", burst)
+	}
+	writeFile(t, file, "provider: {type: csv, csv: {file_path: "+data+"}}\nrate_limit: {rps: 1, burst: 1, "+batchPolicy(1)+"}\n")
+
+	cfg, err := config.Load([]string{"-config", file}, zap.NewNop())
+	require.NoError(t, err)
+	policyConfigs, err := limiter.ParsePolicyConfigs(cfg.RateLimitPolicies)
+	require.NoError(t, err)
+	limiterFactory, err := limiter.NewFactory(limiter.FactoryConfig{}, zap.NewNop())
+	require.NoError(t, err)
+	policies, err := limiter.BuildPolicies(policyConfigs, router.CostFuncs(), limiterFactory, zap.NewNop())
+	require.NoError(t, err)
+	batch := policies[0]
+	clientLimiter := batch.LimiterFor("client-a")
+
+	tel := &telemetry.Telemetry{Meter: noop.NewMeterProvider().Meter("test")}
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	factory := lookup.NewDbProviderFactory(zap.NewNop(), tel)
+	initial, err := factory.CreateProvider(cfg.ProviderJSON())
+	require.NoError(t, err)
+	provider := lookup.NewSwappableProvider(initial)
+	rateLimiter := limiter.NewBurstRateLimiter(1, 1, zap.NewNop())
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	spec := limiter.LimitSpec{Name: limiter.DefaultPolicyName, RPS: 1, Burst: 1, Limit: 1, Window: time.Second}
+	cache := lookup.NewCachedProvider(provider, 10, time.Minute)
+	r := newController(cfg, level, spec, rateLimiter, limiter.NewPolicySet(policies...), provider, cache, factory, meter, zap.NewNop())
+	_, _, err = cache.Lookup(context.Background(), "1.2.3.4")
+	require.NoError(t, err)
+
+	// New limits, log level and data are applied; a new port needs a restart
+	writeFile(t, data, "1.2.3.4,Berlin,Germany\n")
+	writeFile(t, file, "provider: {type: csv, csv: {file_path: "+data+"}}\nrate_limit: {rps: 5, burst: 5, "+batchPolicy(3)+"}\nlog: {level: debug}\nserver: {port: 9000}\n")
+	result, err := r.Reload(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, file, result.ConfigFile)
+	assert.Empty(t, result.Note)
+	assert.Equal(t, []string{"LOG_LEVEL", "RPS_LIMIT", "RPS_BURST", "RATE_LIMIT_POLICIES"}, result.Applied)
+	assert.Equal(t, []string{"PORT"}, result.RestartRequired)
+	// Policies are reconfigured in place, including the limiters of existing clients
+	assert.Equal(t, 3, batch.Limiter.Decide().Limit)
+	assert.Equal(t, 3, clientLimiter.Decide().Limit)
+	assert.Same(t, clientLimiter, batch.LimiterFor("client-a"))
+	assert.Equal(t, 3, batch.LimiterFor("client-b").Decide().Limit)
+
+	// A policy changed other than in its rates keeps running as it is until a restart
+	writeFile(t, file, "provider: {type: csv, csv: {file_path: "+data+"}}\nrate_limit: {rps: 5, burst: 5, "+
+		"policies: [{name: batch, path_prefix: /v1/find-country/batch, rps: 1, burst: 9}]}\nlog: {level: debug}\nserver: {port: 9000}\n")
+	result, err = r.Reload(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, result.Applied)
+	assert.Equal(t, []string{"PORT", "RATE_LIMIT_POLICIES"}, result.RestartRequired)
+	assert.Equal(t, 3, batch.Limiter.Decide().Limit)
+
+	assert.Equal(t, zapcore.DebugLevel, level.Level())
+	assert.Equal(t, 5, rateLimiter.Decide().Limit)
+	assert.Equal(t, 5, r.RateLimit().RPS)
+	// The cache no longer holds answers from the previous data
+	_, country, err := cache.Lookup(context.Background(), "1.2.3.4")
+	require.NoError(t, err)
+	assert.Equal(t, "Germany", country)
+
+	// An invalid configuration changes nothing
+	writeFile(t, file, "provider: {type: csv, csv: {file_path: "+data+"}}\nrate_limit: {rps: 0}\n")
+	_, err = r.reload(context.Background(), reloadTriggerSignal)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "RPS_LIMIT: must be positive")
+	assert.Equal(t, zapcore.DebugLevel, level.Level())
+	assert.Equal(t, 5, rateLimiter.Decide().Limit)
+
+	// So does a provider that fails to load
+	writeFile(t, file, "provider: {type: csv, csv: {file_path: "+data+"}}\n")
+	writeFile(t, data, "not,a,valid\nrow")
+	_, err = r.reload(context.Background(), reloadTriggerSignal)
+	require.Error(t, err)
+	_, country, err = provider.Lookup(context.Background(), "1.2.3.4")
+	require.NoError(t, err)
+	assert.Equal(t, "Germany", country)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	counts := make(map[string]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "config_reloads_total" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				trigger, _ := dp.Attributes.Value("trigger")
+				result, _ := dp.Attributes.Value("result")
+				counts[trigger.AsString()+"/"+result.AsString()] = dp.Value
+			}
+		}
+	}
+	assert.Equal(t, map[string]int64{"admin/success": 2, "signal/failure": 2}, counts)
+}
+
+// fixedLimiter admits everything and cannot be reconfigured
+type fixedLimiter struct{}
+
+func (fixedLimiter) Allow() bool                  { return true }
+func (fixedLimiter) Decide() limiter.Decision     { return limiter.Decision{Allowed: true, Limit: 1} }
+func (fixedLimiter) DecideN(int) limiter.Decision { return limiter.Decision{Allowed: true, Limit: 1} }
+
+func TestController_ReloadIsAtomic(t *testing.T) {
+	dir := t.TempDir()
+	data := filepath.Join(dir, "ip_data.csv")
+	writeFile(t, data, "1.2.3.4,Paris,France\n")
+	file := filepath.Join(dir, "torq.yaml")
+	writeFile(t, file, "provider: {type: csv, csv: {file_path: "+data+"}}\n"+
+		"rate_limit: {policies: [{name: batch, path_prefix: /v1/find-country/batch, rps: 1, burst: 1}]}\n")
+
+	cfg, err := config.Load([]string{"-config", file}, zap.NewNop())
+	require.NoError(t, err)
+	policyConfigs, err := limiter.ParsePolicyConfigs(cfg.RateLimitPolicies)
+	require.NoError(t, err)
+	limiterFactory, err := limiter.NewFactory(limiter.FactoryConfig{}, zap.NewNop())
+	require.NoError(t, err)
+	policies, err := limiter.BuildPolicies(policyConfigs, router.CostFuncs(), limiterFactory, zap.NewNop())
+	require.NoError(t, err)
+
+	tel := &telemetry.Telemetry{Meter: noop.NewMeterProvider().Meter("test")}
+	factory := lookup.NewDbProviderFactory(zap.NewNop(), tel)
+	initial, err := factory.CreateProvider(cfg.ProviderJSON())
+	require.NoError(t, err)
+	provider := lookup.NewSwappableProvider(initial)
+	spec := limiter.LimitSpec{Name: limiter.DefaultPolicyName, RPS: 10, Burst: 10, Limit: 10, Window: time.Second}
+	c := newController(cfg, zap.NewAtomicLevelAt(zapcore.InfoLevel), spec, fixedLimiter{}, limiter.NewPolicySet(policies...),
+		provider, nil, factory, tel.Meter, zap.NewNop())
+
+	// The default limiter cannot take the new rate, so the policy's new burst is not applied either
+	writeFile(t, data, "1.2.3.4,Berlin,Germany\n")
+	writeFile(t, file, "provider: {type: csv, csv: {file_path: "+data+"}}\n"+
+		"rate_limit: {policies: [{name: batch, path_prefix: /v1/find-country/batch, rps: 1, burst: 5}]}\n")
+	_, err = c.Reload(context.Background())
+	require.ErrorContains(t, err, "cannot be reconfigured")
+	assert.Equal(t, 1, policies[0].Limiter.Decide().Limit)
+	assert.Same(t, initial, provider.Current())
+}
+
+func newTestController(t *testing.T, rateLimiter limiter.RateLimiter, spec limiter.LimitSpec, cache bool) (*controller, zap.AtomicLevel) {
+	t.Helper()
+	dir := t.TempDir()
+	data := filepath.Join(dir, "ip_data.csv")
+	writeFile(t, data, "1.2.3.4,Paris,France\n")
+	cfg, err := config.Load([]string{"-provider-type", "csv", "-provider-csv-file-path", data}, zap.NewNop())
+	require.NoError(t, err)
+
+	tel := &telemetry.Telemetry{Meter: noop.NewMeterProvider().Meter("test")}
+	factory := lookup.NewDbProviderFactory(zap.NewNop(), tel)
+	initial, err := factory.CreateProvider(cfg.ProviderJSON())
+	require.NoError(t, err)
+	provider := lookup.NewSwappableProvider(initial)
+	var cached *lookup.CachedProvider
+	if cache {
+		cached = lookup.NewCachedProvider(provider, 10, time.Minute)
+	}
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	return newController(cfg, level, spec, rateLimiter, limiter.NewPolicySet(limiter.NewDefaultPolicy(rateLimiter)), provider, cached, factory, tel.Meter, zap.NewNop()), level
+}
+
+func TestController_ReloadWithoutConfigFile(t *testing.T) {
+	spec := limiter.LimitSpec{Name: limiter.DefaultPolicyName, RPS: 10, Burst: 10, Limit: 10, Window: time.Second}
+	c, _ := newTestController(t, limiter.NewBurstRateLimiter(10, 10, zap.NewNop()), spec, false)
+
+	result, err := c.Reload(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, result.ConfigFile)
+	assert.Equal(t, noConfigFileNote, result.Note)
+	assert.Empty(t, result.Applied)
+	assert.Empty(t, result.RestartRequired)
+}
+
+func TestController_LogLevel(t *testing.T) {
+	spec := limiter.LimitSpec{RPS: 1, Burst: 1, Limit: 1, Window: time.Second}
+	c, level := newTestController(t, limiter.NewBurstRateLimiter(1, 1, zap.NewNop()), spec, false)
+
+	assert.Equal(t, "info", c.LogLevel())
+	require.NoError(t, c.SetLogLevel("debug"))
+	assert.Equal(t, zapcore.DebugLevel, level.Level())
+	assert.Equal(t, "debug", c.EffectiveConfig()["LOG_LEVEL"])
+
+	err := c.SetLogLevel("loud")
+	require.ErrorIs(t, err, router.ErrInvalidSetting)
+	assert.Equal(t, "debug", c.LogLevel())
+}
+
+func TestController_RateLimit(t *testing.T) {
+	rateLimiter := limiter.NewBurstRateLimiter(1, 1, zap.NewNop())
+	spec := limiter.LimitSpec{Name: limiter.DefaultPolicyName, RPS: 1, Burst: 1, Limit: 1, Window: time.Second}
+	c, _ := newTestController(t, rateLimiter, spec, false)
+
+	assert.Equal(t, router.RateLimitParams{Algorithm: "token_bucket", RPS: 1, Burst: 1, Limit: 1, Window: "1s"}, c.RateLimit())
+
+	params := router.RateLimitParams{Algorithm: "token_bucket", RPS: 7, Burst: 7, Limit: 7, Window: "1s"}
+	require.NoError(t, c.SetRateLimit(params))
+	assert.Equal(t, params, c.RateLimit())
+	assert.Equal(t, 7, rateLimiter.Decide().Limit)
+	assert.Equal(t, 7, c.EffectiveConfig()["RPS_LIMIT"])
+
+	// The algorithm cannot change in place, and invalid values change nothing
+	for _, bad := range []router.RateLimitParams{
+		{Algorithm: "gcra", RPS: 5, Burst: 5, Limit: 5, Window: "1s"},
+		{Algorithm: "token_bucket", RPS: 0, Burst: 5, Limit: 5, Window: "1s"},
+		{Algorithm: "token_bucket", RPS: 5, Burst: 5, Limit: 5, Window: "soon"},
+	} {
+		require.ErrorIs(t, c.SetRateLimit(bad), router.ErrInvalidSetting)
+	}
+	assert.Equal(t, params, c.RateLimit())
+}
+
+func TestController_PurgeCache(t *testing.T) {
+	spec := limiter.LimitSpec{RPS: 1, Burst: 1, Limit: 1, Window: time.Second}
+	c, _ := newTestController(t, limiter.NewBurstRateLimiter(1, 1, zap.NewNop()), spec, true)
+	_, _, err := c.cache.Lookup(context.Background(), "1.2.3.4")
+	require.NoError(t, err)
+
+	assert.Equal(t, router.CachePurgeResult{Enabled: true, Purged: 1}, c.PurgeCache())
+	assert.Equal(t, 0, c.cache.Len())
+
+	disabled, _ := newTestController(t, limiter.NewBurstRateLimiter(1, 1, zap.NewNop()), spec, false)
+	assert.Equal(t, router.CachePurgeResult{}, disabled.PurgeCache())
+
+	dataset, err := disabled.Dataset(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, lookup.DbType("csv"), dataset.Type)
+	assert.Equal(t, int64(1), dataset.Records)
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func writeKeys(t *testing.T, path string, keys string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(`{"keys": [`+keys+`]}`), 0o600))
+}
+
+func keyJSON(id, raw string, enabled bool, scopes ...string) string {
+	quoted := ""
+	for i, s := range scopes {
+		if i > 0 {
+			quoted += ","
+		}
+		quoted += `"` + s + `"`
+	}
+	return fmt.Sprintf(`{"id": %q, "key_hash": "sha256:%s", "scopes": [%s], "enabled": %t}`, id, HashKey(raw), quoted, enabled)
+}
+
+func newFileAuthenticator(t *testing.T, keys string) (*Authenticator, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	writeKeys(t, path, keys)
+	store, err := NewFileKeyStore(path)
+	require.NoError(t, err)
+	a, err := NewAuthenticator(store, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = a.Close() })
+	return a, path
+}
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	a, _ := newFileAuthenticator(t, keyJSON("team-a", "secret-a", true, "lookup", "batch")+","+keyJSON("team-b", "secret-b", false, "lookup"))
+
+	key, err := a.Authenticate("secret-a")
+	require.NoError(t, err)
+	assert.Equal(t, "team-a", key.ID)
+	assert.True(t, key.HasScope(ScopeBatch))
+	assert.False(t, key.HasScope(ScopeAdmin))
+
+	_, err = a.Authenticate("secret-b")
+	assert.ErrorIs(t, err, ErrKeyDisabled)
+
+	_, err = a.Authenticate("unknown")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = a.Authenticate("")
+	assert.ErrorIs(t, err, ErrMissingKey)
+}
+
+func TestAuthenticator_ReloadValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		keys   string
+		errMsg string
+	}{
+		{name: "missing id", keys: keyJSON("", "s", true), errMsg: "id is required"},
+		{name: "duplicate id", keys: keyJSON("a", "s1", true) + "," + keyJSON("a", "s2", true), errMsg: "duplicate id"},
+		{name: "duplicate hash", keys: keyJSON("a", "s", true) + "," + keyJSON("b", "s", true), errMsg: "duplicate key_hash"},
+		{name: "bad hash", keys: `{"id": "a", "key_hash": "plaintext", "enabled": true}`, errMsg: "hex SHA-256"},
+		{name: "bad scope", keys: keyJSON("a", "s", true, "everything"), errMsg: "unsupported scope"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "keys.json")
+			writeKeys(t, path, tt.keys)
+			store, err := NewFileKeyStore(path)
+			require.NoError(t, err)
+			_, err = NewAuthenticator(store, zap.NewNop())
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+}
+
+func TestAuthenticator_HotReload(t *testing.T) {
+	a, path := newFileAuthenticator(t, keyJSON("team-a", "secret-a", true, "lookup"))
+	a.Start(10 * time.Millisecond)
+
+	// Disable the key and add a new one
+	writeKeys(t, path, keyJSON("team-a", "secret-a", false, "lookup")+","+keyJSON("team-c", "secret-c", true, "lookup"))
+	require.Eventually(t, func() bool {
+		_, err := a.Authenticate("secret-c")
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	_, err := a.Authenticate("secret-a")
+	assert.ErrorIs(t, err, ErrKeyDisabled)
+
+	// A broken file keeps the previous keys
+	require.NoError(t, os.WriteFile(path, []byte("{broken"), 0o600))
+	assert.Error(t, a.Reload(context.Background()))
+	_, err = a.Authenticate("secret-c")
+	assert.NoError(t, err)
+}
+
+func TestKeyFromRequest(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	assert.Empty(t, KeyFromRequest(req))
+
+	req.Header.Set("Authorization", "Bearer from-bearer")
+	assert.Equal(t, "from-bearer", KeyFromRequest(req))
+
+	req.Header.Set(APIKeyHeader, "from-header")
+	assert.Equal(t, "from-header", KeyFromRequest(req))
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig("")
+	require.NoError(t, err)
+	assert.Nil(t, cfg)
+
+	cfg, err = ParseConfig(`{"store": {"type": "file", "file_path": "keys.json"}, "reload_interval": "5s"}`)
+	require.NoError(t, err)
+	interval, err := cfg.Interval()
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, interval)
+
+	_, err = NewKeyStore(StoreConfig{Type: "ldap"}, zap.NewNop())
+	assert.ErrorContains(t, err, "unsupported")
+}
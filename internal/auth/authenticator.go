@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// APIKeyHeader carries the caller's API key. Keys may also be sent as a bearer token.
+const APIKeyHeader = "X-API-Key"
+
+var (
+	// ErrMissingKey is returned when the request carries no API key
+	ErrMissingKey = errors.New("missing API key")
+	// ErrInvalidKey is returned when the key is unknown
+	ErrInvalidKey = errors.New("invalid API key")
+	// ErrKeyDisabled is returned when the key exists but has been disabled
+	ErrKeyDisabled = errors.New("API key disabled")
+)
+
+// DefaultReloadInterval is how often the key store is polled for changes
+const DefaultReloadInterval = 30 * time.Second
+
+// Config is the JSON form of AUTH_CONFIG. API keys are enabled by Store, JWT
+// bearer tokens by JWT; at least one is required.
+type Config struct {
+	Store StoreConfig `json:"store"`
+	// ReloadInterval is how often keys are reloaded from the store, e.g. "30s"
+	ReloadInterval string     `json:"reload_interval,omitempty"`
+	JWT            *JWTConfig `json:"jwt,omitempty"`
+}
+
+// ParseConfig parses AUTH_CONFIG; an empty string disables authentication and returns nil
+func ParseConfig(configJSON string) (*Config, error) {
+	if strings.TrimSpace(configJSON) == "" {
+		return nil, nil
+	}
+	var cfg Config
+	if err := json.Unmarshal([]byte(configJSON), &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse auth configuration JSON: %w", err)
+	}
+	if !cfg.APIKeysEnabled() && cfg.JWT == nil {
+		return nil, fmt.Errorf("auth configuration requires a key store, jwt settings or both")
+	}
+	return &cfg, nil
+}
+
+// APIKeysEnabled reports whether a key store is configured
+func (c *Config) APIKeysEnabled() bool {
+	return c.Store.Type != ""
+}
+
+// Interval returns the parsed reload interval, or DefaultReloadInterval when unset
+func (c *Config) Interval() (time.Duration, error) {
+	if c.ReloadInterval == "" {
+		return DefaultReloadInterval, nil
+	}
+	interval, err := time.ParseDuration(c.ReloadInterval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid auth reload_interval: %w", err)
+	}
+	if interval <= 0 {
+		return 0, fmt.Errorf("auth reload_interval must be positive")
+	}
+	return interval, nil
+}
+
+// KeyFromRequest extracts the raw API key from the X-API-Key header or an
+// "Authorization: Bearer" token
+func KeyFromRequest(r *http.Request) string {
+	return KeyFromHeaders(r.Header.Get(APIKeyHeader), r.Header.Get("Authorization"))
+}
+
+// KeyFromHeaders extracts the raw API key from the values of the X-API-Key and
+// Authorization headers, or the gRPC metadata of the same names
+func KeyFromHeaders(apiKey, authz string) string {
+	if apiKey != "" {
+		return apiKey
+	}
+	if len(authz) > len("Bearer ") && strings.EqualFold(authz[:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(authz[len("Bearer "):])
+	}
+	return ""
+}
+
+// Authenticator resolves raw API keys against the keys loaded from a KeyStore.
+// The key set is swapped atomically on reload, so lookups never block.
+type Authenticator struct {
+	store  KeyStore
+	keys   atomic.Pointer[map[string]*APIKey]
+	logger *zap.Logger
+
+	started  atomic.Bool
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewAuthenticator loads the initial key set; a store that cannot be read is fatal
+func NewAuthenticator(store KeyStore, logger *zap.Logger) (*Authenticator, error) {
+	a := &Authenticator{
+		store:  store,
+		logger: logger.Named("auth"),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if err := a.Reload(context.Background()); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Reload replaces the key set with the store's current contents. An invalid
+// key set is rejected as a whole and the previous one stays in effect.
+func (a *Authenticator) Reload(ctx context.Context) error {
+	keys, err := a.store.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	byHash := make(map[string]*APIKey, len(keys))
+	ids := make(map[string]bool, len(keys))
+	enabled := 0
+	for i := range keys {
+		key := keys[i]
+		if key.ID == "" {
+			return fmt.Errorf("API key %d: id is required", i)
+		}
+		if ids[key.ID] {
+			return fmt.Errorf("API key %q: duplicate id", key.ID)
+		}
+		ids[key.ID] = true
+
+		key.Hash = normalizeHash(key.Hash)
+		if len(key.Hash) != 64 || strings.Trim(key.Hash, "0123456789abcdef") != "" {
+			return fmt.Errorf("API key %q: key_hash must be a hex SHA-256 digest", key.ID)
+		}
+		if _, ok := byHash[key.Hash]; ok {
+			return fmt.Errorf("API key %q: duplicate key_hash", key.ID)
+		}
+		for _, scope := range key.Scopes {
+			if !scope.IsValid() {
+				return fmt.Errorf("API key %q: unsupported scope %q", key.ID, scope)
+			}
+		}
+		if key.Enabled {
+			enabled++
+		}
+		byHash[key.Hash] = &key
+	}
+
+	a.keys.Store(&byHash)
+	a.logger.Info("API keys loaded", zap.Int("keys", len(byHash)), zap.Int("enabled", enabled))
+	return nil
+}
+
+// Authenticate returns the key matching the raw secret
+func (a *Authenticator) Authenticate(raw string) (*APIKey, error) {
+	if raw == "" {
+		return nil, ErrMissingKey
+	}
+	key, ok := (*a.keys.Load())[HashKey(raw)]
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	if !key.Enabled {
+		return nil, ErrKeyDisabled
+	}
+	return key, nil
+}
+
+// Start polls the store every interval and reloads keys when it reports a change
+func (a *Authenticator) Start(interval time.Duration) {
+	if !a.started.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer close(a.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-a.stop:
+				return
+			case <-ticker.C:
+				if !a.store.Changed() {
+					continue
+				}
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				if err := a.Reload(ctx); err != nil {
+					a.logger.Error("failed to reload API keys, keeping previous keys", zap.Error(err))
+				}
+				cancel()
+			}
+		}
+	}()
+}
+
+// Close stops reloading and releases the store
+func (a *Authenticator) Close() error {
+	a.stopOnce.Do(func() {
+		close(a.stop)
+	})
+	if a.started.Load() {
+		<-a.done
+	}
+	return a.store.Close()
+}
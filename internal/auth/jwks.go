@@ -0,0 +1,274 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxJWKSBytes caps the size of a JWKS document
+const maxJWKSBytes = 1 << 20
+
+// jwk is the subset of RFC 7517 fields needed for RSA, EC P-256 and Ed25519 signature keys
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS parses a JSON Web Key Set into public keys by key ID. Encryption keys
+// and unsupported key types are skipped; a set without usable keys is an error.
+func ParseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("JWKS key %d (kid %q): %w", i, k.Kid, err)
+		}
+		if key == nil {
+			continue
+		}
+		if _, ok := keys[k.Kid]; ok {
+			return nil, fmt.Errorf("JWKS key %d: duplicate kid %q", i, k.Kid)
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS contains no supported signing keys")
+	}
+	return keys, nil
+}
+
+// publicKey decodes the key, returning nil for key types that are not supported
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid n: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid e: %w", err)
+		}
+		if n.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA keys must be at least 2048 bits")
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, nil
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != 32 {
+			return nil, fmt.Errorf("invalid x coordinate")
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil || len(y) != 32 {
+			return nil, fmt.Errorf("invalid y coordinate")
+		}
+		// ecdh rejects points that are not on the curve
+		if _, err := ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, fmt.Errorf("invalid P-256 point: %w", err)
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, nil
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 public key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, nil
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// JWKS holds the signing keys of a token issuer, loaded from a local file or a
+// URL and refreshed periodically. Keys are swapped atomically on refresh.
+type JWKS struct {
+	file   string
+	url    string
+	client *http.Client
+	keys   atomic.Pointer[map[string]crypto.PublicKey]
+	logger *zap.Logger
+
+	started  atomic.Bool
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewJWKS loads the key set from file or url, exactly one of which must be set
+func NewJWKS(file, url string, logger *zap.Logger) (*JWKS, error) {
+	if (file == "") == (url == "") {
+		return nil, fmt.Errorf("exactly one of jwks_file and jwks_url is required")
+	}
+	j := &JWKS{
+		file:   file,
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		logger: logger.Named("jwks"),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if err := j.Refresh(context.Background()); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Refresh reloads the key set. On failure the previous keys stay in effect.
+func (j *JWKS) Refresh(ctx context.Context) error {
+	data, err := j.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return err
+	}
+	j.keys.Store(&keys)
+	j.logger.Info("JWKS loaded", zap.Int("keys", len(keys)), zap.String("source", j.source()))
+	return nil
+}
+
+func (j *JWKS) source() string {
+	if j.file != "" {
+		return j.file
+	}
+	return j.url
+}
+
+func (j *JWKS) fetch(ctx context.Context) ([]byte, error) {
+	if j.file != "" {
+		data, err := os.ReadFile(j.file) // #nosec G304
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+		}
+		return data, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: unexpected status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS response: %w", err)
+	}
+	return data, nil
+}
+
+// Key returns the key with the given ID. A token without a kid is accepted only
+// when the set holds a single key.
+func (j *JWKS) Key(kid string) (crypto.PublicKey, error) {
+	keys := *j.keys.Load()
+	if kid == "" {
+		if len(keys) == 1 {
+			for _, key := range keys {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("token has no kid and the JWKS holds %d keys", len(keys))
+	}
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	return key, nil
+}
+
+// Start refreshes the key set every interval
+func (j *JWKS) Start(interval time.Duration) {
+	if !j.started.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer close(j.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-j.stop:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				if err := j.Refresh(ctx); err != nil {
+					j.logger.Error("failed to refresh JWKS, keeping previous keys", zap.Error(err))
+				}
+				cancel()
+			}
+		}
+	}()
+}
+
+// Close stops refreshing
+func (j *JWKS) Close() error {
+	j.stopOnce.Do(func() {
+		close(j.stop)
+	})
+	if j.started.Load() {
+		<-j.done
+	}
+	return nil
+}
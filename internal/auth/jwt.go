@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidToken is returned for bearer tokens that fail signature or claim validation
+var ErrInvalidToken = errors.New("invalid token")
+
+// SupportedJWTAlgorithms are the signature algorithms accepted in the token header
+var SupportedJWTAlgorithms = []string{"RS256", "ES256", "EdDSA"}
+
+const (
+	defaultJWKSRefreshInterval = 5 * time.Minute
+	defaultJWTClockSkew        = 30 * time.Second
+	defaultScopeClaim          = "scope"
+)
+
+// JWTConfig configures bearer token validation
+type JWTConfig struct {
+	// JWKSFile or JWKSURL locates the issuer's signing keys
+	JWKSFile string `json:"jwks_file,omitempty"`
+	JWKSURL  string `json:"jwks_url,omitempty"`
+	// RefreshInterval is how often the JWKS is reloaded, e.g. "5m"
+	RefreshInterval string `json:"refresh_interval,omitempty"`
+	Issuer          string `json:"issuer"`
+	Audience        string `json:"audience"`
+	// ClockSkew is the leeway applied to exp, nbf and iat, e.g. "30s"
+	ClockSkew string `json:"clock_skew,omitempty"`
+	// ScopeClaim names the claim holding the token's scopes, either a
+	// space-separated string or an array of strings
+	ScopeClaim string `json:"scope_claim,omitempty"`
+	// ScopeMap translates claim values to Torq scopes. When empty, claim values
+	// that name a Torq scope are granted as is.
+	ScopeMap map[string][]Scope `json:"scope_map,omitempty"`
+}
+
+// Validate checks the configuration and fills in defaults
+func (c *JWTConfig) Validate() error {
+	if (c.JWKSFile == "") == (c.JWKSURL == "") {
+		return fmt.Errorf("jwt: exactly one of jwks_file and jwks_url is required")
+	}
+	if c.Issuer == "" || c.Audience == "" {
+		return fmt.Errorf("jwt: issuer and audience are required")
+	}
+	if _, err := c.refreshInterval(); err != nil {
+		return err
+	}
+	if _, err := c.clockSkew(); err != nil {
+		return err
+	}
+	for claim, scopes := range c.ScopeMap {
+		for _, scope := range scopes {
+			if !scope.IsValid() {
+				return fmt.Errorf("jwt: scope_map %q: unsupported scope %q", claim, scope)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *JWTConfig) refreshInterval() (time.Duration, error) {
+	return parsePositiveDuration("refresh_interval", c.RefreshInterval, defaultJWKSRefreshInterval)
+}
+
+func (c *JWTConfig) clockSkew() (time.Duration, error) {
+	if c.ClockSkew == "0" || c.ClockSkew == "0s" {
+		return 0, nil
+	}
+	return parsePositiveDuration("clock_skew", c.ClockSkew, defaultJWTClockSkew)
+}
+
+func parsePositiveDuration(name, value string, defaultValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("jwt: invalid %s: %w", name, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("jwt: %s must be positive", name)
+	}
+	return d, nil
+}
+
+// JWTValidator validates bearer tokens issued by the platform's identity provider
+type JWTValidator struct {
+	config JWTConfig
+	jwks   *JWKS
+	parser *jwt.Parser
+	logger *zap.Logger
+}
+
+// NewJWTValidator loads the JWKS and starts refreshing it
+func NewJWTValidator(config JWTConfig, logger *zap.Logger) (*JWTValidator, error) {
+	return NewJWTValidatorWithTimeProvider(config, logger, time.Now)
+}
+
+func NewJWTValidatorWithTimeProvider(config JWTConfig, logger *zap.Logger, tp func() time.Time) (*JWTValidator, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	logger = logger.Named("jwt")
+
+	jwks, err := NewJWKS(config.JWKSFile, config.JWKSURL, logger)
+	if err != nil {
+		return nil, err
+	}
+	refresh, _ := config.refreshInterval()
+	jwks.Start(refresh)
+
+	skew, _ := config.clockSkew()
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(SupportedJWTAlgorithms),
+		jwt.WithIssuer(config.Issuer),
+		jwt.WithAudience(config.Audience),
+		jwt.WithLeeway(skew),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithTimeFunc(tp),
+	)
+	if config.ScopeClaim == "" {
+		config.ScopeClaim = defaultScopeClaim
+	}
+
+	logger.Info("JWT validation configured",
+		zap.String("issuer", config.Issuer),
+		zap.String("audience", config.Audience),
+		zap.Duration("clock_skew", skew),
+		zap.Duration("jwks_refresh_interval", refresh))
+
+	return &JWTValidator{config: config, jwks: jwks, parser: parser, logger: logger}, nil
+}
+
+// LooksLikeJWT reports whether a bearer credential has the three-part compact JWS form
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// Validate verifies the token and returns its subject and mapped scopes
+func (v *JWTValidator) Validate(token string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.jwks.Key(kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidToken)
+	}
+	return &Principal{ID: subject, Method: "jwt", Scopes: v.scopes(claims)}, nil
+}
+
+// scopes maps the scope claim to Torq scopes
+func (v *JWTValidator) scopes(claims jwt.MapClaims) []Scope {
+	var values []string
+	switch raw := claims[v.config.ScopeClaim].(type) {
+	case string:
+		values = strings.Fields(raw)
+	case []interface{}:
+		for _, item := range raw {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	seen := make(map[Scope]bool)
+	var scopes []Scope
+	grant := func(scope Scope) {
+		if scope.IsValid() && !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	for _, value := range values {
+		if len(v.config.ScopeMap) == 0 {
+			grant(Scope(value))
+			continue
+		}
+		for _, scope := range v.config.ScopeMap[value] {
+			grant(scope)
+		}
+	}
+	return scopes
+}
+
+// Close stops refreshing the JWKS
+func (v *JWTValidator) Close() error {
+	return v.jwks.Close()
+}
@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testSigner struct {
+	kid    string
+	method jwt.SigningMethod
+	key    crypto.Signer
+}
+
+func (s testSigner) jwk() map[string]string {
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch pub := s.key.Public().(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "kid": s.kid, "use": "sig", "n": b64(pub.N.Bytes()), "e": b64(big.NewInt(int64(pub.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		x, y := make([]byte, 32), make([]byte, 32)
+		pub.X.FillBytes(x)
+		pub.Y.FillBytes(y)
+		return map[string]string{"kty": "EC", "kid": s.kid, "crv": "P-256", "x": b64(x), "y": b64(y)}
+	case ed25519.PublicKey:
+		return map[string]string{"kty": "OKP", "kid": s.kid, "crv": "Ed25519", "x": b64(pub)}
+	}
+	panic("unsupported key")
+}
+
+func (s testSigner) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(s.method, claims)
+	token.Header["kid"] = s.kid
+	signed, err := token.SignedString(s.key)
+	require.NoError(t, err)
+	return signed
+}
+
+func newTestSigners(t *testing.T) []testSigner {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return []testSigner{
+		{kid: "rsa", method: jwt.SigningMethodRS256, key: rsaKey},
+		{kid: "ec", method: jwt.SigningMethodES256, key: ecKey},
+		{kid: "ed", method: jwt.SigningMethodEdDSA, key: edKey},
+	}
+}
+
+// jwksServer is a local stand-in for the identity provider's JWKS endpoint
+func jwksServer(t *testing.T, signers *atomic.Pointer[[]testSigner]) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var keys []map[string]string
+		for _, s := range *signers.Load() {
+			keys = append(keys, s.jwk())
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func validClaims(now time.Time) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":   "https://idp.example.com",
+		"aud":   "torq",
+		"sub":   "service-a",
+		"iat":   now.Unix(),
+		"nbf":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"scope": "geo:read geo:bulk",
+	}
+}
+
+func newTestValidator(t *testing.T, url string, now *time.Time) *JWTValidator {
+	t.Helper()
+	v, err := NewJWTValidatorWithTimeProvider(JWTConfig{
+		JWKSURL:   url,
+		Issuer:    "https://idp.example.com",
+		Audience:  "torq",
+		ClockSkew: "30s",
+		ScopeMap:  map[string][]Scope{"geo:read": {ScopeLookup}, "geo:bulk": {ScopeBatch}},
+	}, zap.NewNop(), func() time.Time { return *now })
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = v.Close() })
+	return v
+}
+
+func TestJWTValidator_Algorithms(t *testing.T) {
+	signers := newTestSigners(t)
+	var current atomic.Pointer[[]testSigner]
+	current.Store(&signers)
+	now := time.Unix(1_700_000_000, 0)
+	v := newTestValidator(t, jwksServer(t, &current).URL, &now)
+
+	for _, s := range signers {
+		t.Run(s.method.Alg(), func(t *testing.T) {
+			principal, err := v.Validate(s.sign(t, validClaims(now)))
+			require.NoError(t, err)
+			assert.Equal(t, "service-a", principal.ID)
+			assert.Equal(t, "jwt", principal.Method)
+			assert.Equal(t, []Scope{ScopeLookup, ScopeBatch}, principal.Scopes)
+		})
+	}
+}
+
+func TestJWTValidator_Claims(t *testing.T) {
+	signers := newTestSigners(t)
+	var current atomic.Pointer[[]testSigner]
+	current.Store(&signers)
+	now := time.Unix(1_700_000_000, 0)
+	v := newTestValidator(t, jwksServer(t, &current).URL, &now)
+	signer := signers[0]
+
+	tests := []struct {
+		name   string
+		mutate func(jwt.MapClaims)
+		valid  bool
+	}{
+		{name: "wrong issuer", mutate: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{name: "wrong audience", mutate: func(c jwt.MapClaims) { c["aud"] = "other" }},
+		{name: "audience list", mutate: func(c jwt.MapClaims) { c["aud"] = []string{"other", "torq"} }, valid: true},
+		{name: "expired", mutate: func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Minute).Unix() }},
+		{name: "expired within skew", mutate: func(c jwt.MapClaims) { c["exp"] = now.Add(-10 * time.Second).Unix() }, valid: true},
+		{name: "missing exp", mutate: func(c jwt.MapClaims) { delete(c, "exp") }},
+		{name: "not yet valid", mutate: func(c jwt.MapClaims) { c["nbf"] = now.Add(time.Minute).Unix() }},
+		{name: "not yet valid within skew", mutate: func(c jwt.MapClaims) { c["nbf"] = now.Add(10 * time.Second).Unix() }, valid: true},
+		{name: "missing subject", mutate: func(c jwt.MapClaims) { delete(c, "sub") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims(now)
+			tt.mutate(claims)
+			_, err := v.Validate(signer.sign(t, claims))
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidToken)
+			}
+		})
+	}
+
+	// Tokens signed with HS256 or by unknown keys are rejected
+	hs := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims(now))
+	hsToken, err := hs.SignedString([]byte("secret"))
+	require.NoError(t, err)
+	_, err = v.Validate(hsToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	stranger := newTestSigners(t)[0]
+	stranger.kid = "unknown"
+	_, err = v.Validate(stranger.sign(t, validClaims(now)))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestJWTValidator_ScopeClaimForms(t *testing.T) {
+	signers := newTestSigners(t)
+	var current atomic.Pointer[[]testSigner]
+	current.Store(&signers)
+	now := time.Unix(1_700_000_000, 0)
+
+	v, err := NewJWTValidatorWithTimeProvider(JWTConfig{
+		JWKSURL:    jwksServer(t, &current).URL,
+		Issuer:     "https://idp.example.com",
+		Audience:   "torq",
+		ScopeClaim: "scp",
+	}, zap.NewNop(), func() time.Time { return now })
+	require.NoError(t, err)
+	defer v.Close() //nolint:errcheck
+
+	claims := validClaims(now)
+	claims["scp"] = []string{"lookup", "admin", "unknown"}
+	principal, err := v.Validate(signers[1].sign(t, claims))
+	require.NoError(t, err)
+	assert.Equal(t, []Scope{ScopeLookup, ScopeAdmin}, principal.Scopes)
+}
+
+func TestJWKS_Refresh(t *testing.T) {
+	signers := newTestSigners(t)
+	first := signers[:1]
+	var current atomic.Pointer[[]testSigner]
+	current.Store(&first)
+	srv := jwksServer(t, &current)
+
+	jwks, err := NewJWKS("", srv.URL, zap.NewNop())
+	require.NoError(t, err)
+	_, err = jwks.Key("ec")
+	assert.Error(t, err)
+
+	// The identity provider rotates in a new key
+	current.Store(&signers)
+	require.NoError(t, jwks.Refresh(context.Background()))
+	_, err = jwks.Key("ec")
+	assert.NoError(t, err)
+
+	// A failed refresh keeps the previous keys
+	srv.Close()
+	assert.Error(t, jwks.Refresh(context.Background()))
+	_, err = jwks.Key("ec")
+	assert.NoError(t, err)
+}
+
+func TestParseJWKS(t *testing.T) {
+	_, err := ParseJWKS([]byte(`{"keys": []}`))
+	assert.ErrorContains(t, err, "no supported signing keys")
+
+	_, err = ParseJWKS([]byte(`{"keys": [{"kty": "RSA", "kid": "small", "n": "AQAB", "e": "AQAB"}]}`))
+	assert.ErrorContains(t, err, "2048 bits")
+
+	_, err = ParseJWKS([]byte(`{"keys": [{"kty": "EC", "crv": "P-256", "x": "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA", "y": "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"}]}`))
+	assert.ErrorContains(t, err, "invalid P-256 point")
+
+	signer := newTestSigners(t)[2]
+	enc := signer.jwk()
+	enc["use"] = "enc"
+	data, err := json.Marshal(map[string]interface{}{"keys": []map[string]string{enc, signer.jwk()}})
+	require.NoError(t, err)
+	keys, err := ParseJWKS(data)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+}
+
+func TestJWTConfig_Validate(t *testing.T) {
+	assert.ErrorContains(t, (&JWTConfig{Issuer: "i", Audience: "a"}).Validate(), "jwks_file")
+	assert.ErrorContains(t, (&JWTConfig{JWKSFile: "f", JWKSURL: "u", Issuer: "i", Audience: "a"}).Validate(), "exactly one")
+	assert.ErrorContains(t, (&JWTConfig{JWKSFile: "f"}).Validate(), "issuer and audience")
+	assert.ErrorContains(t, (&JWTConfig{JWKSFile: "f", Issuer: "i", Audience: "a", ClockSkew: "-1s"}).Validate(), "clock_skew")
+	assert.ErrorContains(t, (&JWTConfig{JWKSFile: "f", Issuer: "i", Audience: "a", ScopeMap: map[string][]Scope{"x": {"root"}}}).Validate(), "unsupported scope")
+}
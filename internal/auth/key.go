@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Scope grants access to a group of routes
+type Scope string
+
+const (
+	ScopeLookup Scope = "lookup"
+	ScopeBatch  Scope = "batch"
+	ScopeAdmin  Scope = "admin"
+)
+
+// IsValid checks if the scope is supported
+func (s Scope) IsValid() bool {
+	switch s {
+	case ScopeLookup, ScopeBatch, ScopeAdmin:
+		return true
+	default:
+		return false
+	}
+}
+
+// APIKey is a stored key. Only the SHA-256 digest of the secret is kept.
+type APIKey struct {
+	ID      string  `json:"id"`
+	Name    string  `json:"name,omitempty"`
+	Hash    string  `json:"key_hash"`
+	Scopes  []Scope `json:"scopes"`
+	Enabled bool    `json:"enabled"`
+}
+
+// HasScope reports whether the key grants the scope
+func (k *APIKey) HasScope(scope Scope) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Principal returns the caller identified by the key
+func (k *APIKey) Principal() *Principal {
+	return &Principal{ID: k.ID, Method: "api_key", Scopes: k.Scopes}
+}
+
+// HashKey returns the hex SHA-256 digest under which a raw key is stored
+func HashKey(raw string) string {
+	digest := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(digest[:])
+}
+
+// normalizeHash accepts digests with or without a "sha256:" prefix, in any case
+func normalizeHash(hash string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(hash), "sha256:"))
+}
@@ -0,0 +1,40 @@
+package auth
+
+// Principal is an authenticated caller, whether identified by an API key or a JWT
+type Principal struct {
+	// ID is the API key ID or the token subject
+	ID string
+	// Method is "api_key" or "jwt"
+	Method string
+	Scopes []Scope
+}
+
+// HasScope reports whether the principal was granted the scope
+func (p *Principal) HasScope(scope Scope) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify resolves a credential to its principal. Credentials that look like a JWT are
+// validated as tokens when tokens is set, unless they came in the X-API-Key header;
+// everything else is an API key checked against keys. Either may be nil.
+func Verify(credential string, fromAPIKeyHeader bool, keys *Authenticator, tokens *JWTValidator) (*Principal, error) {
+	if credential == "" {
+		return nil, ErrMissingKey
+	}
+	if tokens != nil && !fromAPIKeyHeader && LooksLikeJWT(credential) {
+		return tokens.Validate(credential)
+	}
+	if keys == nil {
+		return nil, ErrInvalidToken
+	}
+	key, err := keys.Authenticate(credential)
+	if err != nil {
+		return nil, err
+	}
+	return key.Principal(), nil
+}
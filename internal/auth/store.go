@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/shaibs3/Torq/internal/redact"
+	"go.uber.org/zap"
+)
+
+// KeyStore loads the current set of API keys
+type KeyStore interface {
+	// Load returns all keys, enabled or not
+	Load(ctx context.Context) ([]APIKey, error)
+	// Changed reports whether Load may return something new since the last call;
+	// stores that cannot tell cheaply always return true
+	Changed() bool
+	Close() error
+}
+
+const (
+	StoreTypeFile     = "file"
+	StoreTypePostgres = "postgres"
+)
+
+// StoreConfig selects where API keys are kept
+type StoreConfig struct {
+	Type     string `json:"type"`
+	FilePath string `json:"file_path,omitempty"`
+	ConnStr  string `json:"conn_str,omitempty"`
+	// ConnStrFile names a file holding the connection string and takes precedence over ConnStr
+	ConnStrFile string `json:"conn_str_file,omitempty"`
+}
+
+// NewKeyStore creates the store selected by config
+func NewKeyStore(config StoreConfig, logger *zap.Logger) (KeyStore, error) {
+	switch config.Type {
+	case StoreTypeFile:
+		return NewFileKeyStore(config.FilePath)
+	case StoreTypePostgres:
+		connStr, err := redact.Resolve(config.ConnStr, config.ConnStrFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read API key store conn_str_file: %w", err)
+		}
+		return NewPostgresKeyStore(connStr, logger)
+	default:
+		return nil, fmt.Errorf("unsupported API key store type: %s", config.Type)
+	}
+}
+
+// FileKeyStore reads keys from a JSON file of the form {"keys": [...]}
+type FileKeyStore struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+func NewFileKeyStore(path string) (*FileKeyStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("file_path is required for the file API key store")
+	}
+	return &FileKeyStore{path: path}, nil
+}
+
+func (s *FileKeyStore) Load(ctx context.Context) ([]APIKey, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat API key file: %w", err)
+	}
+	data, err := os.ReadFile(s.path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API key file: %w", err)
+	}
+
+	var file struct {
+		Keys []APIKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse API key file: %w", err)
+	}
+
+	s.modTime = info.ModTime()
+	s.size = info.Size()
+	return file.Keys, nil
+}
+
+func (s *FileKeyStore) Changed() bool {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return true
+	}
+	return !info.ModTime().Equal(s.modTime) || info.Size() != s.size
+}
+
+func (s *FileKeyStore) Close() error {
+	return nil
+}
+
+// PostgresKeyStore reads keys from the api_keys table
+type PostgresKeyStore struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+const createAPIKeysTable = `CREATE TABLE IF NOT EXISTS api_keys (
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scopes VARCHAR(255) NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE
+)`
+
+func NewPostgresKeyStore(connStr string, logger *zap.Logger) (*PostgresKeyStore, error) {
+	if connStr == "" {
+		return nil, fmt.Errorf("conn_str or conn_str_file is required for the postgres API key store")
+	}
+	logger = logger.Named("api_key_postgres_store")
+
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Postgres connection: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping Postgres: %w", err)
+	}
+	if _, err := db.Exec(createAPIKeysTable); err != nil {
+		return nil, fmt.Errorf("failed to create api_keys table: %w", err)
+	}
+
+	logger.Info("Postgres API key store initialized successfully")
+	return &PostgresKeyStore{db: db, logger: logger}, nil
+}
+
+func (s *PostgresKeyStore) Load(ctx context.Context) ([]APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, name, key_hash, scopes, enabled FROM api_keys")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var keys []APIKey
+	for rows.Next() {
+		var key APIKey
+		var scopes string
+		if err := rows.Scan(&key.ID, &key.Name, &key.Hash, &scopes, &key.Enabled); err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		for _, scope := range strings.Split(scopes, ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				key.Scopes = append(key.Scopes, Scope(scope))
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (s *PostgresKeyStore) Changed() bool {
+	return true
+}
+
+func (s *PostgresKeyStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,272 @@
+// Package bench drives lookups against a provider or a Torq endpoint at a fixed rate
+// and concurrency, and summarises their latency, outcomes and throughput.
+package bench
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"net/netip"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Outcome classifies a lookup
+type Outcome string
+
+const (
+	OutcomeOK          Outcome = "ok"
+	OutcomeNotFound    Outcome = "not_found"
+	OutcomeRateLimited Outcome = "rate_limited"
+	OutcomeError       Outcome = "error"
+)
+
+// Target performs one lookup. The error explains an OutcomeError and is grouped by
+// message in the result, so it should not vary by IP.
+type Target func(ctx context.Context, ip string) (Outcome, error)
+
+// Options shape the load
+type Options struct {
+	// RPS is the rate at which lookups start; zero runs them back to back
+	RPS float64
+	// Concurrency is the number of lookups in flight at most
+	Concurrency int
+	// Duration bounds the run
+	Duration time.Duration
+	// Requests, when positive, ends the run after that many lookups
+	Requests int
+}
+
+// Validate checks that the options describe a run that ends
+func (o Options) Validate() error {
+	switch {
+	case o.RPS < 0:
+		return errors.New("rps must not be negative")
+	case o.Concurrency < 1:
+		return errors.New("concurrency must be at least 1")
+	case o.Duration <= 0 && o.Requests <= 0:
+		return errors.New("a duration or a number of requests is required")
+	}
+	return nil
+}
+
+// Latency summarises the latencies of the lookups that completed
+type Latency struct {
+	Min  time.Duration `json:"min_ns"`
+	Mean time.Duration `json:"mean_ns"`
+	P50  time.Duration `json:"p50_ns"`
+	P90  time.Duration `json:"p90_ns"`
+	P99  time.Duration `json:"p99_ns"`
+	P999 time.Duration `json:"p999_ns"`
+	Max  time.Duration `json:"max_ns"`
+}
+
+// Bucket counts the lookups that took at most UpperBound, and longer than the
+// previous bucket's bound. The last bucket has no bound.
+type Bucket struct {
+	UpperBound time.Duration `json:"le_ns,omitempty"`
+	Count      int           `json:"count"`
+}
+
+// bucketBounds are the upper bounds of the histogram buckets
+var bucketBounds = []time.Duration{
+	50 * time.Microsecond, 100 * time.Microsecond, 250 * time.Microsecond, 500 * time.Microsecond,
+	time.Millisecond, 2500 * time.Microsecond, 5 * time.Millisecond, 10 * time.Millisecond,
+	25 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond, 250 * time.Millisecond,
+	500 * time.Millisecond, time.Second, 2500 * time.Millisecond,
+}
+
+// Result is the outcome of a run
+type Result struct {
+	Requests int           `json:"requests"`
+	Elapsed  time.Duration `json:"elapsed_ns"`
+	// Throughput is the number of lookups completed per second
+	Throughput float64         `json:"throughput_rps"`
+	Outcomes   map[Outcome]int `json:"outcomes"`
+	// Errors counts the errors behind OutcomeError by message
+	Errors    map[string]int `json:"errors"`
+	Latency   Latency        `json:"latency"`
+	Histogram []Bucket       `json:"histogram"`
+}
+
+// worker accumulates the samples of one goroutine, merged when the run ends
+type worker struct {
+	latencies []time.Duration
+	outcomes  map[Outcome]int
+	errors    map[string]int
+}
+
+// Run looks up ips in turn against target until the duration elapses, the requests
+// are done or ctx is cancelled. Lookups cut short by the end of the run are not counted.
+func Run(ctx context.Context, target Target, ips []string, opts Options) (Result, error) {
+	if err := opts.Validate(); err != nil {
+		return Result{}, err
+	}
+	if len(ips) == 0 {
+		return Result{}, errors.New("no IP addresses to look up")
+	}
+	// Cancelling at the end also stops the pacer of a run bounded by requests
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if opts.Duration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	var tokens <-chan struct{}
+	if opts.RPS > 0 {
+		tokens = pace(ctx, opts.RPS)
+	}
+	var next atomic.Int64
+	workers := make([]*worker, opts.Concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := range workers {
+		w := &worker{outcomes: make(map[Outcome]int), errors: make(map[string]int)}
+		workers[i] = w
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if tokens != nil {
+					if _, ok := <-tokens; !ok {
+						return
+					}
+				}
+				n := int(next.Add(1)) - 1
+				if (opts.Requests > 0 && n >= opts.Requests) || ctx.Err() != nil {
+					return
+				}
+				began := time.Now()
+				outcome, err := target(ctx, ips[n%len(ips)])
+				took := time.Since(began)
+				if ctx.Err() != nil {
+					return
+				}
+				w.latencies = append(w.latencies, took)
+				w.outcomes[outcome]++
+				if err != nil {
+					w.errors[err.Error()]++
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return summarize(workers, time.Since(start)), nil
+}
+
+// pace emits rps tokens per second on a fixed schedule until ctx is done. A token
+// that waits for a free worker delays the ones after it, which then catch up.
+func pace(ctx context.Context, rps float64) <-chan struct{} {
+	tokens := make(chan struct{})
+	interval := time.Duration(float64(time.Second) / rps)
+	go func() {
+		defer close(tokens)
+		start := time.Now()
+		for i := 0; ; i++ {
+			if wait := time.Until(start.Add(time.Duration(i) * interval)); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case tokens <- struct{}{}:
+			}
+		}
+	}()
+	return tokens
+}
+
+func summarize(workers []*worker, elapsed time.Duration) Result {
+	result := Result{
+		Elapsed:  elapsed,
+		Outcomes: make(map[Outcome]int),
+		Errors:   make(map[string]int),
+	}
+	var latencies []time.Duration
+	for _, w := range workers {
+		latencies = append(latencies, w.latencies...)
+		for outcome, n := range w.outcomes {
+			result.Outcomes[outcome] += n
+		}
+		for msg, n := range w.errors {
+			result.Errors[msg] += n
+		}
+	}
+	result.Requests = len(latencies)
+	if elapsed > 0 {
+		result.Throughput = float64(result.Requests) / elapsed.Seconds()
+	}
+	result.Histogram = histogram(latencies)
+	if len(latencies) == 0 {
+		return result
+	}
+
+	slices.Sort(latencies)
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+	}
+	result.Latency = Latency{
+		Min:  latencies[0],
+		Mean: total / time.Duration(len(latencies)),
+		P50:  percentile(latencies, 0.5),
+		P90:  percentile(latencies, 0.9),
+		P99:  percentile(latencies, 0.99),
+		P999: percentile(latencies, 0.999),
+		Max:  latencies[len(latencies)-1],
+	}
+	return result
+}
+
+// percentile returns the nearest-rank percentile p of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
+
+func histogram(latencies []time.Duration) []Bucket {
+	buckets := make([]Bucket, len(bucketBounds)+1)
+	for i, bound := range bucketBounds {
+		buckets[i].UpperBound = bound
+	}
+	for _, l := range latencies {
+		i, _ := slices.BinarySearch(bucketBounds, l)
+		buckets[i].Count++
+	}
+	return buckets
+}
+
+// benchmarkRange is reserved for benchmarking by RFC 2544; datasets should not list it
+var benchmarkRange = netip.MustParsePrefix("198.18.0.0/15")
+
+// Sample returns n IPs drawn from ips, with missRatio of them replaced by addresses
+// from 198.18.0.0/15 that the dataset is not expected to hold. The same seed gives
+// the same sample.
+func Sample(ips []string, n int, missRatio float64, seed uint64) []string {
+	rng := rand.New(rand.NewPCG(seed, seed))
+	sample := make([]string, n)
+	base := benchmarkRange.Addr().As4()
+	for i := range sample {
+		if len(ips) == 0 || rng.Float64() < missRatio {
+			host := rng.Uint32N(1 << (32 - benchmarkRange.Bits()))
+			addr := base
+			addr[1] += byte(host >> 16)
+			addr[2] = byte(host >> 8)
+			addr[3] = byte(host)
+			sample[i] = netip.AddrFrom4(addr).String()
+			continue
+		}
+		sample[i] = ips[rng.IntN(len(ips))]
+	}
+	return sample
+}
@@ -0,0 +1,117 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	var calls atomic.Int64
+	target := func(ctx context.Context, ip string) (Outcome, error) {
+		switch n := calls.Add(1); {
+		case ip == "8.8.8.8":
+			return OutcomeNotFound, nil
+		case n%10 == 0:
+			return OutcomeError, errors.New("boom")
+		}
+		return OutcomeOK, nil
+	}
+
+	result, err := Run(context.Background(), target, []string{"1.2.3.4", "8.8.8.8"}, Options{Concurrency: 4, Requests: 100})
+	require.NoError(t, err)
+	assert.Equal(t, 100, result.Requests)
+	assert.Equal(t, 100, result.Outcomes[OutcomeOK]+result.Outcomes[OutcomeNotFound]+result.Outcomes[OutcomeError])
+	assert.Equal(t, 50, result.Outcomes[OutcomeNotFound], "the IPs are looked up in turn")
+	assert.Equal(t, result.Outcomes[OutcomeError], result.Errors["boom"])
+	assert.Positive(t, result.Throughput)
+	assert.LessOrEqual(t, result.Latency.Min, result.Latency.P50)
+	assert.LessOrEqual(t, result.Latency.P50, result.Latency.P999)
+	assert.LessOrEqual(t, result.Latency.P999, result.Latency.Max)
+
+	counted := 0
+	for _, b := range result.Histogram {
+		counted += b.Count
+	}
+	assert.Equal(t, 100, counted)
+}
+
+func TestRun_RPS(t *testing.T) {
+	target := func(ctx context.Context, ip string) (Outcome, error) { return OutcomeOK, nil }
+	result, err := Run(context.Background(), target, []string{"1.2.3.4"}, Options{RPS: 100, Concurrency: 2, Duration: 300 * time.Millisecond})
+	require.NoError(t, err)
+	assert.InDelta(t, 30, result.Requests, 10, "lookups start at the requested rate")
+}
+
+func TestRun_InvalidOptions(t *testing.T) {
+	target := func(ctx context.Context, ip string) (Outcome, error) { return OutcomeOK, nil }
+	_, err := Run(context.Background(), target, []string{"1.2.3.4"}, Options{Concurrency: 1})
+	assert.ErrorContains(t, err, "a duration or a number of requests is required")
+	_, err = Run(context.Background(), target, []string{"1.2.3.4"}, Options{Requests: 1})
+	assert.ErrorContains(t, err, "concurrency must be at least 1")
+	_, err = Run(context.Background(), target, nil, Options{Concurrency: 1, Requests: 1})
+	assert.ErrorContains(t, err, "no IP addresses")
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 1000)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+	assert.Equal(t, 500*time.Millisecond, percentile(sorted, 0.5))
+	assert.Equal(t, 990*time.Millisecond, percentile(sorted, 0.99))
+	assert.Equal(t, 999*time.Millisecond, percentile(sorted, 0.999))
+	assert.Equal(t, time.Millisecond, percentile(sorted[:1], 0.999))
+}
+
+func TestSample(t *testing.T) {
+	ips := []string{"1.2.3.4", "5.6.7.8"}
+	sample := Sample(ips, 1000, 0.25, 7)
+	assert.Equal(t, sample, Sample(ips, 1000, 0.25, 7), "the same seed gives the same sample")
+	assert.NotEqual(t, sample, Sample(ips, 1000, 0.25, 8))
+
+	misses := 0
+	for _, ip := range sample {
+		if ip != "1.2.3.4" && ip != "5.6.7.8" {
+			assert.True(t, benchmarkRange.Contains(netip.MustParseAddr(ip)), ip)
+			misses++
+		}
+	}
+	assert.InDelta(t, 250, misses, 60)
+}
+
+func TestHTTPTarget(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/find-country", r.URL.Path)
+		assert.Equal(t, "secret", r.Header.Get("X-API-Key"))
+		switch r.URL.Query().Get("ip") {
+		case "1.2.3.4":
+			w.WriteHeader(http.StatusOK)
+		case "8.8.8.8":
+			w.WriteHeader(http.StatusNotFound)
+		case "9.9.9.9":
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	target := HTTPTarget(srv.Client(), srv.URL+"/", http.Header{"X-Api-Key": {"secret"}})
+	tests := map[string]Outcome{"1.2.3.4": OutcomeOK, "8.8.8.8": OutcomeNotFound, "9.9.9.9": OutcomeRateLimited, "7.7.7.7": OutcomeError}
+	for ip, want := range tests {
+		outcome, err := target(context.Background(), ip)
+		assert.Equal(t, want, outcome, ip)
+		if want == OutcomeError {
+			assert.EqualError(t, err, "HTTP 503")
+		}
+	}
+}
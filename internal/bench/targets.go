@@ -0,0 +1,65 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/shaibs3/Torq/internal/lookup"
+)
+
+// ProviderTarget looks IPs up in provider directly. Like FindIpHandler, it counts any
+// provider error as not found, except for a cancelled or timed out lookup.
+func ProviderTarget(provider lookup.DbProvider) Target {
+	return func(ctx context.Context, ip string) (Outcome, error) {
+		_, _, err := provider.Lookup(ctx, ip)
+		switch {
+		case err == nil:
+			return OutcomeOK, nil
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			return OutcomeError, err
+		}
+		return OutcomeNotFound, nil
+	}
+}
+
+// HTTPTarget looks IPs up with GET /v1/find-country on the Torq API at baseURL,
+// sending header with every request
+func HTTPTarget(client *http.Client, baseURL string, header http.Header) Target {
+	endpoint := strings.TrimSuffix(baseURL, "/") + "/v1/find-country?ip="
+	return func(ctx context.Context, ip string) (Outcome, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+url.QueryEscape(ip), nil)
+		if err != nil {
+			return OutcomeError, err
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			// The URL holds the IP; report the cause alone so errors group together
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				err = urlErr.Err
+			}
+			return OutcomeError, err
+		}
+		// Drain the body so the connection is reused
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			return OutcomeOK, nil
+		case http.StatusNotFound:
+			return OutcomeNotFound, nil
+		case http.StatusTooManyRequests:
+			return OutcomeRateLimited, nil
+		}
+		return OutcomeError, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+}
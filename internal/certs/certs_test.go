@@ -0,0 +1,198 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shaibs3/Torq/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.uber.org/zap"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns PEM encoded certificate and key signed by the CA
+func (ca *testCA) issue(t *testing.T, tmpl *x509.Certificate) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	tmpl.SerialNumber = serial
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	if tmpl.NotAfter.IsZero() {
+		tmpl.NotAfter = time.Now().Add(time.Hour)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func (ca *testCA) serverFiles(t *testing.T, dir string, notAfter time.Time) (string, string) {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "torq"},
+		DNSNames:    []string{"localhost"},
+		NotAfter:    notAfter,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0o600))
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0o600))
+	return certFile, keyFile
+}
+
+func testTelemetry() *telemetry.Telemetry {
+	return &telemetry.Telemetry{Meter: noop.NewMeterProvider().Meter("test")}
+}
+
+func TestReloader_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile := ca.serverFiles(t, dir, time.Time{})
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0o600))
+
+	reloader, err := NewReloader(Config{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile}, testTelemetry(), zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, ClientAuthRequire, reloader.ClientAuth())
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, PeerName(r.TLS))
+	}))
+	srv.TLS = reloader.TLSConfig()
+	srv.StartTLS()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	spiffe, err := url.Parse("spiffe://cluster.local/ns/geo/sa/client")
+	require.NoError(t, err)
+	clientPEM, clientKey := ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "client"},
+		URIs:        []*url.URL{spiffe},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	clientCert, err := tls.X509KeyPair(clientPEM, clientKey)
+	require.NoError(t, err)
+
+	get := func(certs ...tls.Certificate) (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			ServerName:   "localhost",
+			Certificates: certs,
+		}}}
+		return client.Get(srv.URL)
+	}
+
+	resp, err := get(clientCert)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, spiffe.String(), string(body))
+
+	_, err = get()
+	assert.Error(t, err, "a client certificate is required")
+}
+
+func TestReloader_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	firstExpiry := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+	certFile, keyFile := ca.serverFiles(t, dir, firstExpiry)
+
+	reloader, err := NewReloader(Config{CertFile: certFile, KeyFile: keyFile, ReloadInterval: 10 * time.Millisecond}, testTelemetry(), zap.NewNop())
+	require.NoError(t, err)
+	reloader.Start()
+	defer reloader.Close() //nolint:errcheck
+	assert.Equal(t, firstExpiry, reloader.current.Load().leaf.NotAfter)
+
+	// Write the rotated pair with a later mtime so the change is seen on coarse clocks
+	secondExpiry := firstExpiry.Add(24 * time.Hour)
+	ca.serverFiles(t, dir, secondExpiry)
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	require.NoError(t, os.Chtimes(keyFile, later, later))
+
+	require.Eventually(t, func() bool {
+		return reloader.current.Load().leaf.NotAfter.Equal(secondExpiry)
+	}, time.Second, 10*time.Millisecond)
+
+	// A broken file keeps the current certificate
+	require.NoError(t, os.WriteFile(certFile, []byte("garbage"), 0o600))
+	assert.Error(t, reloader.Reload())
+	assert.Equal(t, secondExpiry, reloader.current.Load().leaf.NotAfter)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		errMsg string
+	}{
+		{name: "missing key", config: Config{CertFile: "c"}, errMsg: "both"},
+		{name: "bad mode", config: Config{CertFile: "c", KeyFile: "k", ClientAuth: "sometimes"}, errMsg: "unsupported"},
+		{name: "mode without CA", config: Config{CertFile: "c", KeyFile: "k", ClientAuth: ClientAuthOptional}, errMsg: "TLS_CLIENT_CA_FILE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorContains(t, tt.config.validate(), tt.errMsg)
+		})
+	}
+}
+
+func TestPeerName(t *testing.T) {
+	assert.Empty(t, PeerName(nil))
+	assert.Empty(t, PeerName(&tls.ConnectionState{}))
+
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "cn"}, EmailAddresses: []string{"a@example.com"}}
+	state := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	assert.Equal(t, "a@example.com", PeerName(state))
+	cert.DNSNames = []string{"svc.example.com"}
+	assert.Equal(t, "svc.example.com", PeerName(state))
+	cert.DNSNames, cert.EmailAddresses = nil, nil
+	assert.Equal(t, "cn", PeerName(state))
+}
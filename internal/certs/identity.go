@@ -0,0 +1,29 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+)
+
+// PeerName returns the identity of a verified client certificate: the first URI
+// SAN (e.g. a SPIFFE ID), then the first DNS SAN, then the first email SAN, and
+// finally the subject common name. It returns "" when no verified certificate was presented.
+func PeerName(state *tls.ConnectionState) string {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return certName(state.VerifiedChains[0][0])
+}
+
+func certName(cert *x509.Certificate) string {
+	switch {
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	case len(cert.EmailAddresses) > 0:
+		return cert.EmailAddresses[0]
+	default:
+		return cert.Subject.CommonName
+	}
+}
@@ -0,0 +1,64 @@
+package certs
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+// CertMetrics exports certificate expiry and reload outcomes
+type CertMetrics struct {
+	ExpiryTimestamp metric.Float64ObservableGauge
+	Reloads         metric.Int64Counter
+}
+
+// NewCertMetrics registers an expiry gauge observed from the reloader's current certificates
+func NewCertMetrics(meter metric.Meter, r *Reloader, logger *zap.Logger) *CertMetrics {
+	expiry, err := meter.Float64ObservableGauge(
+		"tls_certificate_expiry_timestamp_seconds",
+		metric.WithDescription("Unix time at which the serving certificate or a client CA expires"),
+		metric.WithUnit("s"),
+		metric.WithFloat64Callback(func(ctx context.Context, o metric.Float64Observer) error {
+			b := r.current.Load()
+			o.Observe(float64(b.leaf.NotAfter.Unix()), metric.WithAttributes(
+				attribute.String("kind", "server"),
+				attribute.String("subject", b.leaf.Subject.CommonName)))
+			for _, ca := range b.caCerts {
+				o.Observe(float64(ca.NotAfter.Unix()), metric.WithAttributes(
+					attribute.String("kind", "client_ca"),
+					attribute.String("subject", ca.Subject.CommonName)))
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		logger.Error("failed to create certificate expiry metric", zap.Error(err))
+	}
+
+	reloads, err := meter.Int64Counter(
+		"tls_certificate_reloads_total",
+		metric.WithDescription("Total number of certificate reloads triggered by file changes"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		logger.Error("failed to create certificate reloads metric", zap.Error(err))
+	}
+
+	return &CertMetrics{
+		ExpiryTimestamp: expiry,
+		Reloads:         reloads,
+	}
+}
+
+func (m *CertMetrics) recordReload(err error) {
+	if m == nil || m.Reloads == nil {
+		return
+	}
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.Reloads.Add(context.Background(), 1, metric.WithAttributes(attribute.String("result", result)))
+}
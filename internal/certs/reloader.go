@@ -0,0 +1,294 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/shaibs3/Torq/internal/telemetry"
+	"go.uber.org/zap"
+)
+
+// ClientAuth selects whether clients must present a certificate
+type ClientAuth string
+
+const (
+	// ClientAuthNone does not request client certificates
+	ClientAuthNone ClientAuth = "none"
+	// ClientAuthOptional verifies a client certificate when one is presented
+	ClientAuthOptional ClientAuth = "optional"
+	// ClientAuthRequire rejects handshakes without a valid client certificate
+	ClientAuthRequire ClientAuth = "require"
+)
+
+// IsValid checks if the client auth mode is supported
+func (c ClientAuth) IsValid() bool {
+	switch c {
+	case ClientAuthNone, ClientAuthOptional, ClientAuthRequire:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c ClientAuth) tlsType() tls.ClientAuthType {
+	switch c {
+	case ClientAuthOptional:
+		return tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		return tls.RequireAndVerifyClientCert
+	default:
+		return tls.NoClientCert
+	}
+}
+
+// DefaultReloadInterval is how often the certificate files are checked for changes
+const DefaultReloadInterval = 30 * time.Second
+
+// Config locates the server certificate and, for mutual TLS, the client CA bundle
+type Config struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+	// ClientAuth defaults to require when ClientCAFile is set and none otherwise
+	ClientAuth     ClientAuth
+	ReloadInterval time.Duration
+}
+
+// Enabled reports whether TLS is configured
+func (c Config) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// validate checks the configuration and fills in defaults
+func (c *Config) validate() error {
+	if c.CertFile == "" || c.KeyFile == "" {
+		return fmt.Errorf("both TLS_CERT_FILE and TLS_KEY_FILE are required for TLS")
+	}
+	if c.ClientAuth == "" {
+		c.ClientAuth = ClientAuthNone
+		if c.ClientCAFile != "" {
+			c.ClientAuth = ClientAuthRequire
+		}
+	}
+	if !c.ClientAuth.IsValid() {
+		return fmt.Errorf("unsupported TLS client auth mode: %s", c.ClientAuth)
+	}
+	if c.ClientAuth != ClientAuthNone && c.ClientCAFile == "" {
+		return fmt.Errorf("TLS_CLIENT_CA_FILE is required for client auth mode %s", c.ClientAuth)
+	}
+	if c.ReloadInterval <= 0 {
+		c.ReloadInterval = DefaultReloadInterval
+	}
+	return nil
+}
+
+// bundle is one consistent generation of loaded certificate material
+type bundle struct {
+	cert     *tls.Certificate
+	leaf     *x509.Certificate
+	clientCA *x509.CertPool
+	// caCerts are kept for the expiry metric
+	caCerts []*x509.Certificate
+}
+
+// Reloader serves TLS from certificate files and picks up rotated files
+// without a restart. Handshakes always see a consistent certificate and CA pool.
+type Reloader struct {
+	config  Config
+	current atomic.Pointer[bundle]
+	// mu serialises reloads and guards modTime
+	mu      sync.Mutex
+	modTime map[string]time.Time
+	metrics *CertMetrics
+	logger  *zap.Logger
+
+	started  atomic.Bool
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewReloader loads the certificate files; unreadable or invalid files are fatal at startup
+func NewReloader(config Config, tel *telemetry.Telemetry, logger *zap.Logger) (*Reloader, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	r := &Reloader{
+		config:  config,
+		modTime: make(map[string]time.Time),
+		logger:  logger.Named("tls"),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	r.metrics = NewCertMetrics(tel.Meter, r, logger.Named("metrics"))
+	return r, nil
+}
+
+// Reload reads the certificate files. On failure the previous material stays in use.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cert, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse TLS certificate: %w", err)
+	}
+	cert.Leaf = leaf
+	b := &bundle{cert: &cert, leaf: leaf}
+
+	if r.config.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.config.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read TLS client CA file: %w", err)
+		}
+		b.caCerts, err = parsePEMCertificates(pem)
+		if err != nil {
+			return fmt.Errorf("failed to parse TLS client CA file: %w", err)
+		}
+		b.clientCA = x509.NewCertPool()
+		for _, ca := range b.caCerts {
+			b.clientCA.AddCert(ca)
+		}
+	}
+
+	r.current.Store(b)
+	for _, path := range r.files() {
+		if info, err := os.Stat(path); err == nil {
+			r.modTime[path] = info.ModTime()
+		}
+	}
+
+	r.logger.Info("TLS certificate loaded",
+		zap.String("subject", leaf.Subject.String()),
+		zap.Strings("dns_names", leaf.DNSNames),
+		zap.Time("not_after", leaf.NotAfter),
+		zap.Int("client_cas", len(b.caCerts)))
+	return nil
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.config.CertFile, r.config.KeyFile}
+	if r.config.ClientCAFile != "" {
+		files = append(files, r.config.ClientCAFile)
+	}
+	return files
+}
+
+// changed reports whether any certificate file was modified since the last load
+func (r *Reloader) changed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, path := range r.files() {
+		info, err := os.Stat(path)
+		if err != nil {
+			// Rotation tools may briefly remove files; retry on the next tick
+			continue
+		}
+		if !info.ModTime().Equal(r.modTime[path]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Start checks the files every reload interval and reloads them on change
+func (r *Reloader) Start() {
+	if !r.started.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.config.ReloadInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				if !r.changed() {
+					continue
+				}
+				err := r.Reload()
+				if err != nil {
+					r.logger.Error("failed to reload TLS certificate, keeping previous certificate", zap.Error(err))
+				}
+				r.metrics.recordReload(err)
+			}
+		}
+	}()
+}
+
+// Close stops watching the certificate files
+func (r *Reloader) Close() error {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+	if r.started.Load() {
+		<-r.done
+	}
+	return nil
+}
+
+// TLSConfig returns a server configuration that always uses the latest certificate and client CAs
+func (r *Reloader) TLSConfig() *tls.Config {
+	base := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: r.config.ClientAuth.tlsType(),
+		// Set here because the per-handshake config does not inherit protocols
+		// that net/http and gRPC add to their own copies
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		b := r.current.Load()
+		cfg := base.Clone()
+		cfg.GetConfigForClient = nil
+		cfg.Certificates = []tls.Certificate{*b.cert}
+		cfg.ClientCAs = b.clientCA
+		return cfg, nil
+	}
+	return base
+}
+
+// ClientAuth returns the effective client certificate mode
+func (r *Reloader) ClientAuth() ClientAuth {
+	return r.config.ClientAuth
+}
+
+// parsePEMCertificates returns every certificate in a PEM bundle
+func parsePEMCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificates found")
+	}
+	return certs, nil
+}
@@ -0,0 +1,273 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shaibs3/Torq/internal/auth"
+	"github.com/shaibs3/Torq/internal/bench"
+	"github.com/shaibs3/Torq/internal/config"
+	"github.com/shaibs3/Torq/internal/finder"
+	"github.com/shaibs3/Torq/internal/limiter"
+	"github.com/shaibs3/Torq/internal/lookup"
+	"github.com/shaibs3/Torq/internal/router"
+	"github.com/shaibs3/Torq/internal/telemetry"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.uber.org/zap"
+)
+
+// Modes of the bench command
+const (
+	benchModeProvider = "provider"
+	benchModeHandler  = "handler"
+	benchModeHTTP     = "http"
+)
+
+// sampleSize is the length of the IP sequence the lookups cycle through
+const sampleSize = 1 << 16
+
+// runBench drives lookups against the configured provider, the API handler served
+// in-process or a running instance, and reports their latency and outcomes
+func runBench(ctx context.Context, args []string, streams Streams) int {
+	flags := flag.NewFlagSet("torq bench", flag.ContinueOnError)
+	flags.SetOutput(streams.Err)
+	mode := flags.String("mode", benchModeProvider, "what to drive: provider, handler (the API served in-process) or http")
+	target := flags.String("url", "http://localhost:8080", "base URL of the Torq API in http mode")
+	apiKey := flags.String("api-key", "", "API key sent in the X-API-Key header in http mode")
+	rps := flags.Float64("rps", 0, "lookups started per second; 0 runs them back to back")
+	concurrency := flags.Int("concurrency", runtime.NumCPU(), "number of lookups in flight at most")
+	duration := flags.Duration("duration", 10*time.Second, "how long to run; 0 runs until -requests are done")
+	requests := flags.Int("requests", 0, "stop after this many lookups; 0 runs for -duration")
+	ipsFile := flags.String("ips", "", "CSV dataset or list of IP addresses to sample; defaults to the configured provider's rows")
+	missRatio := flags.Float64("miss-ratio", 0, "share of lookups for addresses missing from the dataset, between 0 and 1")
+	seed := flags.Uint64("seed", 1, "seed of the IP sample; the same seed replays the same lookups")
+	rateLimit := flags.Bool("rate-limit", false, "apply the configured default rate limit in handler mode")
+	timeout := flags.Duration("timeout", 5*time.Second, "timeout of each request in handler and http modes")
+	output := flags.String("output", outputText, "report format: text or json")
+	configArgs := config.BindFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: torq bench [flags]")
+		fmt.Fprintln(flags.Output(), "\nLooks up IP addresses sampled from a dataset at a fixed rate and concurrency, and reports")
+		fmt.Fprintln(flags.Output(), "latency percentiles, a latency histogram, outcomes and throughput.")
+		fmt.Fprintln(flags.Output(), "\nFlags:")
+		printFlags(flags, "mode", "url", "api-key", "rps", "concurrency", "duration", "requests", "ips", "miss-ratio", "seed", "rate-limit", "timeout", "output")
+	}
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	opts := bench.Options{RPS: *rps, Concurrency: *concurrency, Duration: *duration, Requests: *requests}
+	problems := []string{}
+	if err := opts.Validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if !slices.Contains([]string{benchModeProvider, benchModeHandler, benchModeHTTP}, *mode) {
+		problems = append(problems, fmt.Sprintf("unknown mode %q: must be provider, handler or http", *mode))
+	}
+	if *output != outputText && *output != outputJSON {
+		problems = append(problems, fmt.Sprintf("unknown output format %q: must be text or json", *output))
+	}
+	if *missRatio < 0 || *missRatio > 1 {
+		problems = append(problems, "-miss-ratio must be between 0 and 1")
+	}
+	if flags.NArg() > 0 {
+		problems = append(problems, "unexpected arguments: "+strings.Join(flags.Args(), " "))
+	}
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Fprintf(streams.Err, "torq bench: %s\n", problem)
+		}
+		return ExitUsage
+	}
+
+	log := newLogger()
+	defer log.Sync() //nolint:errcheck
+
+	// The provider serves provider and handler modes, and the IP sample when -ips is not given
+	var cfg *config.Config
+	var provider lookup.DbProvider
+	if *mode != benchModeHTTP || *ipsFile == "" {
+		var err error
+		if cfg, err = config.Load(configArgs(), zap.NewNop()); err != nil {
+			printError(streams.Err, err)
+			return ExitFailure
+		}
+		if provider, err = newProviderFactory(log).CreateProvider(cfg.ProviderJSON()); err != nil {
+			printError(streams.Err, err)
+			return ExitFailure
+		}
+		defer lookup.CloseProvider(provider) //nolint:errcheck
+	}
+
+	ips, err := benchIPs(ctx, *ipsFile, provider)
+	if err != nil {
+		printError(streams.Err, err)
+		return ExitFailure
+	}
+	if len(ips) == 0 && *missRatio < 1 {
+		fmt.Fprintln(streams.Err, "torq bench: no IP addresses to sample; use -ips or -miss-ratio 1")
+		return ExitUsage
+	}
+	ips = bench.Sample(ips, sampleSize, *missRatio, *seed)
+
+	var run bench.Target
+	switch *mode {
+	case benchModeProvider:
+		run = bench.ProviderTarget(provider)
+	case benchModeHandler:
+		srv, err := newBenchServer(cfg, provider, *rateLimit, log)
+		if err != nil {
+			printError(streams.Err, err)
+			return ExitFailure
+		}
+		defer srv.Close()
+		client := srv.Client()
+		client.Timeout = *timeout
+		transport := client.Transport.(*http.Transport)
+		transport.MaxIdleConnsPerHost = *concurrency
+		run = bench.HTTPTarget(client, srv.URL, nil)
+	case benchModeHTTP:
+		header := http.Header{}
+		if *apiKey != "" {
+			header.Set(auth.APIKeyHeader, *apiKey)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.MaxIdleConnsPerHost = *concurrency
+		run = bench.HTTPTarget(&http.Client{Transport: transport, Timeout: *timeout}, *target, header)
+	}
+
+	result, err := bench.Run(ctx, run, ips, opts)
+	if err != nil {
+		printError(streams.Err, err)
+		return ExitFailure
+	}
+	if err := writeBenchResult(streams.Out, *output, result); err != nil {
+		printError(streams.Err, fmt.Errorf("failed to write result: %w", err))
+		return ExitFailure
+	}
+	if ctx.Err() != nil {
+		printError(streams.Err, fmt.Errorf("interrupted: %w", ctx.Err()))
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+// benchIPs reads the IPs to sample from the first column of path, a CSV dataset or a
+// list with one IP per line, or lists the provider's rows
+func benchIPs(ctx context.Context, path string, provider lookup.DbProvider) ([]string, error) {
+	var ips []string
+	if path == "" {
+		err := lookup.ListRows(ctx, provider, func(ip, city, country string) error {
+			ips = append(ips, ip)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the provider's rows: %w", err)
+		}
+		return ips, nil
+	}
+
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("failed to open IP list: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ip, _, _ := strings.Cut(line, ",")
+		ips = append(ips, strings.TrimSpace(ip))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read IP list: %w", err)
+	}
+	return ips, nil
+}
+
+// newBenchServer serves the public API over loopback, with the production router and
+// FindIpHandler, optionally behind the configured default rate limit
+func newBenchServer(cfg *config.Config, provider lookup.DbProvider, rateLimit bool, log *zap.Logger) (*httptest.Server, error) {
+	// The limiter warns about every rejected request, which is the point of the run
+	log = log.WithOptions(zap.IncreaseLevel(zap.ErrorLevel))
+	policies := limiter.NewPolicySet()
+	if rateLimit {
+		window, err := time.ParseDuration(cfg.RateLimitWindow)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RATE_LIMIT_WINDOW: %w", err)
+		}
+		factory, err := limiter.NewFactory(limiter.FactoryConfig{Backend: limiter.BackendMemory}, log)
+		if err != nil {
+			return nil, err
+		}
+		rl, err := factory.New(limiter.LimitSpec{
+			Name:      limiter.DefaultPolicyName,
+			Algorithm: limiter.Algorithm(cfg.RateLimitAlgo),
+			RPS:       cfg.RPSLimit,
+			Burst:     cfg.RPSBurst,
+			Limit:     cfg.RPSLimit,
+			Window:    window,
+		})
+		if err != nil {
+			return nil, err
+		}
+		policies = limiter.NewPolicySet(limiter.NewDefaultPolicy(rl))
+	}
+	tel := &telemetry.Telemetry{Meter: noop.NewMeterProvider().Meter("torq-bench")}
+	r := router.NewRouter(policies, tel, log, router.WithAccessLog(router.AccessLogOff))
+	return httptest.NewServer(r.CreateServer(":0", finder.NewIpFinder(provider)).Handler), nil
+}
+
+// writeBenchResult writes the result as JSON, or as a summary followed by the histogram
+func writeBenchResult(w io.Writer, output string, result bench.Result) error {
+	if output == outputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+	fmt.Fprintf(w, "requests:   %d in %s\n", result.Requests, result.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "throughput: %.1f/s\n", result.Throughput)
+	l := result.Latency
+	fmt.Fprintf(w, "latency:    min %s  mean %s  p50 %s  p90 %s  p99 %s  p999 %s  max %s\n",
+		l.Min, l.Mean, l.P50, l.P90, l.P99, l.P999, l.Max)
+	fmt.Fprintf(w, "outcomes:   ok %d  not found %d  rate limited %d  errors %d\n",
+		result.Outcomes[bench.OutcomeOK], result.Outcomes[bench.OutcomeNotFound],
+		result.Outcomes[bench.OutcomeRateLimited], result.Outcomes[bench.OutcomeError])
+	for _, msg := range slices.Sorted(maps.Keys(result.Errors)) {
+		fmt.Fprintf(w, "  %6d  %s\n", result.Errors[msg], msg)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nLATENCY\tCOUNT\t")
+	peak := 0
+	for _, b := range result.Histogram {
+		peak = max(peak, b.Count)
+	}
+	for _, b := range result.Histogram {
+		bound := "+Inf"
+		if b.UpperBound > 0 {
+			bound = "<= " + b.UpperBound.String()
+		}
+		bar := ""
+		if peak > 0 {
+			bar = strings.Repeat("#", b.Count*40/peak)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", bound, b.Count, bar)
+	}
+	return tw.Flush()
+}
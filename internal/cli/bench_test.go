@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/shaibs3/Torq/internal/bench"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBench(t *testing.T) {
+	provider := writeCSV(t, "1.2.3.4,Paris,France\n5.6.7.8,Berlin,Germany\n")
+
+	for _, mode := range []string{"provider", "handler"} {
+		t.Run(mode, func(t *testing.T) {
+			args := append([]string{"bench", "-mode", mode, "-requests", "200", "-concurrency", "4", "-miss-ratio", "0.5", "-output", "json"}, provider...)
+			code, stdout, stderr := run(t, "", args...)
+			require.Equal(t, ExitOK, code, stderr)
+			var result bench.Result
+			require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+			assert.Equal(t, 200, result.Requests)
+			assert.Positive(t, result.Outcomes[bench.OutcomeOK])
+			assert.Positive(t, result.Outcomes[bench.OutcomeNotFound])
+			assert.Zero(t, result.Outcomes[bench.OutcomeError], result.Errors)
+		})
+	}
+
+	t.Run("rate limited handler", func(t *testing.T) {
+		args := append([]string{"bench", "-mode", "handler", "-rate-limit", "-rps-limit", "1", "-rps-burst", "5", "-requests", "50"}, provider...)
+		code, stdout, _ := run(t, "", args...)
+		assert.Equal(t, ExitOK, code)
+		assert.Contains(t, stdout, "ok 5  not found 0  rate limited 45  errors 0")
+		assert.Contains(t, stdout, "p999")
+	})
+
+	t.Run("invalid usage", func(t *testing.T) {
+		code, _, stderr := run(t, "", append([]string{"bench", "-mode", "grpc", "-concurrency", "0", "-miss-ratio", "2"}, provider...)...)
+		assert.Equal(t, ExitUsage, code)
+		assert.Contains(t, stderr, `unknown mode "grpc"`)
+		assert.Contains(t, stderr, "concurrency must be at least 1")
+		assert.Contains(t, stderr, "-miss-ratio must be between 0 and 1")
+	})
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/shaibs3/Torq/internal/config"
+	"github.com/shaibs3/Torq/internal/logger"
+	"github.com/shaibs3/Torq/internal/lookup"
+	"github.com/shaibs3/Torq/internal/telemetry"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.uber.org/zap"
+)
+
+// Exit codes returned by Run
+const (
+	ExitOK = 0
+	// ExitFailure reports a failure that is not the caller's fault, e.g. a provider that fails to load
+	ExitFailure = 1
+	// ExitUsage reports invalid input: unknown commands or flags, invalid IP addresses or
+	// a dataset with errors
+	ExitUsage = 2
+)
+
+// Streams are the standard streams of a command
+type Streams struct {
+	In  io.Reader
+	Out io.Writer
+	Err io.Writer
+}
+
+// command is a torq subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string, streams Streams) int
+}
+
+// commands lists the subcommands served by Run; serve is handled by main
+var commands = []command{
+	{name: "lookup", summary: "Resolve IP addresses against the configured provider, without a server", run: runLookup},
+	{name: "diff", summary: "Report what changed between two datasets, e.g. a CSV file and the live table", run: runDiff},
+	{name: "bench", summary: "Measure lookup latency and throughput of a provider, the API handler or a running server", run: runBench},
+	{name: "validate-data", summary: "Check a dataset file or table for errors before it is deployed", run: runValidateData},
+}
+
+// IsServe reports whether args start the server: no subcommand, only flags, or "serve"
+func IsServe(args []string) bool {
+	return len(args) == 0 || strings.HasPrefix(args[0], "-") || args[0] == "serve"
+}
+
+// Run runs the subcommand named by args[0] and returns the process exit code
+func Run(ctx context.Context, args []string, streams Streams) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(streams.Out)
+		return ExitOK
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(ctx, args[1:], streams)
+		}
+	}
+	fmt.Fprintf(streams.Err, "torq: unknown command %q\n\n", args[0])
+	printUsage(streams.Err)
+	return ExitUsage
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: torq <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	fmt.Fprintf(w, "  %-14s %s\n", "serve", "Start the API, gRPC, ops and admin servers (the default)")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'torq <command> -h' for the flags of a command.")
+}
+
+// parseFlags parses a command's flags. It returns false and the exit code when the
+// command should stop, e.g. after -h.
+func parseFlags(flags *flag.FlagSet, args []string) (int, bool) {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK, false
+		}
+		return ExitUsage, false
+	}
+	return ExitOK, true
+}
+
+// printFlags prints the usage of a command's own flags, leaving out the configuration
+// flags shared with serve
+func printFlags(flags *flag.FlagSet, own ...string) {
+	names := make(map[string]bool, len(own))
+	for _, name := range own {
+		names[name] = true
+	}
+	flags.VisitAll(func(f *flag.Flag) {
+		if names[f.Name] {
+			fmt.Fprintf(flags.Output(), "  -%s\n    \t%s (default %q)\n", f.Name, f.Usage, f.DefValue)
+		}
+	})
+	fmt.Fprintln(flags.Output(), "\nThe provider is configured like the server: -config, -ip-db-config, -provider-type and the")
+	fmt.Fprintln(flags.Output(), "other server flags are accepted, as are CONFIG_FILE, IP_DB_CONFIG and the other variables.")
+}
+
+// newLogger reports warnings and errors on stderr; commands print their results on stdout
+func newLogger() *zap.Logger {
+	l, err := logger.NewLogger("production", "warn")
+	if err != nil {
+		return zap.NewNop()
+	}
+	return l
+}
+
+// openProvider creates the IP database provider configured by the config file, the
+// environment and args, exactly as the server does
+func openProvider(args []string, log *zap.Logger) (lookup.DbProvider, error) {
+	cfg, err := config.Load(args, zap.NewNop())
+	if err != nil {
+		return nil, err
+	}
+	return newProviderFactory(log).CreateProvider(cfg.ProviderJSON())
+}
+
+// newProviderFactory creates providers as the server does, without recording metrics
+func newProviderFactory(log *zap.Logger) *lookup.DbProviderFactory {
+	tel := &telemetry.Telemetry{Meter: noop.NewMeterProvider().Meter("torq-cli")}
+	return lookup.NewDbProviderFactory(log, tel)
+}
+
+// printError reports err on stderr, listing every configuration problem
+func printError(w io.Writer, err error) {
+	var cfgErr *config.Error
+	if errors.As(err, &cfgErr) {
+		fmt.Fprintln(w, "torq: invalid configuration:")
+		for _, problem := range cfgErr.Problems {
+			fmt.Fprintf(w, "  %v\n", problem)
+		}
+		return
+	}
+	fmt.Fprintf(w, "torq: %v\n", err)
+}
@@ -0,0 +1,182 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/shaibs3/Torq/internal/config"
+	"github.com/shaibs3/Torq/internal/dataset"
+	"github.com/shaibs3/Torq/internal/lookup"
+	"github.com/shaibs3/Torq/internal/redact"
+	"go.uber.org/zap"
+)
+
+// runDiff reports the entries added, removed and changed between two datasets, with
+// the addresses each country gained and lost
+func runDiff(ctx context.Context, args []string, streams Streams) int {
+	flags := flag.NewFlagSet("torq diff", flag.ContinueOnError)
+	flags.SetOutput(streams.Err)
+	output := flags.String("output", outputText, "report format: text or json")
+	summary := flags.Bool("summary", false, "report the counts and country statistics without listing every entry")
+	configArgs := config.BindFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: torq diff [flags] [old] new")
+		fmt.Fprintln(flags.Output(), "\nReports what changed from the old dataset to the new one. A dataset is a CSV file, a")
+		fmt.Fprintln(flags.Output(), "postgres:// URL of a database with an ip_locations table, or a provider configuration in")
+		fmt.Fprintln(flags.Output(), "the JSON form of IP_DB_CONFIG. Without old, new is compared with the configured provider.")
+		fmt.Fprintln(flags.Output(), "\nFlags:")
+		printFlags(flags, "output", "summary")
+	}
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	switch *output {
+	case outputText, outputJSON:
+	default:
+		fmt.Fprintf(streams.Err, "torq diff: unknown output format %q: must be text or json\n", *output)
+		return ExitUsage
+	}
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		fmt.Fprintln(streams.Err, "torq diff: expected the old and new datasets, or only the new one")
+		return ExitUsage
+	}
+
+	var sources []lookup.DbProviderConfig
+	if flags.NArg() == 1 {
+		cfg, err := config.Load(configArgs(), zap.NewNop())
+		if err != nil {
+			printError(streams.Err, err)
+			return ExitFailure
+		}
+		provider := cfg.Provider()
+		sources = append(sources, lookup.DbProviderConfig{DbType: lookup.DbType(provider.DbType), ExtraDetails: provider.ExtraDetails})
+	}
+	for _, arg := range flags.Args() {
+		source, err := parseSource(arg)
+		if err != nil {
+			fmt.Fprintf(streams.Err, "torq diff: %v\n", err)
+			return ExitUsage
+		}
+		sources = append(sources, source)
+	}
+
+	log := newLogger()
+	defer log.Sync() //nolint:errcheck
+	snapshots := make([]dataset.Snapshot, len(sources))
+	for i, source := range sources {
+		var err error
+		if snapshots[i], err = loadSnapshot(ctx, source, log); err != nil {
+			printError(streams.Err, fmt.Errorf("failed to load %s: %w", sourceName(source), err))
+			return ExitFailure
+		}
+	}
+
+	diff := dataset.Compare(sourceName(sources[0]), snapshots[0], sourceName(sources[1]), snapshots[1])
+	if *summary {
+		diff.Added, diff.Removed, diff.Changed = nil, nil, nil
+	}
+	if err := writeDiff(streams.Out, *output, diff); err != nil {
+		printError(streams.Err, fmt.Errorf("failed to write diff: %w", err))
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+// parseSource reads a dataset argument: a provider configuration in JSON, a Postgres
+// URL or a CSV file path
+func parseSource(arg string) (lookup.DbProviderConfig, error) {
+	switch {
+	case strings.HasPrefix(strings.TrimSpace(arg), "{"):
+		var source lookup.DbProviderConfig
+		if err := json.Unmarshal([]byte(arg), &source); err != nil {
+			return source, fmt.Errorf("invalid provider configuration: %w", err)
+		}
+		return source, nil
+	case strings.HasPrefix(arg, "postgres://"), strings.HasPrefix(arg, "postgresql://"):
+		return lookup.DbProviderConfig{DbType: lookup.DbTypePostgres, ExtraDetails: map[string]any{"conn_str": arg}}, nil
+	}
+	return lookup.DbProviderConfig{DbType: lookup.DbTypeCSV, ExtraDetails: map[string]any{"file_path": arg}}, nil
+}
+
+// sourceName names a dataset in reports, without the password of its connection string
+func sourceName(source lookup.DbProviderConfig) string {
+	switch source.DbType {
+	case lookup.DbTypeCSV:
+		path, _ := source.ExtraDetails["file_path"].(string)
+		return path
+	case lookup.DbTypePostgres:
+		if file, _ := source.ExtraDetails["conn_str_file"].(string); file != "" {
+			return "postgres:" + file
+		}
+		connStr, _ := source.ExtraDetails["conn_str"].(string)
+		return "postgres:" + redact.MaskURL(connStr)
+	}
+	return source.DbType.String()
+}
+
+// loadSnapshot loads a dataset through its provider, as the server would serve it
+func loadSnapshot(ctx context.Context, source lookup.DbProviderConfig, log *zap.Logger) (dataset.Snapshot, error) {
+	configJSON, err := json.Marshal(source)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := newProviderFactory(log).CreateProvider(string(configJSON))
+	if err != nil {
+		return nil, err
+	}
+	defer lookup.CloseProvider(provider) //nolint:errcheck
+
+	snapshot := dataset.Snapshot{}
+	err = lookup.ListRows(ctx, provider, func(ip, city, country string) error {
+		snapshot.Add(ip, city, country)
+		return ctx.Err()
+	})
+	return snapshot, err
+}
+
+// writeDiff writes the diff as JSON, or as a summary, the country statistics and one
+// line per entry: + added, - removed and ~ changed
+func writeDiff(w io.Writer, output string, diff dataset.Diff) error {
+	if output == outputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(diff)
+	}
+	s := diff.Summary
+	fmt.Fprintf(w, "old: %s (%d entries)\nnew: %s (%d entries)\n", diff.Old, s.OldEntries, diff.New, s.NewEntries)
+	fmt.Fprintf(w, "%d added, %d removed, %d changed (%d moved to another country), %d unchanged\n",
+		s.Added, s.Removed, s.Changed, s.Moved, s.Unchanged)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if len(diff.Moves) > 0 {
+		fmt.Fprintln(tw, "\nFROM\tTO\tENTRIES\tADDRESSES")
+		for _, move := range diff.Moves {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\n", move.From, move.To, move.Entries, move.Addresses)
+		}
+	}
+	if len(diff.Countries) > 0 {
+		fmt.Fprintln(tw, "\nCOUNTRY\tADDED\tREMOVED\tMOVED IN\tMOVED OUT")
+		for _, c := range diff.Countries {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\n", c.Country, c.Added, c.Removed, c.MovedIn, c.MovedOut)
+		}
+	}
+	if len(diff.Added)+len(diff.Removed)+len(diff.Changed) > 0 {
+		fmt.Fprintln(tw)
+	}
+	for _, e := range diff.Added {
+		fmt.Fprintf(tw, "+ %s\t%s, %s\n", e.IP, e.City, e.Country)
+	}
+	for _, e := range diff.Removed {
+		fmt.Fprintf(tw, "- %s\t%s, %s\n", e.IP, e.City, e.Country)
+	}
+	for _, c := range diff.Changed {
+		fmt.Fprintf(tw, "~ %s\t%s, %s -> %s, %s\n", c.IP, c.Old.City, c.Old.Country, c.New.City, c.New.Country)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/shaibs3/Torq/internal/dataset"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	oldFlags := writeCSV(t, "1.2.3.4,Berlin,DE\n5.6.7.8,Paris,FR\n")
+	newFlags := writeCSV(t, "1.2.3.4,Amsterdam,NL\n9.9.9.9,Rome,IT\n")
+	oldPath, newPath := oldFlags[len(oldFlags)-1], newFlags[len(newFlags)-1]
+
+	t.Run("text", func(t *testing.T) {
+		code, stdout, _ := run(t, "", "diff", oldPath, newPath)
+		assert.Equal(t, ExitOK, code)
+		assert.Contains(t, stdout, "1 added, 1 removed, 1 changed (1 moved to another country), 0 unchanged")
+		assert.Contains(t, stdout, "+ 9.9.9.9")
+		assert.Contains(t, stdout, "- 5.6.7.8")
+		assert.Contains(t, stdout, "~ 1.2.3.4")
+	})
+
+	t.Run("json against the configured provider", func(t *testing.T) {
+		source := `{"dbtype":"csv","extra_details":{"file_path":"` + newPath + `"}}`
+		code, stdout, _ := run(t, "", append(append([]string{"diff", "-output", "json"}, oldFlags...), source)...)
+		assert.Equal(t, ExitOK, code)
+		var diff dataset.Diff
+		require.NoError(t, json.Unmarshal([]byte(stdout), &diff))
+		assert.Equal(t, oldPath, diff.Old)
+		assert.Equal(t, newPath, diff.New)
+		assert.Equal(t, []dataset.CountryMove{{From: "DE", To: "NL", Entries: 1, Addresses: 1}}, diff.Moves)
+		assert.Len(t, diff.Added, 1)
+	})
+
+	t.Run("summary", func(t *testing.T) {
+		code, stdout, _ := run(t, "", "diff", "-summary", "-output", "json", oldPath, newPath)
+		assert.Equal(t, ExitOK, code)
+		assert.NotContains(t, stdout, `"added": [`)
+		assert.Contains(t, stdout, `"moves"`)
+	})
+
+	t.Run("missing dataset", func(t *testing.T) {
+		code, _, stderr := run(t, "", "diff", oldPath, "missing.csv")
+		assert.Equal(t, ExitFailure, code)
+		assert.Contains(t, stderr, "failed to load missing.csv")
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		code, _, stderr := run(t, "", "diff", "a", "b", "c")
+		assert.Equal(t, ExitUsage, code)
+		assert.Contains(t, stderr, "expected the old and new datasets")
+
+		code, _, stderr = run(t, "", "diff", "{not json", newPath)
+		assert.Equal(t, ExitUsage, code)
+		assert.Contains(t, stderr, "invalid provider configuration")
+	})
+}
+
+func TestSourceName(t *testing.T) {
+	source, err := parseSource("postgres://torq:hunter2@db:5432/geo")
+	require.NoError(t, err)
+	name := sourceName(source)
+	assert.True(t, strings.HasPrefix(name, "postgres:postgres://torq:"), name)
+	assert.NotContains(t, name, "hunter2")
+
+	source, err = parseSource("data/ip_data.csv")
+	require.NoError(t, err)
+	assert.Equal(t, "data/ip_data.csv", sourceName(source))
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/shaibs3/Torq/internal/config"
+	"github.com/shaibs3/Torq/internal/finder"
+	"github.com/shaibs3/Torq/internal/lookup"
+)
+
+// Output formats of the lookup command
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+)
+
+// runLookup resolves the IPs given as arguments, or read from stdin one per line
+func runLookup(ctx context.Context, args []string, streams Streams) int {
+	flags := flag.NewFlagSet("torq lookup", flag.ContinueOnError)
+	flags.SetOutput(streams.Err)
+	output := flags.String("output", outputTable, "output format: table, json or csv")
+	concurrency := flags.Int("concurrency", runtime.NumCPU(), "number of lookups run in parallel")
+	configArgs := config.BindFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: torq lookup [flags] [ip ...]")
+		fmt.Fprintln(flags.Output(), "\nResolves each IP address to its city and country. Without arguments, IP addresses are")
+		fmt.Fprintln(flags.Output(), "read from stdin, one per line; blank lines and lines starting with # are skipped.")
+		fmt.Fprintln(flags.Output(), "\nFlags:")
+		printFlags(flags, "output", "concurrency")
+	}
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	switch *output {
+	case outputTable, outputJSON, outputCSV:
+	default:
+		fmt.Fprintf(streams.Err, "torq lookup: unknown output format %q: must be table, json or csv\n", *output)
+		return ExitUsage
+	}
+	if *concurrency < 1 {
+		fmt.Fprintf(streams.Err, "torq lookup: -concurrency must be at least 1, got %d\n", *concurrency)
+		return ExitUsage
+	}
+
+	ips := flags.Args()
+	if len(ips) == 0 {
+		var err error
+		if ips, err = readIPs(streams.In); err != nil {
+			printError(streams.Err, fmt.Errorf("failed to read IP addresses from stdin: %w", err))
+			return ExitFailure
+		}
+	}
+	if len(ips) == 0 {
+		fmt.Fprintln(streams.Err, "torq lookup: no IP addresses given")
+		return ExitUsage
+	}
+
+	log := newLogger()
+	defer log.Sync() //nolint:errcheck
+	provider, err := openProvider(configArgs(), log)
+	if err != nil {
+		printError(streams.Err, err)
+		return ExitFailure
+	}
+	defer lookup.CloseProvider(provider) //nolint:errcheck
+
+	results, invalid := lookupAll(ctx, provider, ips, *concurrency)
+	if err := writeResults(streams.Out, *output, results); err != nil {
+		printError(streams.Err, fmt.Errorf("failed to write results: %w", err))
+		return ExitFailure
+	}
+	if ctx.Err() != nil {
+		printError(streams.Err, fmt.Errorf("interrupted: %w", ctx.Err()))
+		return ExitFailure
+	}
+	if invalid > 0 {
+		fmt.Fprintf(streams.Err, "torq lookup: %d of %d IP addresses are invalid\n", invalid, len(ips))
+		return ExitUsage
+	}
+	return ExitOK
+}
+
+// readIPs reads one IP address per line, skipping blank lines and # comments
+func readIPs(r io.Reader) ([]string, error) {
+	var ips []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ips = append(ips, line)
+	}
+	return ips, scanner.Err()
+}
+
+// lookupAll resolves ips with up to concurrency lookups in flight and returns the
+// results in input order, with the number of invalid addresses. Invalid addresses are
+// reported like the batch API does, without reaching the provider.
+func lookupAll(ctx context.Context, provider lookup.DbProvider, ips []string, concurrency int) ([]finder.LookupResult, int) {
+	results := make([]finder.LookupResult, len(ips))
+	invalid := 0
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(concurrency, len(ips)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = lookupOne(ctx, provider, ips[i])
+			}
+		}()
+	}
+	for i, ip := range ips {
+		if err := finder.ValidateIP(ip); err != nil {
+			results[i] = finder.LookupResult{IP: ip, Error: err.Error()}
+			invalid++
+			continue
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results, invalid
+}
+
+func lookupOne(ctx context.Context, provider lookup.DbProvider, ip string) finder.LookupResult {
+	result := finder.LookupResult{IP: ip}
+	if err := ctx.Err(); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	city, country, err := provider.Lookup(ctx, ip)
+	if err != nil {
+		result.Error = "IP not found"
+		return result
+	}
+	result.City = city
+	result.Country = country
+	return result
+}
+
+// writeResults writes results as an aligned table, or in the JSON and CSV formats of the HTTP API
+func writeResults(w io.Writer, output string, results []finder.LookupResult) error {
+	switch output {
+	case outputJSON:
+		return finder.JSONEncoder{}.EncodeBatch(w, results)
+	case outputCSV:
+		return finder.CSVEncoder{}.EncodeBatch(w, results)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "IP\tCITY\tCOUNTRY\tERROR")
+	for _, result := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", result.IP, result.City, result.Country, result.Error)
+	}
+	return tw.Flush()
+}
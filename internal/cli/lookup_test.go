@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shaibs3/Torq/internal/finder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCSV writes a CSV provider data file and returns the flags that select it
+func writeCSV(t *testing.T, rows string) []string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ip_data.csv")
+	require.NoError(t, os.WriteFile(path, []byte(rows), 0o600))
+	return []string{"-provider-type", "csv", "-provider-csv-file-path", path}
+}
+
+func run(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := Run(context.Background(), args, Streams{In: strings.NewReader(stdin), Out: &stdout, Err: &stderr})
+	return code, stdout.String(), stderr.String()
+}
+
+func TestLookup(t *testing.T) {
+	provider := writeCSV(t, "1.2.3.4,Paris,France\n5.6.7.8,Berlin,Germany\n")
+
+	t.Run("table", func(t *testing.T) {
+		code, stdout, _ := run(t, "", append([]string{"lookup"}, append(provider, "1.2.3.4", "9.9.9.9")...)...)
+		assert.Equal(t, ExitOK, code)
+		lines := strings.Split(strings.TrimSpace(stdout), "\n")
+		require.Len(t, lines, 3)
+		assert.Equal(t, []string{"IP", "CITY", "COUNTRY", "ERROR"}, strings.Fields(lines[0]))
+		assert.Equal(t, []string{"1.2.3.4", "Paris", "France"}, strings.Fields(lines[1]))
+		assert.Equal(t, []string{"9.9.9.9", "IP", "not", "found"}, strings.Fields(lines[2]))
+	})
+
+	t.Run("json from stdin", func(t *testing.T) {
+		stdin := "# analyst list\n5.6.7.8\n\n 1.2.3.4 \n"
+		code, stdout, _ := run(t, stdin, append([]string{"lookup", "-output", "json", "-concurrency", "4"}, provider...)...)
+		assert.Equal(t, ExitOK, code)
+		var results []finder.LookupResult
+		require.NoError(t, json.Unmarshal([]byte(stdout), &results))
+		assert.Equal(t, []finder.LookupResult{
+			{IP: "5.6.7.8", City: "Berlin", Country: "Germany"},
+			{IP: "1.2.3.4", City: "Paris", Country: "France"},
+		}, results)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		code, stdout, _ := run(t, "", append([]string{"lookup", "-output", "csv"}, append(provider, "1.2.3.4")...)...)
+		assert.Equal(t, ExitOK, code)
+		rows, err := csv.NewReader(strings.NewReader(stdout)).ReadAll()
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{{"ip", "city", "country", "override", "error"}, {"1.2.3.4", "Paris", "France", "false", ""}}, rows)
+	})
+
+	t.Run("invalid IP", func(t *testing.T) {
+		code, stdout, stderr := run(t, "", append([]string{"lookup"}, append(provider, "1.2.3.4", "bogus")...)...)
+		assert.Equal(t, ExitUsage, code)
+		assert.Contains(t, stdout, "invalid IP address format: bogus")
+		assert.Contains(t, stdout, "Paris", "valid addresses are still resolved")
+		assert.Contains(t, stderr, "1 of 2 IP addresses are invalid")
+	})
+}
+
+func TestLookup_InvalidUsage(t *testing.T) {
+	provider := writeCSV(t, "1.2.3.4,Paris,France\n")
+
+	tests := []struct {
+		name   string
+		args   []string
+		stdin  string
+		code   int
+		stderr string
+	}{
+		{name: "unknown output", args: []string{"-output", "yaml", "1.2.3.4"}, code: ExitUsage, stderr: `unknown output format "yaml"`},
+		{name: "bad concurrency", args: []string{"-concurrency", "0", "1.2.3.4"}, code: ExitUsage, stderr: "-concurrency must be at least 1"},
+		{name: "unknown flag", args: []string{"-colour", "1.2.3.4"}, code: ExitUsage, stderr: "flag provided but not defined"},
+		{name: "no addresses", stdin: "\n# nothing\n", code: ExitUsage, stderr: "no IP addresses given"},
+		{name: "no provider", args: []string{"-provider-type", "", "1.2.3.4"}, code: ExitFailure, stderr: "invalid configuration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, stderr := run(t, tt.stdin, append(append([]string{"lookup"}, provider...), tt.args...)...)
+			assert.Equal(t, tt.code, code)
+			assert.Contains(t, stderr, tt.stderr)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	code, stdout, _ := run(t, "", "help")
+	assert.Equal(t, ExitOK, code)
+	assert.Contains(t, stdout, "lookup")
+
+	code, _, stderr := run(t, "", "frobnicate")
+	assert.Equal(t, ExitUsage, code)
+	assert.Contains(t, stderr, `unknown command "frobnicate"`)
+
+	assert.True(t, IsServe(nil))
+	assert.True(t, IsServe([]string{"-port", "8080"}))
+	assert.True(t, IsServe([]string{"serve"}))
+	assert.False(t, IsServe([]string{"lookup", "1.2.3.4"}))
+}
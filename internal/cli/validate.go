@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/shaibs3/Torq/internal/config"
+	"github.com/shaibs3/Torq/internal/dataset"
+	"github.com/shaibs3/Torq/internal/lookup"
+	"go.uber.org/zap"
+)
+
+// outputText is the default report format of the validate-data command
+const outputText = "text"
+
+// runValidateData validates a dataset file, or the file or table of the configured
+// provider, and fails when it has errors
+func runValidateData(ctx context.Context, args []string, streams Streams) int {
+	flags := flag.NewFlagSet("torq validate-data", flag.ContinueOnError)
+	flags.SetOutput(streams.Err)
+	output := flags.String("output", outputText, "report format: text or json")
+	strict := flags.Bool("strict", false, "fail on warnings as well as errors")
+	configArgs := config.BindFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: torq validate-data [flags] [file]")
+		fmt.Fprintln(flags.Output(), "\nValidates a CSV dataset before it is deployed; - reads it from stdin. Without a file, the")
+		fmt.Fprintln(flags.Output(), "CSV file or ip_locations table of the configured provider is validated. Exits with 2 when")
+		fmt.Fprintln(flags.Output(), "the dataset has errors.")
+		fmt.Fprintln(flags.Output(), "\nFlags:")
+		printFlags(flags, "output", "strict")
+	}
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	switch *output {
+	case outputText, outputJSON:
+	default:
+		fmt.Fprintf(streams.Err, "torq validate-data: unknown output format %q: must be text or json\n", *output)
+		return ExitUsage
+	}
+	if flags.NArg() > 1 {
+		fmt.Fprintln(streams.Err, "torq validate-data: at most one file can be validated at a time")
+		return ExitUsage
+	}
+
+	log := newLogger()
+	defer log.Sync() //nolint:errcheck
+	var report dataset.Report
+	var err error
+	switch path := flags.Arg(0); path {
+	case "":
+		report, err = validateConfigured(ctx, configArgs(), log)
+	case "-":
+		report, err = dataset.ValidateCSV("stdin", streams.In)
+	default:
+		report, err = validateFile(path)
+	}
+	if err != nil {
+		printError(streams.Err, err)
+		return ExitFailure
+	}
+
+	if err := writeReport(streams.Out, *output, report); err != nil {
+		printError(streams.Err, fmt.Errorf("failed to write report: %w", err))
+		return ExitFailure
+	}
+	if !report.Valid(*strict) {
+		fmt.Fprintf(streams.Err, "torq validate-data: %s has %d errors and %d warnings\n", report.Source, report.Errors, report.Warnings)
+		return ExitUsage
+	}
+	return ExitOK
+}
+
+func validateFile(path string) (dataset.Report, error) {
+	f, err := os.Open(path) // #nosec G304
+	if err != nil {
+		return dataset.Report{}, fmt.Errorf("failed to open dataset: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+	return dataset.ValidateCSV(path, f)
+}
+
+// validateConfigured validates the dataset of the provider configured by the config
+// file, the environment and args, exactly as the server loads it
+func validateConfigured(ctx context.Context, args []string, log *zap.Logger) (dataset.Report, error) {
+	cfg, err := config.Load(args, zap.NewNop())
+	if err != nil {
+		return dataset.Report{}, err
+	}
+	provider := cfg.Provider()
+	switch lookup.DbType(provider.DbType) {
+	case lookup.DbTypeCSV:
+		path, _ := provider.ExtraDetails["file_path"].(string)
+		return validateFile(path)
+	case lookup.DbTypePostgres:
+		pg, err := lookup.NewPostgresProvider(lookup.DbProviderConfig{DbType: lookup.DbTypePostgres, ExtraDetails: provider.ExtraDetails}, log, nil)
+		if err != nil {
+			return dataset.Report{}, err
+		}
+		defer pg.Close() //nolint:errcheck
+		v := dataset.NewValidator("postgres:ip_locations")
+		line := 0
+		err = pg.Rows(ctx, func(ip, city, country string) error {
+			line++
+			v.Check(line, []string{ip, city, country})
+			return ctx.Err()
+		})
+		if err != nil {
+			return dataset.Report{}, err
+		}
+		return v.Report(), nil
+	}
+	return dataset.Report{}, fmt.Errorf("unsupported database type: %s", provider.DbType)
+}
+
+// writeReport writes the report as JSON, or as one line per issue followed by a summary
+func writeReport(w io.Writer, output string, report dataset.Report) error {
+	if output == outputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	for _, issue := range report.Issues {
+		fmt.Fprintf(w, "%s:%d: %s: %s: %s", report.Source, issue.Line, issue.Severity, issue.Code, issue.Message)
+		if issue.Value != "" {
+			fmt.Fprintf(w, " (%q)", issue.Value)
+		}
+		fmt.Fprintln(w)
+	}
+	_, err := fmt.Fprintf(w, "%s: %d rows, %d errors, %d warnings\n", report.Source, report.Rows, report.Errors, report.Warnings)
+	return err
+}
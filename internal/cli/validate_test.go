@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/shaibs3/Torq/internal/dataset"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateData(t *testing.T) {
+	valid := writeCSV(t, "1.2.3.4,Paris,France\n5.6.7.8,Berlin,Germany\n")
+	invalid := writeCSV(t, "1.2.3.4,Paris,France\n1.2.3,Berlin,Germany\n1.2.3.4,Paris,France\n")
+
+	t.Run("configured provider", func(t *testing.T) {
+		code, stdout, _ := run(t, "", append([]string{"validate-data"}, valid...)...)
+		assert.Equal(t, ExitOK, code)
+		assert.Contains(t, stdout, "2 rows, 0 errors, 0 warnings")
+	})
+
+	t.Run("file", func(t *testing.T) {
+		code, stdout, stderr := run(t, "", "validate-data", invalid[len(invalid)-1])
+		assert.Equal(t, ExitUsage, code)
+		assert.Contains(t, stdout, `:2: error: invalid_ip:`)
+		assert.Contains(t, stderr, "has 1 errors and 1 warnings")
+	})
+
+	t.Run("json from stdin", func(t *testing.T) {
+		code, stdout, _ := run(t, "1.2.3.4,Paris,France\n1.2.3.4,Paris,France\n", "validate-data", "-output", "json", "-")
+		assert.Equal(t, ExitOK, code, "warnings alone pass")
+		var report dataset.Report
+		require.NoError(t, json.Unmarshal([]byte(stdout), &report))
+		assert.Equal(t, "stdin", report.Source)
+		require.Len(t, report.Issues, 1)
+		assert.Equal(t, dataset.CodeDuplicate, report.Issues[0].Code)
+		assert.Equal(t, 1, report.Issues[0].RelatedLine)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		code, _, _ := run(t, "1.2.3.4,Paris,France\n1.2.3.4,Paris,France\n", "validate-data", "-strict", "-")
+		assert.Equal(t, ExitUsage, code)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		code, _, stderr := run(t, "", "validate-data", "does-not-exist.csv")
+		assert.Equal(t, ExitFailure, code)
+		assert.Contains(t, stderr, "failed to open dataset")
+	})
+
+	t.Run("unknown output", func(t *testing.T) {
+		code, _, stderr := run(t, "", "validate-data", "-output", "yaml", "-")
+		assert.Equal(t, ExitUsage, code)
+		assert.Contains(t, stderr, `unknown output format "yaml"`)
+	})
+}
@@ -1,64 +1,250 @@
 package config
 
 import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
 	"os"
-	"strconv"
 
 	"github.com/joho/godotenv"
+	"github.com/shaibs3/Torq/internal/redact"
 	"go.uber.org/zap"
 )
 
+// ConfigFileEnv names the config file when the -config flag is not given
+const ConfigFileEnv = "CONFIG_FILE"
+
 // Config holds all application configuration
 type Config struct {
-	Port        string
-	RPSLimit    int
-	RPSBurst    int
-	IPDBConfig  string
-	Environment string
-	LogLevel    string
+	ConfigFile          string
+	Port                string
+	OpsPort             string
+	AdminPort           string
+	GRPCPort            string
+	RPSLimit            int
+	RPSBurst            int
+	RateLimitPolicies   string
+	RateLimitBackend    string
+	RateLimitAlgo       string
+	RateLimitWindow     string
+	RateLimitFailMode   string
+	RedisAddr           string
+	RedisPassword       redact.Secret
+	RedisPasswordFile   string
+	RedisDB             int
+	RedisKeyPrefix      string
+	QuotaConfig         string
+	AuthConfig          string
+	TLSCertFile         string
+	TLSKeyFile          string
+	TLSClientCAFile     string
+	TLSClientAuth       string
+	TLSReloadInterval   string
+	TracingExporter     string
+	TracingEndpoint     string
+	TracingInsecure     bool
+	TracingSampling     float64
+	MetricsExporters    string
+	MetricsEndpoint     string
+	MetricsInsecure     bool
+	MetricsInterval     string
+	InstanceID          string
+	ProviderType        string
+	CSVFilePath         string
+	PostgresConnStr     redact.Secret
+	PostgresConnStrFile string
+	IPDBConfig          string
+	CacheSize           int
+	CacheTTL            string
+	OverridesFile       string
+	OverridesAuditFile  string
+	Environment         string
+	LogLevel            string
+	AccessLogFormat     string
+
+	// args are the command-line arguments the configuration was loaded from
+	args []string
 }
 
-// Load loads configuration from environment variables
-func Load(logger *zap.Logger) *Config {
+// Load builds the configuration from, in increasing precedence, defaults, the config
+// file named by -config or CONFIG_FILE, environment variables and command-line flags.
+// It returns every parse and validation problem at once.
+func Load(args []string, logger *zap.Logger) (*Config, error) {
 	// Load .env if present (optional)
 	if err := godotenv.Load(); err != nil {
 		logger.Debug("no .env file found, using environment variables")
 	}
 
-	config := &Config{
-		Port:        getEnv("PORT", "8080"),
-		RPSLimit:    getEnvAsInt("RPS_LIMIT", 10),
-		RPSBurst:    getEnvAsInt("RPS_BURST", 10),
-		IPDBConfig:  os.Getenv("IP_DB_CONFIG"),
-		Environment: getEnv("ENVIRONMENT", "production"),
-		LogLevel:    getEnv("LOG_LEVEL", "info"),
+	flags := flag.NewFlagSet("torq", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	configFile := flags.String("config", os.Getenv(ConfigFileEnv), "YAML or JSON config file")
+	byFlag := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		flags.String(s.flag(), "", s.usage)
+		byFlag[s.flag()] = s
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, fmt.Errorf("invalid command-line flags: %w", err)
+	}
+
+	config := &Config{ConfigFile: *configFile, args: args}
+	var errs []error
+
+	for _, s := range settings {
+		if s.def != "" {
+			if err := s.apply(config, s.def); err != nil {
+				errs = append(errs, fmt.Errorf("default for %s: %w", s.env, err))
+			}
+		}
+	}
+
+	if config.ConfigFile != "" {
+		values, fileErrs := readFile(config.ConfigFile)
+		for _, err := range fileErrs {
+			errs = append(errs, fmt.Errorf("config file %s: %w", config.ConfigFile, err))
+		}
+		for _, s := range settings {
+			if raw, ok := values[s.env]; ok {
+				if err := s.apply(config, raw); err != nil {
+					errs = append(errs, fmt.Errorf("config file %s: %s: %w", config.ConfigFile, s.key, err))
+				}
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if raw := os.Getenv(s.env); raw != "" {
+			if err := s.apply(config, raw); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+			}
+		}
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		if s, ok := byFlag[f.Name]; ok {
+			if err := s.apply(config, f.Value.String()); err != nil {
+				errs = append(errs, fmt.Errorf("-%s: %w", f.Name, err))
+			}
+		}
+	})
+
+	// Secrets mounted as files replace inline values
+	if config.RedisPasswordFile != "" {
+		password, err := redact.ReadSecretFile(config.RedisPasswordFile)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("REDIS_PASSWORD_FILE: %w", err))
+		}
+		config.RedisPassword = redact.Secret(password)
+	}
+
+	// Parse problems are reported together with the validation problems of the result
+	errs = append(errs, config.validate()...)
+	if len(errs) > 0 {
+		return nil, &Error{Problems: errs}
 	}
 
 	logger.Info("configuration loaded",
+		zap.String("config_file", config.ConfigFile),
 		zap.String("port", config.Port),
+		zap.String("ops_port", config.OpsPort),
+		zap.String("admin_port", config.AdminPort),
+		zap.String("grpc_port", config.GRPCPort),
+		zap.String("provider", config.Provider().DbType),
 		zap.Int("rps_limit", config.RPSLimit),
 		zap.Int("rps_burst", config.RPSBurst),
+		zap.String("rate_limit_backend", config.RateLimitBackend),
+		zap.String("rate_limit_algorithm", config.RateLimitAlgo),
+		zap.Bool("tls", config.TLSCertFile != ""),
+		zap.String("metrics_exporters", config.MetricsExporters),
+		zap.String("tracing_exporter", config.TracingExporter),
 		zap.String("environment", config.Environment),
 		zap.String("log_level", config.LogLevel),
+		zap.String("access_log_format", config.AccessLogFormat),
 	)
 
-	return config
+	return config, nil
 }
 
-// getEnv gets an environment variable with a default value
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// BindFlags registers -config and the flag of every setting on flags, for subcommands
+// that parse flags of their own. After flags.Parse, the returned function gives the
+// arguments to pass to Load.
+func BindFlags(flags *flag.FlagSet) func() []string {
+	names := map[string]bool{"config": true}
+	flags.String("config", "", "YAML or JSON config file")
+	for _, s := range settings {
+		flags.String(s.flag(), "", s.usage)
+		names[s.flag()] = true
+	}
+	return func() []string {
+		var args []string
+		flags.Visit(func(f *flag.Flag) {
+			if names[f.Name] {
+				args = append(args, "-"+f.Name+"="+f.Value.String())
+			}
+		})
+		return args
 	}
-	return defaultValue
 }
 
-// getEnvAsInt gets an environment variable as integer with a default value
-func getEnvAsInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
+// Reload loads the configuration again from the same command-line arguments, picking
+// up changes to the config file and to *_FILE secrets. The environment and flags are
+// those the process started with, so settings given there cannot change.
+func (c *Config) Reload(logger *zap.Logger) (*Config, error) {
+	return Load(c.args, logger)
+}
+
+// ProviderConfig is the provider configuration in the JSON form accepted by
+// lookup.DbProviderFactory
+type ProviderConfig struct {
+	DbType       string         `json:"dbtype"`
+	ExtraDetails map[string]any `json:"extra_details"`
+}
+
+// Provider returns the IP database provider configuration. IP_DB_CONFIG, when set,
+// takes precedence over the typed provider settings.
+func (c *Config) Provider() ProviderConfig {
+	if c.IPDBConfig != "" {
+		var provider ProviderConfig
+		_ = json.Unmarshal([]byte(c.IPDBConfig), &provider)
+		return provider
+	}
+	provider := ProviderConfig{DbType: c.ProviderType, ExtraDetails: map[string]any{}}
+	switch c.ProviderType {
+	case "csv":
+		provider.ExtraDetails["file_path"] = c.CSVFilePath
+	case "postgres":
+		if c.PostgresConnStrFile != "" {
+			provider.ExtraDetails["conn_str_file"] = c.PostgresConnStrFile
+		} else {
+			provider.ExtraDetails["conn_str"] = c.PostgresConnStr.Value()
 		}
 	}
-	return defaultValue
+	return provider
+}
+
+// ProviderJSON returns the provider configuration for lookup.DbProviderFactory
+func (c *Config) ProviderJSON() string {
+	if c.IPDBConfig != "" {
+		return c.IPDBConfig
+	}
+	data, _ := json.Marshal(c.Provider())
+	return string(data)
+}
+
+// Error lists every problem found while loading the configuration
+type Error struct {
+	Problems []error
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("invalid configuration (%d problems):", len(e.Problems))
+	for _, problem := range e.Problems {
+		msg += "\n  - " + problem.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() []error {
+	return e.Problems
 }
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// readFile decodes a YAML or JSON config file, chosen by extension, into raw setting
+// values keyed by environment variable. Every unknown key and malformed value is reported;
+// the caller prefixes the problems with the file name.
+func readFile(path string) (map[string]string, []error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- the path is chosen by the operator
+	if err != nil {
+		return nil, []error{fmt.Errorf("failed to read: %w", err)}
+	}
+
+	var doc map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		// Keep numbers as written so integers are not turned into floats
+		decoder.UseNumber()
+		err = decoder.Decode(&doc)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	default:
+		return nil, []error{errors.New("unsupported extension, use .yaml, .yml or .json")}
+	}
+	if err != nil {
+		return nil, []error{fmt.Errorf("failed to parse: %w", err)}
+	}
+
+	byKey := make(map[string]setting)
+	for _, s := range settings {
+		if s.key != "" {
+			byKey[s.key] = s
+		}
+	}
+
+	values := make(map[string]string)
+	var errs []error
+	var walk func(section map[string]any, prefix string)
+	walk = func(section map[string]any, prefix string) {
+		keys := make([]string, 0, len(section))
+		for key := range section {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			path := prefix + key
+			value := section[key]
+			if s, ok := byKey[path]; ok {
+				raw, err := s.fileValue(value)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", path, err))
+					continue
+				}
+				values[s.env] = raw
+				continue
+			}
+			if nested, ok := value.(map[string]any); ok && isSection(byKey, path) {
+				walk(nested, path+".")
+				continue
+			}
+			errs = append(errs, fmt.Errorf("%s: unknown key", path))
+		}
+	}
+	walk(doc, "")
+
+	return values, errs
+}
+
+// isSection reports whether some setting key is nested under path
+func isSection(byKey map[string]setting, path string) bool {
+	for key := range byKey {
+		if strings.HasPrefix(key, path+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/shaibs3/Torq/internal/redact"
+)
+
+// kind describes how a setting is written in the config file
+type kind int
+
+const (
+	kindScalar kind = iota
+	// kindJSON settings are nested objects or arrays in the config file and JSON strings in env vars and flags
+	kindJSON
+	// kindList settings are YAML lists in the config file and comma-separated in env vars and flags
+	kindList
+)
+
+// setting binds one Config field to its config file key, env var and flag. The flag
+// name is the env var in lower case with dashes, e.g. RPS_LIMIT is -rps-limit.
+type setting struct {
+	key   string
+	env   string
+	def   string
+	kind  kind
+	usage string
+	field func(c *Config) any
+}
+
+// flag returns the command-line flag name for the setting
+func (s setting) flag() string {
+	return strings.ToLower(strings.ReplaceAll(s.env, "_", "-"))
+}
+
+// apply parses raw into the setting's field
+func (s setting) apply(c *Config, raw string) error {
+	switch field := s.field(c).(type) {
+	case *string:
+		*field = raw
+	case *redact.Secret:
+		*field = redact.Secret(raw)
+	case *int:
+		value, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		*field = value
+	case *bool:
+		value, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		*field = value
+	case *float64:
+		value, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		*field = value
+	default:
+		return fmt.Errorf("unsupported field type %T", field)
+	}
+	return nil
+}
+
+// fileValue converts a decoded config file value to the raw form accepted by apply
+func (s setting) fileValue(value any) (string, error) {
+	switch s.kind {
+	case kindJSON:
+		if str, ok := value.(string); ok {
+			return str, nil
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", fmt.Errorf("cannot be encoded as JSON: %w", err)
+		}
+		return string(data), nil
+	case kindList:
+		if items, ok := value.([]any); ok {
+			parts := make([]string, len(items))
+			for i, item := range items {
+				parts[i] = fmt.Sprint(item)
+			}
+			return strings.Join(parts, ","), nil
+		}
+	}
+	switch value.(type) {
+	case map[string]any, []any:
+		return "", fmt.Errorf("must be a single value")
+	case nil:
+		return "", nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// Changed returns the environment variable names of the settings whose values differ
+// between old and new, in the order of the settings table
+func Changed(old, new *Config) []string {
+	var changed []string
+	for _, s := range settings {
+		if !reflect.DeepEqual(s.field(old), s.field(new)) {
+			changed = append(changed, s.env)
+		}
+	}
+	return changed
+}
+
+// Effective returns every setting keyed by environment variable, with secrets
+// redacted, for display. JSON settings are included as nested JSON.
+func (c *Config) Effective() map[string]any {
+	effective := map[string]any{ConfigFileEnv: c.ConfigFile}
+	for _, s := range settings {
+		value := reflect.ValueOf(s.field(c)).Elem().Interface()
+		switch v := value.(type) {
+		case redact.Secret:
+			value = v.String()
+		case string:
+			if s.kind == kindJSON {
+				if v == "" {
+					value = nil
+				} else if redacted := redact.JSON(v); redacted != redact.Placeholder {
+					value = json.RawMessage(redacted)
+				} else {
+					value = redacted
+				}
+			}
+		}
+		effective[s.env] = value
+	}
+	return effective
+}
+
+// settings lists every configurable field. Keys are documented in config.example.yaml.
+var settings = []setting{
+	{key: "server.port", env: "PORT", def: "8080", usage: "public API port",
+		field: func(c *Config) any { return &c.Port }},
+	{key: "server.ops_port", env: "OPS_PORT", def: "8081", usage: "health, metrics and pprof port",
+		field: func(c *Config) any { return &c.OpsPort }},
+	{key: "server.admin_port", env: "ADMIN_PORT", def: "8082", usage: "admin API port",
+		field: func(c *Config) any { return &c.AdminPort }},
+	{key: "server.grpc_port", env: "GRPC_PORT", def: "9090", usage: "gRPC server port",
+		field: func(c *Config) any { return &c.GRPCPort }},
+	{key: "server.access_log_format", env: "ACCESS_LOG_FORMAT", def: "structured", usage: "structured, combined or off",
+		field: func(c *Config) any { return &c.AccessLogFormat }},
+
+	{key: "environment", env: "ENVIRONMENT", def: "production", usage: "deployment environment",
+		field: func(c *Config) any { return &c.Environment }},
+	{key: "log.level", env: "LOG_LEVEL", def: "info", usage: "log level",
+		field: func(c *Config) any { return &c.LogLevel }},
+
+	{key: "provider.type", env: "PROVIDER_TYPE", usage: "IP database provider: csv or postgres",
+		field: func(c *Config) any { return &c.ProviderType }},
+	{key: "provider.csv.file_path", env: "PROVIDER_CSV_FILE_PATH", usage: "CSV provider data file",
+		field: func(c *Config) any { return &c.CSVFilePath }},
+	{key: "provider.postgres.conn_str", env: "PROVIDER_POSTGRES_CONN_STR", usage: "Postgres provider connection string",
+		field: func(c *Config) any { return &c.PostgresConnStr }},
+	{key: "provider.postgres.conn_str_file", env: "PROVIDER_POSTGRES_CONN_STR_FILE", usage: "file holding the Postgres provider connection string",
+		field: func(c *Config) any { return &c.PostgresConnStrFile }},
+	// IP_DB_CONFIG predates the typed provider settings and is not part of the file schema
+	{env: "IP_DB_CONFIG", kind: kindJSON, usage: "JSON provider configuration; overrides the typed provider settings",
+		field: func(c *Config) any { return &c.IPDBConfig }},
+
+	{key: "cache.size", env: "LOOKUP_CACHE_SIZE", def: "0", usage: "number of lookups cached in memory; 0 disables the cache",
+		field: func(c *Config) any { return &c.CacheSize }},
+	{key: "cache.ttl", env: "LOOKUP_CACHE_TTL", def: "5m", usage: "how long a cached lookup is served",
+		field: func(c *Config) any { return &c.CacheTTL }},
+	{key: "overrides.file_path", env: "OVERRIDES_FILE", usage: "file holding local IP overrides; empty disables overrides",
+		field: func(c *Config) any { return &c.OverridesFile }},
+	{key: "overrides.audit_file_path", env: "OVERRIDES_AUDIT_FILE", usage: "file the override audit trail is appended to (default: OVERRIDES_FILE with .audit.jsonl)",
+		field: func(c *Config) any { return &c.OverridesAuditFile }},
+
+	{key: "rate_limit.rps", env: "RPS_LIMIT", def: "10", usage: "default policy requests per second",
+		field: func(c *Config) any { return &c.RPSLimit }},
+	{key: "rate_limit.burst", env: "RPS_BURST", def: "10", usage: "default policy burst",
+		field: func(c *Config) any { return &c.RPSBurst }},
+	{key: "rate_limit.algorithm", env: "RATE_LIMIT_ALGORITHM", def: "token_bucket", usage: "default policy algorithm",
+		field: func(c *Config) any { return &c.RateLimitAlgo }},
+	{key: "rate_limit.window", env: "RATE_LIMIT_WINDOW", def: "1s", usage: "window for the sliding window algorithms",
+		field: func(c *Config) any { return &c.RateLimitWindow }},
+	{key: "rate_limit.backend", env: "RATE_LIMIT_BACKEND", def: "memory", usage: "memory or redis",
+		field: func(c *Config) any { return &c.RateLimitBackend }},
+	{key: "rate_limit.failure_mode", env: "RATE_LIMIT_FAILURE_MODE", def: "local", usage: "local, open or closed",
+		field: func(c *Config) any { return &c.RateLimitFailMode }},
+	{key: "rate_limit.policies", env: "RATE_LIMIT_POLICIES", kind: kindJSON, usage: "route-specific rate limit policies",
+		field: func(c *Config) any { return &c.RateLimitPolicies }},
+
+	{key: "redis.addr", env: "REDIS_ADDR", def: "localhost:6379", usage: "Redis-compatible store address",
+		field: func(c *Config) any { return &c.RedisAddr }},
+	{key: "redis.password", env: "REDIS_PASSWORD", usage: "Redis password",
+		field: func(c *Config) any { return &c.RedisPassword }},
+	{key: "redis.password_file", env: "REDIS_PASSWORD_FILE", usage: "file holding the Redis password",
+		field: func(c *Config) any { return &c.RedisPasswordFile }},
+	{key: "redis.db", env: "REDIS_DB", def: "0", usage: "Redis database number",
+		field: func(c *Config) any { return &c.RedisDB }},
+	{key: "redis.key_prefix", env: "REDIS_KEY_PREFIX", def: "torq:ratelimit:", usage: "prefix for rate limit bucket keys",
+		field: func(c *Config) any { return &c.RedisKeyPrefix }},
+
+	{key: "quota", env: "QUOTA_CONFIG", kind: kindJSON, usage: "daily and monthly quotas",
+		field: func(c *Config) any { return &c.QuotaConfig }},
+	{key: "auth", env: "AUTH_CONFIG", kind: kindJSON, usage: "API key and JWT authentication",
+		field: func(c *Config) any { return &c.AuthConfig }},
+
+	{key: "tls.cert_file", env: "TLS_CERT_FILE", usage: "PEM server certificate",
+		field: func(c *Config) any { return &c.TLSCertFile }},
+	{key: "tls.key_file", env: "TLS_KEY_FILE", usage: "PEM private key",
+		field: func(c *Config) any { return &c.TLSKeyFile }},
+	{key: "tls.client_ca_file", env: "TLS_CLIENT_CA_FILE", usage: "PEM CA bundle for client certificates",
+		field: func(c *Config) any { return &c.TLSClientCAFile }},
+	{key: "tls.client_auth", env: "TLS_CLIENT_AUTH", usage: "none, optional or require",
+		field: func(c *Config) any { return &c.TLSClientAuth }},
+	{key: "tls.reload_interval", env: "TLS_RELOAD_INTERVAL", def: "30s", usage: "certificate reload check interval",
+		field: func(c *Config) any { return &c.TLSReloadInterval }},
+
+	{key: "telemetry.instance_id", env: "SERVICE_INSTANCE_ID", usage: "service.instance.id resource attribute",
+		field: func(c *Config) any { return &c.InstanceID }},
+	{key: "telemetry.metrics.exporters", env: "METRICS_EXPORTERS", def: "prometheus", kind: kindList, usage: "prometheus, otlp-grpc, otlp-http or none",
+		field: func(c *Config) any { return &c.MetricsExporters }},
+	{key: "telemetry.metrics.endpoint", env: "METRICS_ENDPOINT", usage: "OTLP collector host:port for metrics",
+		field: func(c *Config) any { return &c.MetricsEndpoint }},
+	{key: "telemetry.metrics.insecure", env: "METRICS_INSECURE", def: "false", usage: "push OTLP metrics without TLS",
+		field: func(c *Config) any { return &c.MetricsInsecure }},
+	{key: "telemetry.metrics.interval", env: "METRICS_EXPORT_INTERVAL", def: "60s", usage: "OTLP metrics push interval",
+		field: func(c *Config) any { return &c.MetricsInterval }},
+	{key: "telemetry.tracing.exporter", env: "TRACING_EXPORTER", def: "none", usage: "none, otlp-grpc, otlp-http or stdout",
+		field: func(c *Config) any { return &c.TracingExporter }},
+	{key: "telemetry.tracing.endpoint", env: "TRACING_ENDPOINT", usage: "OTLP collector host:port for spans",
+		field: func(c *Config) any { return &c.TracingEndpoint }},
+	{key: "telemetry.tracing.insecure", env: "TRACING_INSECURE", def: "false", usage: "send OTLP spans without TLS",
+		field: func(c *Config) any { return &c.TracingInsecure }},
+	{key: "telemetry.tracing.sample_ratio", env: "TRACING_SAMPLE_RATIO", def: "1", usage: "fraction of new traces sampled",
+		field: func(c *Config) any { return &c.TracingSampling }},
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/shaibs3/Torq/internal/auth"
+	"github.com/shaibs3/Torq/internal/certs"
+	"github.com/shaibs3/Torq/internal/limiter"
+	"github.com/shaibs3/Torq/internal/lookup"
+	"github.com/shaibs3/Torq/internal/override"
+	"github.com/shaibs3/Torq/internal/quota"
+	"github.com/shaibs3/Torq/internal/telemetry"
+	"go.uber.org/zap/zapcore"
+)
+
+// Validate checks the whole configuration and reports every problem at once
+func (c *Config) Validate() error {
+	if errs := c.validate(); len(errs) > 0 {
+		return &Error{Problems: errs}
+	}
+	return nil
+}
+
+func (c *Config) validate() []error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	// Listeners
+	ports := make(map[string]string)
+	for _, port := range []struct{ name, value string }{
+		{"PORT", c.Port}, {"OPS_PORT", c.OpsPort}, {"ADMIN_PORT", c.AdminPort}, {"GRPC_PORT", c.GRPCPort},
+	} {
+		n, err := strconv.Atoi(port.value)
+		if err != nil || n < 1 || n > 65535 {
+			errs = append(errs, fmt.Errorf("%s: must be a port number between 1 and 65535, got %q", port.name, port.value))
+			continue
+		}
+		if other, ok := ports[port.value]; ok {
+			errs = append(errs, fmt.Errorf("%s: port %s is already used by %s", port.name, port.value, other))
+			continue
+		}
+		ports[port.value] = port.name
+	}
+	check(isAccessLogFormat(c.AccessLogFormat), "ACCESS_LOG_FORMAT: must be structured, combined or off, got %q", c.AccessLogFormat)
+	_, err := zapcore.ParseLevel(c.LogLevel)
+	check(err == nil, "LOG_LEVEL: unknown level %q", c.LogLevel)
+
+	// Provider
+	errs = append(errs, c.validateProvider()...)
+
+	// Lookup cache
+	check(c.CacheSize >= 0, "LOOKUP_CACHE_SIZE: must not be negative, got %d", c.CacheSize)
+	errs = append(errs, checkDuration("LOOKUP_CACHE_TTL", c.CacheTTL)...)
+
+	// Overrides
+	if c.OverridesFile != "" {
+		if _, err := override.LoadFile(c.OverridesFile); err != nil {
+			errs = append(errs, fmt.Errorf("OVERRIDES_FILE: %w", err))
+		}
+	}
+	check(c.OverridesAuditFile == "" || c.OverridesFile != "", "OVERRIDES_AUDIT_FILE: requires OVERRIDES_FILE")
+
+	// Rate limiting
+	check(c.RPSLimit > 0, "RPS_LIMIT: must be positive, got %d", c.RPSLimit)
+	check(c.RPSBurst > 0, "RPS_BURST: must be positive, got %d", c.RPSBurst)
+	check(limiter.Algorithm(c.RateLimitAlgo).IsValid(), "RATE_LIMIT_ALGORITHM: unsupported algorithm %q", c.RateLimitAlgo)
+	check(limiter.Backend(c.RateLimitBackend).IsValid(), "RATE_LIMIT_BACKEND: must be memory or redis, got %q", c.RateLimitBackend)
+	check(limiter.FailureMode(c.RateLimitFailMode).IsValid(), "RATE_LIMIT_FAILURE_MODE: must be local, open or closed, got %q", c.RateLimitFailMode)
+	errs = append(errs, checkDuration("RATE_LIMIT_WINDOW", c.RateLimitWindow)...)
+	if _, err := limiter.ParsePolicyConfigs(c.RateLimitPolicies); err != nil {
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_POLICIES: %w", err))
+	}
+	check(c.RedisDB >= 0, "REDIS_DB: must not be negative, got %d", c.RedisDB)
+
+	// Quotas and authentication
+	if _, err := quota.ParseConfig(c.QuotaConfig); err != nil {
+		errs = append(errs, fmt.Errorf("QUOTA_CONFIG: %w", err))
+	}
+	if _, err := auth.ParseConfig(c.AuthConfig); err != nil {
+		errs = append(errs, fmt.Errorf("AUTH_CONFIG: %w", err))
+	}
+
+	// TLS
+	check((c.TLSCertFile == "") == (c.TLSKeyFile == ""), "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	check(c.TLSClientCAFile == "" || c.TLSCertFile != "", "TLS_CLIENT_CA_FILE: requires TLS_CERT_FILE and TLS_KEY_FILE")
+	check(c.TLSClientAuth == "" || certs.ClientAuth(c.TLSClientAuth).IsValid(), "TLS_CLIENT_AUTH: must be none, optional or require, got %q", c.TLSClientAuth)
+	for _, file := range []struct{ name, path string }{
+		{"TLS_CERT_FILE", c.TLSCertFile}, {"TLS_KEY_FILE", c.TLSKeyFile}, {"TLS_CLIENT_CA_FILE", c.TLSClientCAFile},
+	} {
+		if file.path != "" {
+			errs = append(errs, checkReadable(file.name, file.path)...)
+		}
+	}
+	errs = append(errs, checkDuration("TLS_RELOAD_INTERVAL", c.TLSReloadInterval)...)
+
+	// Telemetry
+	if _, err := telemetry.ParseMetricsExporters(c.MetricsExporters); err != nil {
+		errs = append(errs, fmt.Errorf("METRICS_EXPORTERS: %w", err))
+	}
+	errs = append(errs, checkDuration("METRICS_EXPORT_INTERVAL", c.MetricsInterval)...)
+	check(telemetry.TraceExporter(c.TracingExporter).IsValid(), "TRACING_EXPORTER: must be none, otlp-grpc, otlp-http or stdout, got %q", c.TracingExporter)
+	check(c.TracingSampling >= 0 && c.TracingSampling <= 1, "TRACING_SAMPLE_RATIO: must be between 0 and 1, got %v", c.TracingSampling)
+
+	return errs
+}
+
+// validateProvider checks that the provider type is supported and its required fields are set
+func (c *Config) validateProvider() []error {
+	if c.IPDBConfig == "" && c.ProviderType == "" {
+		return []error{fmt.Errorf("provider: set provider.type in the config file, PROVIDER_TYPE or IP_DB_CONFIG")}
+	}
+	if c.IPDBConfig != "" {
+		var provider ProviderConfig
+		if err := json.Unmarshal([]byte(c.IPDBConfig), &provider); err != nil {
+			return []error{fmt.Errorf("IP_DB_CONFIG: invalid JSON: %w", err)}
+		}
+	}
+
+	provider := c.Provider()
+	switch lookup.DbType(provider.DbType) {
+	case lookup.DbTypeCSV:
+		path, _ := provider.ExtraDetails["file_path"].(string)
+		if path == "" {
+			return []error{fmt.Errorf("provider: file_path is required for the csv provider")}
+		}
+		return checkReadable("provider file_path", path)
+	case lookup.DbTypePostgres:
+		if file, _ := provider.ExtraDetails["conn_str_file"].(string); file != "" {
+			return checkReadable("provider conn_str_file", file)
+		}
+		if connStr, _ := provider.ExtraDetails["conn_str"].(string); connStr == "" {
+			return []error{fmt.Errorf("provider: conn_str or conn_str_file is required for the postgres provider")}
+		}
+		return nil
+	default:
+		return []error{fmt.Errorf("provider: unsupported type %q, must be csv or postgres", provider.DbType)}
+	}
+}
+
+// checkDuration reports a setting that is not a positive duration
+func checkDuration(name, value string) []error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return []error{fmt.Errorf("%s: invalid duration %q", name, value)}
+	}
+	if d <= 0 {
+		return []error{fmt.Errorf("%s: must be positive, got %s", name, value)}
+	}
+	return nil
+}
+
+// checkReadable reports a file that cannot be opened for reading
+func checkReadable(name, path string) []error {
+	file, err := os.Open(path) // #nosec G304 -- the path comes from the operator's configuration
+	if err != nil {
+		return []error{fmt.Errorf("%s: %w", name, err)}
+	}
+	_ = file.Close()
+	return nil
+}
+
+// isAccessLogFormat reports whether format is one the router can write
+func isAccessLogFormat(format string) bool {
+	switch format {
+	case "structured", "combined", "off":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package dataset
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Byte order marks at the start of a file
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// ValidateCSV validates a dataset in the format of the csv provider: ip,city,country
+// rows without a header. The error is only set when r cannot be read.
+func ValidateCSV(source string, r io.Reader) (Report, error) {
+	v := NewValidator(source)
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(len(bomUTF8))
+	switch {
+	case bytes.HasPrefix(head, bomUTF8):
+		v.Add(Issue{Line: 1, Severity: SeverityError, Code: CodeEncoding, Field: fieldIP,
+			Message: "file starts with a UTF-8 byte order mark, which the csv provider keeps as part of the first ip"})
+		_, _ = br.Discard(len(bomUTF8))
+	case bytes.HasPrefix(head, bomUTF16LE), bytes.HasPrefix(head, bomUTF16BE):
+		v.Add(Issue{Line: 1, Severity: SeverityError, Code: CodeEncoding,
+			Message: "file is UTF-16 encoded; the csv provider reads UTF-8"})
+		return v.Report(), nil
+	}
+
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	fields := 0
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			v.Add(Issue{Line: parseErr.StartLine, Severity: SeverityError, Code: CodeMalformedCSV,
+				Message: fmt.Sprintf("%v; the csv provider fails to load the file", parseErr.Err)})
+			continue
+		}
+		if err != nil {
+			return Report{}, fmt.Errorf("failed to read %s: %w", source, err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		// Like encoding/csv's default, the csv provider requires every row to have as
+		// many fields as the first
+		if fields == 0 {
+			fields = len(row)
+		} else if len(row) != fields {
+			v.Add(Issue{Line: line, Severity: SeverityError, Code: CodeFieldCount,
+				Message: fmt.Sprintf("row has %d fields but the first row has %d; the csv provider fails to load the file", len(row), fields)})
+			if len(row) < 3 {
+				v.report.Rows++
+				continue
+			}
+		}
+		v.Check(line, row)
+	}
+	return v.Report(), nil
+}
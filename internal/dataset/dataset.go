@@ -0,0 +1,303 @@
+// Package dataset validates and compares IP location datasets before they are deployed:
+// the CSV files and ip_locations tables served by the lookup providers.
+package dataset
+
+import (
+	"cmp"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Severity grades an issue. Errors are rows the providers drop, mangle or serve
+// inconsistently; warnings are rows that load but probably not as intended.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Issue codes
+const (
+	// CodeMalformedCSV is a line the CSV parser rejects, which fails the whole file
+	CodeMalformedCSV = "malformed_csv"
+	// CodeFieldCount is a row without ip, city and country, or with extra columns
+	CodeFieldCount = "field_count"
+	// CodeEmptyField is a row with an empty ip, city or country
+	CodeEmptyField = "empty_field"
+	// CodeEncoding is a field that is not valid UTF-8 or holds control characters or a byte order mark
+	CodeEncoding = "invalid_encoding"
+	// CodeWhitespace is a field with leading or trailing whitespace
+	CodeWhitespace = "whitespace"
+	// CodeHeader is a header row, which the providers load as data
+	CodeHeader = "header_row"
+	// CodeInvalidIP is an ip that is neither an IP address nor a CIDR range
+	CodeInvalidIP = "invalid_ip"
+	// CodeNonCanonicalIP is an ip not written in its canonical form, e.g. 010.0.0.1 or
+	// 10.0.0.1/8. The providers match the ip column exactly, so lookups of the canonical
+	// form miss the row.
+	CodeNonCanonicalIP = "non_canonical_ip"
+	// CodeRange is a CIDR range. The providers match exact addresses, so lookups of
+	// addresses in the range miss the row.
+	CodeRange = "cidr_range"
+	// CodeDuplicate is an ip listed more than once with the same city and country
+	CodeDuplicate = "duplicate"
+	// CodeConflict is an ip listed more than once, or inside a listed range, with a
+	// different city or country
+	CodeConflict = "conflict"
+	// CodeUnknownCountry is a country that is not an ISO 3166-1 code or country name
+	CodeUnknownCountry = "unknown_country"
+)
+
+// Issue is a problem found in one row of a dataset
+type Issue struct {
+	// Line is the line of the row in a file, or its position in an ip-ordered table
+	Line     int      `json:"line"`
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Field    string   `json:"field,omitempty"`
+	Value    string   `json:"value,omitempty"`
+	Message  string   `json:"message"`
+	// RelatedLine is the earlier row that a duplicate or conflicting row clashes with
+	RelatedLine int `json:"related_line,omitempty"`
+}
+
+// Report is the result of validating a dataset
+type Report struct {
+	Source   string  `json:"source"`
+	Rows     int     `json:"rows"`
+	Errors   int     `json:"errors"`
+	Warnings int     `json:"warnings"`
+	Issues   []Issue `json:"issues"`
+}
+
+// Valid reports whether the dataset has no errors, and no warnings either when strict
+func (r Report) Valid(strict bool) bool {
+	return r.Errors == 0 && (!strict || r.Warnings == 0)
+}
+
+// Dataset columns
+const (
+	fieldIP      = "ip"
+	fieldCity    = "city"
+	fieldCountry = "country"
+)
+
+// entry is a row whose ip parsed, kept for the checks across rows
+type entry struct {
+	line    int
+	ip      string
+	prefix  netip.Prefix
+	city    string
+	country string
+}
+
+// Validator checks the rows of a dataset one at a time, then across rows
+type Validator struct {
+	report  Report
+	entries map[netip.Prefix]entry
+}
+
+// NewValidator returns a Validator for the dataset named source
+func NewValidator(source string) *Validator {
+	return &Validator{
+		report:  Report{Source: source, Issues: []Issue{}},
+		entries: make(map[netip.Prefix]entry),
+	}
+}
+
+// Add reports an issue found by the caller, e.g. while reading the dataset
+func (v *Validator) Add(issue Issue) {
+	v.report.Issues = append(v.report.Issues, issue)
+	switch issue.Severity {
+	case SeverityError:
+		v.report.Errors++
+	case SeverityWarning:
+		v.report.Warnings++
+	}
+}
+
+func (v *Validator) errorf(line int, code, field, value, format string, args ...any) {
+	v.Add(Issue{Line: line, Severity: SeverityError, Code: code, Field: field, Value: value, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *Validator) warnf(line int, code, field, value, format string, args ...any) {
+	v.Add(Issue{Line: line, Severity: SeverityWarning, Code: code, Field: field, Value: value, Message: fmt.Sprintf(format, args...)})
+}
+
+// Check validates the row at line, whose fields are ip, city and country
+func (v *Validator) Check(line int, fields []string) {
+	v.report.Rows++
+	if len(fields) < 3 {
+		v.errorf(line, CodeFieldCount, "", strings.Join(fields, ","),
+			"row has %d fields, want ip, city and country; the row is skipped", len(fields))
+		return
+	}
+	if len(fields) > 3 {
+		v.warnf(line, CodeFieldCount, "", "", "row has %d fields; fields after country are ignored", len(fields))
+	}
+	ip, city, country := fields[0], fields[1], fields[2]
+	if line == 1 && strings.EqualFold(ip, fieldIP) && strings.EqualFold(country, fieldCountry) {
+		v.warnf(line, CodeHeader, "", "", "header row is loaded as data; remove it")
+		return
+	}
+
+	ipOK := v.checkText(line, fieldIP, ip, SeverityError)
+	cityOK := v.checkText(line, fieldCity, city, SeverityWarning)
+	countryOK := v.checkText(line, fieldCountry, country, SeverityWarning)
+
+	if ipOK {
+		ip = strings.TrimSpace(ip)
+		if prefix, ok := v.checkIP(line, ip); ok {
+			v.checkDuplicate(entry{line: line, ip: ip, prefix: prefix, city: city, country: country})
+		}
+	}
+	if cityOK && strings.TrimSpace(city) == "" {
+		v.warnf(line, CodeEmptyField, fieldCity, "", "city is empty")
+	}
+	if countryOK {
+		v.checkCountry(line, country)
+	}
+}
+
+// checkText reports encoding problems and surrounding whitespace in a field, the
+// latter with severity. It returns false when the encoding is broken and the field
+// cannot be checked further.
+func (v *Validator) checkText(line int, field, value string, severity Severity) bool {
+	if !utf8.ValidString(value) {
+		v.errorf(line, CodeEncoding, field, value, "%s is not valid UTF-8", field)
+		return false
+	}
+	if strings.ContainsRune(value, '\uFEFF') {
+		v.errorf(line, CodeEncoding, field, value, "%s contains a byte order mark", field)
+		return false
+	}
+	if strings.ContainsFunc(value, unicode.IsControl) {
+		v.errorf(line, CodeEncoding, field, value, "%s contains control characters", field)
+		return false
+	}
+	if trimmed := strings.TrimSpace(value); trimmed != value {
+		v.Add(Issue{Line: line, Severity: severity, Code: CodeWhitespace, Field: field, Value: value,
+			Message: fmt.Sprintf("%s has leading or trailing whitespace, which is kept as part of the value", field)})
+	}
+	return true
+}
+
+// checkIP parses ip as an address or a CIDR range. IPv4-mapped IPv6 addresses are
+// unmapped, so that they clash with the IPv4 rows they duplicate.
+func (v *Validator) checkIP(line int, ip string) (netip.Prefix, bool) {
+	if ip == "" {
+		v.errorf(line, CodeEmptyField, fieldIP, "", "ip is empty")
+		return netip.Prefix{}, false
+	}
+	if strings.Contains(ip, "/") {
+		prefix, err := netip.ParsePrefix(ip)
+		if err != nil {
+			v.errorf(line, CodeInvalidIP, fieldIP, ip, "ip is not a valid IP address or CIDR range: %v", err)
+			return netip.Prefix{}, false
+		}
+		if masked := prefix.Masked(); masked.String() != ip {
+			v.warnf(line, CodeNonCanonicalIP, fieldIP, ip, "CIDR range has host bits set or is not in canonical form; it covers %s", masked)
+		}
+		v.warnf(line, CodeRange, fieldIP, ip, "lookups match the ip column exactly, so addresses in this range are not found")
+		return unmap(prefix.Masked()), true
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || addr.Zone() != "" {
+		if err == nil {
+			err = fmt.Errorf("zones are not allowed")
+		}
+		v.errorf(line, CodeInvalidIP, fieldIP, ip, "ip is not a valid IP address or CIDR range: %v", err)
+		return netip.Prefix{}, false
+	}
+	if addr.String() != ip {
+		v.warnf(line, CodeNonCanonicalIP, fieldIP, ip, "lookups of %s do not match this row; write the address in canonical form", addr)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), true
+}
+
+// unmap converts an IPv4-mapped IPv6 range to the IPv4 range it covers
+func unmap(prefix netip.Prefix) netip.Prefix {
+	if !prefix.Addr().Is4In6() || prefix.Bits() < 96 {
+		return prefix
+	}
+	return netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+}
+
+// checkDuplicate reports an ip listed before; the providers keep one of the rows
+func (v *Validator) checkDuplicate(e entry) {
+	first, ok := v.entries[e.prefix]
+	if !ok {
+		v.entries[e.prefix] = e
+		return
+	}
+	if sameLocation(first, e) {
+		v.Add(Issue{Line: e.line, Severity: SeverityWarning, Code: CodeDuplicate, Field: fieldIP, Value: e.ip, RelatedLine: first.line,
+			Message: fmt.Sprintf("%s is already listed on line %d with the same location", e.ip, first.line)})
+		return
+	}
+	v.Add(Issue{Line: e.line, Severity: SeverityError, Code: CodeConflict, Field: fieldIP, Value: e.ip, RelatedLine: first.line,
+		Message: fmt.Sprintf("%s is listed again as %s, %s; line %d has %s, %s", e.ip, e.city, e.country, first.line, first.city, first.country)})
+}
+
+func (v *Validator) checkCountry(line int, country string) {
+	country = strings.TrimSpace(country)
+	if country == "" {
+		v.errorf(line, CodeEmptyField, fieldCountry, "", "country is empty")
+		return
+	}
+	if _, ok := CountryCode(country); !ok {
+		v.errorf(line, CodeUnknownCountry, fieldCountry, country, "%q is not an ISO 3166-1 country code or country name", country)
+	}
+}
+
+// sameLocation compares locations the way a client would, ignoring case and surrounding space
+func sameLocation(a, b entry) bool {
+	return strings.EqualFold(strings.TrimSpace(a.city), strings.TrimSpace(b.city)) &&
+		strings.EqualFold(strings.TrimSpace(a.country), strings.TrimSpace(b.country))
+}
+
+// Report checks the rows for overlapping ranges with conflicting locations and returns
+// the report, with issues in line order
+func (v *Validator) Report() Report {
+	v.checkOverlaps()
+	slices.SortStableFunc(v.report.Issues, func(a, b Issue) int { return cmp.Compare(a.Line, b.Line) })
+	return v.report
+}
+
+// checkOverlaps reports rows inside a range with a different location. Sorted by
+// address then prefix length, a range comes right before the rows it contains, so a
+// stack of the enclosing ranges finds the innermost one of each row.
+func (v *Validator) checkOverlaps() {
+	entries := make([]entry, 0, len(v.entries))
+	for _, e := range v.entries {
+		entries = append(entries, e)
+	}
+	slices.SortFunc(entries, func(a, b entry) int {
+		if c := a.prefix.Addr().Compare(b.prefix.Addr()); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.prefix.Bits(), b.prefix.Bits())
+	})
+
+	var enclosing []entry
+	for _, e := range entries {
+		for len(enclosing) > 0 && !enclosing[len(enclosing)-1].prefix.Contains(e.prefix.Addr()) {
+			enclosing = enclosing[:len(enclosing)-1]
+		}
+		if len(enclosing) > 0 {
+			outer := enclosing[len(enclosing)-1]
+			if !sameLocation(outer, e) {
+				v.Add(Issue{Line: e.line, Severity: SeverityError, Code: CodeConflict, Field: fieldIP, Value: e.ip, RelatedLine: outer.line,
+					Message: fmt.Sprintf("%s is inside %s on line %d, which has %s, %s instead of %s, %s",
+						e.ip, outer.ip, outer.line, outer.city, outer.country, e.city, e.country)})
+			}
+		}
+		enclosing = append(enclosing, e)
+	}
+}
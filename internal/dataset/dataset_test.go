@@ -0,0 +1,95 @@
+package dataset
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// codes returns the line, severity and code of each issue
+func codes(report Report) []string {
+	got := make([]string, 0, len(report.Issues))
+	for _, issue := range report.Issues {
+		got = append(got, fmt.Sprintf("%d %s %s", issue.Line, issue.Severity, issue.Code))
+	}
+	return got
+}
+
+func TestValidateCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{name: "valid", data: "1.2.3.4,Paris,France\n2001:db8::1,Berlin,DE\n5.6.7.8,London,UK\n"},
+		{name: "byte order mark", data: "\xef\xbb\xbf1.2.3.4,Paris,France\n", want: []string{"1 error invalid_encoding"}},
+		{name: "utf-16", data: "\xff\xfe1\x00", want: []string{"1 error invalid_encoding"}},
+		{name: "invalid utf-8", data: "1.2.3.4,Par\xffis,France\n", want: []string{"1 error invalid_encoding"}},
+		{name: "control character", data: "1.2.3.4,Paris\x00,France\n", want: []string{"1 error invalid_encoding"}},
+		{name: "malformed csv", data: "1.2.3.4,Paris,France\n5.6.7.8,\"Berlin,Germany\n", want: []string{"2 error malformed_csv"}},
+		{name: "too few fields", data: "1.2.3.4,Paris\n", want: []string{"1 error field_count"}},
+		{name: "field count differs from first row", data: "1.2.3.4,Paris,France,x\n5.6.7.8,Berlin,Germany\n", want: []string{
+			"1 warning field_count", "2 error field_count"}},
+		{name: "empty fields", data: ",,\n", want: []string{"1 error empty_field", "1 warning empty_field", "1 error empty_field"}},
+		{name: "whitespace", data: " 1.2.3.4,Paris ,France\n", want: []string{"1 error whitespace", "1 warning whitespace"}},
+		{name: "header", data: "ip,city,country\n1.2.3.4,Paris,France\n", want: []string{"1 warning header_row"}},
+		{name: "invalid ip", data: "1.2.3,Paris,France\n10.0.0.0/33,Paris,France\nfe80::1%eth0,Paris,France\n", want: []string{
+			"1 error invalid_ip", "2 error invalid_ip", "3 error invalid_ip"}},
+		{name: "non-canonical ip", data: "2001:DB8::1,Paris,France\n", want: []string{"1 warning non_canonical_ip"}},
+		{name: "unknown country", data: "1.2.3.4,Paris,Frankreich\n", want: []string{"1 error unknown_country"}},
+		{name: "duplicate", data: "1.2.3.4,Paris,France\n::ffff:1.2.3.4,paris,FRANCE\n", want: []string{"2 warning duplicate"}},
+		{name: "conflicting duplicate", data: "1.2.3.4,Paris,France\n1.2.3.4,Berlin,Germany\n", want: []string{"2 error conflict"}},
+		{name: "conflicting range", data: "10.0.0.0/8,Tel Aviv,Israel\n10.1.0.0/16,Tel Aviv,Israel\n10.1.2.3,Berlin,Germany\n11.0.0.1,Paris,France\n", want: []string{
+			"1 warning cidr_range", "2 warning cidr_range", "3 error conflict"}},
+		{name: "range with host bits", data: "10.1.2.3/8,Tel Aviv,Israel\n", want: []string{"1 warning non_canonical_ip", "1 warning cidr_range"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := ValidateCSV("test.csv", strings.NewReader(tt.data))
+			require.NoError(t, err)
+			assert.Equal(t, append([]string{}, tt.want...), codes(report))
+		})
+	}
+}
+
+func TestValidateCSV_Report(t *testing.T) {
+	report, err := ValidateCSV("test.csv", strings.NewReader("1.2.3.4,Paris,France\n1.2.3.4,Berlin,Germany\n5.6.7.8,London,UK\n5.6.7.8,London,UK\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "test.csv", report.Source)
+	assert.Equal(t, 4, report.Rows)
+	assert.Equal(t, 1, report.Errors)
+	assert.Equal(t, 1, report.Warnings)
+	assert.Equal(t, Issue{
+		Line: 2, Severity: SeverityError, Code: CodeConflict, Field: "ip", Value: "1.2.3.4", RelatedLine: 1,
+		Message: "1.2.3.4 is listed again as Berlin, Germany; line 1 has Paris, France",
+	}, report.Issues[0])
+	assert.False(t, report.Valid(false))
+
+	report.Errors = 0
+	assert.True(t, report.Valid(false))
+	assert.False(t, report.Valid(true), "strict validation fails on warnings")
+}
+
+func TestValidateCSV_SampleData(t *testing.T) {
+	f, err := os.Open("../../TestFiles/ip_data.csv")
+	require.NoError(t, err)
+	defer f.Close() //nolint:errcheck
+	report, err := ValidateCSV("ip_data.csv", f)
+	require.NoError(t, err)
+	assert.Empty(t, report.Issues)
+	assert.Positive(t, report.Rows)
+}
+
+func TestCountryCode(t *testing.T) {
+	for input, want := range map[string]string{"DE": "DE", "deu": "DE", "Germany": "DE", "UK": "GB", "usa": "US", "South Korea": "KR", " Russia ": "RU"} {
+		code, ok := CountryCode(input)
+		assert.True(t, ok, input)
+		assert.Equal(t, want, code, input)
+	}
+	_, ok := CountryCode("Atlantis")
+	assert.False(t, ok)
+}
@@ -0,0 +1,249 @@
+package dataset
+
+import (
+	"cmp"
+	"math"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// Location is where a dataset places an IP
+type Location struct {
+	City    string `json:"city"`
+	Country string `json:"country"`
+}
+
+// Snapshot holds the rows of a dataset, keyed by IP in canonical form so that datasets
+// in different formats compare equal
+type Snapshot map[string]Location
+
+// Add records a row; a repeated IP replaces the earlier row, as in the providers
+func (s Snapshot) Add(ip, city, country string) {
+	s[canonicalIP(ip)] = Location{City: city, Country: country}
+}
+
+// canonicalIP writes an address or range in canonical form, unmapping IPv4-mapped IPv6
+// and writing single-address ranges as addresses. Anything else is kept as is.
+func canonicalIP(ip string) string {
+	ip = strings.TrimSpace(ip)
+	prefix, ok := parsePrefix(ip)
+	if !ok {
+		return ip
+	}
+	if prefix.IsSingleIP() {
+		return prefix.Addr().String()
+	}
+	return prefix.String()
+}
+
+// parsePrefix parses an address as a single-address range, or a CIDR range
+func parsePrefix(ip string) (netip.Prefix, bool) {
+	if addr, err := netip.ParseAddr(ip); err == nil {
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), true
+	}
+	if prefix, err := netip.ParsePrefix(ip); err == nil {
+		return unmap(prefix.Masked()), true
+	}
+	return netip.Prefix{}, false
+}
+
+// addresses counts the addresses of an entry, capped at the largest uint64
+func addresses(ip string) uint64 {
+	prefix, ok := parsePrefix(ip)
+	if !ok {
+		return 1
+	}
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits >= 64 {
+		return math.MaxUint64
+	}
+	return 1 << hostBits
+}
+
+// addCapped adds without overflowing
+func addCapped(a, b uint64) uint64 {
+	if a > math.MaxUint64-b {
+		return math.MaxUint64
+	}
+	return a + b
+}
+
+// compareIPs orders addresses and ranges by address then prefix length, after
+// anything that is not an IP
+func compareIPs(a, b string) int {
+	pa, okA := parsePrefix(a)
+	pb, okB := parsePrefix(b)
+	switch {
+	case okA && okB:
+		if c := pa.Addr().Compare(pb.Addr()); c != 0 {
+			return c
+		}
+		return cmp.Compare(pa.Bits(), pb.Bits())
+	case okA != okB:
+		if okA {
+			return 1
+		}
+		return -1
+	}
+	return strings.Compare(a, b)
+}
+
+// countryKey groups the statistics of a country by its ISO 3166-1 alpha-2 code when it
+// has one, so that a dataset writing DE and one writing Germany agree
+func countryKey(country string) string {
+	if code, ok := CountryCode(country); ok {
+		return code
+	}
+	return strings.TrimSpace(country)
+}
+
+// DiffEntry is an entry found in only one of the datasets
+type DiffEntry struct {
+	IP string `json:"ip"`
+	Location
+}
+
+// DiffChange is an entry whose location differs between the datasets
+type DiffChange struct {
+	IP  string   `json:"ip"`
+	Old Location `json:"old"`
+	New Location `json:"new"`
+}
+
+// CountryMove counts the entries and addresses moved from one country to another, named
+// by ISO 3166-1 alpha-2 code when they have one
+type CountryMove struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Entries   int    `json:"entries"`
+	Addresses uint64 `json:"addresses"`
+}
+
+// CountryStats counts the addresses a country gained and lost. Countries are named by
+// ISO 3166-1 alpha-2 code when they have one.
+type CountryStats struct {
+	Country string `json:"country"`
+	// Added and Removed count addresses of entries only in the new or the old dataset
+	Added   uint64 `json:"added"`
+	Removed uint64 `json:"removed"`
+	// MovedIn and MovedOut count addresses of entries moved from or to another country
+	MovedIn  uint64 `json:"moved_in"`
+	MovedOut uint64 `json:"moved_out"`
+}
+
+// DiffSummary counts the entries of each kind
+type DiffSummary struct {
+	OldEntries int `json:"old_entries"`
+	NewEntries int `json:"new_entries"`
+	Added      int `json:"added"`
+	Removed    int `json:"removed"`
+	// Changed counts entries with a different city or country; Moved is those in a
+	// different country, not merely spelled differently
+	Changed   int `json:"changed"`
+	Moved     int `json:"moved"`
+	Unchanged int `json:"unchanged"`
+}
+
+// Diff is what changed from an old dataset to a new one
+type Diff struct {
+	Old     string      `json:"old"`
+	New     string      `json:"new"`
+	Summary DiffSummary `json:"summary"`
+	// Countries lists the countries with changes, most changed first
+	Countries []CountryStats `json:"countries"`
+	// Moves lists the country changes, the most addresses first
+	Moves []CountryMove `json:"moves"`
+	// Added, Removed and Changed list the entries ordered by IP; they are left out of a summary
+	Added   []DiffEntry  `json:"added,omitempty"`
+	Removed []DiffEntry  `json:"removed,omitempty"`
+	Changed []DiffChange `json:"changed,omitempty"`
+}
+
+// Compare diffs the datasets named oldName and newName, matching entries by IP
+func Compare(oldName string, old Snapshot, newName string, updated Snapshot) Diff {
+	diff := Diff{
+		Old: oldName, New: newName,
+		Summary:   DiffSummary{OldEntries: len(old), NewEntries: len(updated)},
+		Countries: []CountryStats{}, Moves: []CountryMove{},
+	}
+	countries := make(map[string]*CountryStats)
+	country := func(name string) *CountryStats {
+		if countries[name] == nil {
+			countries[name] = &CountryStats{Country: name}
+		}
+		return countries[name]
+	}
+	moves := make(map[[2]string]*CountryMove)
+
+	for ip, was := range old {
+		is, ok := updated[ip]
+		switch {
+		case !ok:
+			diff.Removed = append(diff.Removed, DiffEntry{IP: ip, Location: was})
+			stats := country(countryKey(was.Country))
+			stats.Removed = addCapped(stats.Removed, addresses(ip))
+		case is != was:
+			diff.Changed = append(diff.Changed, DiffChange{IP: ip, Old: was, New: is})
+			fromCountry, toCountry := countryKey(was.Country), countryKey(is.Country)
+			if fromCountry == toCountry {
+				break
+			}
+			n := addresses(ip)
+			from, to := country(fromCountry), country(toCountry)
+			from.MovedOut = addCapped(from.MovedOut, n)
+			to.MovedIn = addCapped(to.MovedIn, n)
+			key := [2]string{fromCountry, toCountry}
+			if moves[key] == nil {
+				moves[key] = &CountryMove{From: fromCountry, To: toCountry}
+			}
+			moves[key].Entries++
+			moves[key].Addresses = addCapped(moves[key].Addresses, n)
+			diff.Summary.Moved++
+		default:
+			diff.Summary.Unchanged++
+		}
+	}
+	for ip, is := range updated {
+		if _, ok := old[ip]; !ok {
+			diff.Added = append(diff.Added, DiffEntry{IP: ip, Location: is})
+			stats := country(countryKey(is.Country))
+			stats.Added = addCapped(stats.Added, addresses(ip))
+		}
+	}
+	diff.Summary.Added = len(diff.Added)
+	diff.Summary.Removed = len(diff.Removed)
+	diff.Summary.Changed = len(diff.Changed)
+
+	byIP := func(a, b DiffEntry) int { return compareIPs(a.IP, b.IP) }
+	slices.SortFunc(diff.Added, byIP)
+	slices.SortFunc(diff.Removed, byIP)
+	slices.SortFunc(diff.Changed, func(a, b DiffChange) int { return compareIPs(a.IP, b.IP) })
+
+	for _, stats := range countries {
+		diff.Countries = append(diff.Countries, *stats)
+	}
+	churn := func(s CountryStats) uint64 {
+		return addCapped(addCapped(s.Added, s.Removed), addCapped(s.MovedIn, s.MovedOut))
+	}
+	slices.SortFunc(diff.Countries, func(a, b CountryStats) int {
+		if c := cmp.Compare(churn(b), churn(a)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Country, b.Country)
+	})
+	for _, move := range moves {
+		diff.Moves = append(diff.Moves, *move)
+	}
+	slices.SortFunc(diff.Moves, func(a, b CountryMove) int {
+		if c := cmp.Compare(b.Addresses, a.Addresses); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.From, b.From); c != 0 {
+			return c
+		}
+		return strings.Compare(a.To, b.To)
+	})
+	return diff
+}
@@ -0,0 +1,65 @@
+package dataset
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func snapshot(rows ...[3]string) Snapshot {
+	s := Snapshot{}
+	for _, row := range rows {
+		s.Add(row[0], row[1], row[2])
+	}
+	return s
+}
+
+func TestCompare(t *testing.T) {
+	old := snapshot(
+		[3]string{"1.2.3.4", "Berlin", "DE"},
+		[3]string{"5.6.7.8", "Munich", "DE"},
+		[3]string{"9.9.9.9", "Paris", "France"},
+		[3]string{"10.0.0.0/24", "Hamburg", "DE"},
+		[3]string{"2001:db8::1", "Rome", "IT"},
+	)
+	updated := snapshot(
+		[3]string{"1.2.3.4", "Amsterdam", "NL"},
+		[3]string{"5.6.7.8", "Munich", "Germany"},
+		[3]string{"::ffff:10.0.0.0/120", "Rotterdam", "Netherlands"},
+		[3]string{"2001:DB8::1", "Rome", "IT"},
+		[3]string{"8.8.8.8", "Mountain View", "US"},
+	)
+
+	diff := Compare("old.csv", old, "new.csv", updated)
+	assert.Equal(t, DiffSummary{OldEntries: 5, NewEntries: 5, Added: 1, Removed: 1, Changed: 3, Moved: 2, Unchanged: 1}, diff.Summary,
+		"addresses match across spellings, and DE to Germany is a change but not a move")
+	assert.Equal(t, []DiffEntry{{IP: "8.8.8.8", Location: Location{City: "Mountain View", Country: "US"}}}, diff.Added)
+	assert.Equal(t, []DiffEntry{{IP: "9.9.9.9", Location: Location{City: "Paris", Country: "France"}}}, diff.Removed)
+	assert.Equal(t, []string{"1.2.3.4", "5.6.7.8", "10.0.0.0/24"},
+		[]string{diff.Changed[0].IP, diff.Changed[1].IP, diff.Changed[2].IP}, "changes are ordered by address")
+	assert.Equal(t, []CountryMove{{From: "DE", To: "NL", Entries: 2, Addresses: 257}}, diff.Moves)
+	assert.Equal(t, []CountryStats{
+		{Country: "DE", MovedOut: 257},
+		{Country: "NL", MovedIn: 257},
+		{Country: "FR", Removed: 1},
+		{Country: "US", Added: 1},
+	}, diff.Countries)
+}
+
+func TestCompare_Identical(t *testing.T) {
+	s := snapshot([3]string{"1.2.3.4", "Berlin", "DE"})
+	diff := Compare("a", s, "b", s)
+	assert.Equal(t, DiffSummary{OldEntries: 1, NewEntries: 1, Unchanged: 1}, diff.Summary)
+	assert.Empty(t, diff.Countries)
+	assert.Empty(t, diff.Moves)
+	assert.Empty(t, diff.Changed)
+}
+
+func TestAddresses(t *testing.T) {
+	assert.Equal(t, uint64(1), addresses("1.2.3.4"))
+	assert.Equal(t, uint64(256), addresses("10.0.0.0/24"))
+	assert.Equal(t, uint64(1)<<32, addresses("0.0.0.0/0"))
+	assert.Equal(t, uint64(math.MaxUint64), addresses("2001:db8::/32"), "large IPv6 ranges are capped")
+	assert.Equal(t, uint64(math.MaxUint64), addCapped(math.MaxUint64, 1))
+}
@@ -0,0 +1,132 @@
+package finder
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strconv"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// LookupResult is the outcome of resolving a single IP address
+type LookupResult struct {
+	XMLName xml.Name `json:"-" msgpack:"-" xml:"result"`
+	IP      string   `json:"ip" msgpack:"ip" xml:"ip"`
+	City    string   `json:"city" msgpack:"city" xml:"city"`
+	Country string   `json:"country" msgpack:"country" xml:"country"`
+	// Override is set when a local override, rather than the IP database, answered
+	Override bool   `json:"override,omitempty" msgpack:"override,omitempty" xml:"override,omitempty"`
+	Error    string `json:"error,omitempty" msgpack:"error,omitempty" xml:"error,omitempty"`
+}
+
+// Encoder writes lookup results in a specific wire format
+type Encoder interface {
+	// ContentType is the media type written in the Content-Type header
+	ContentType() string
+	// EncodeOne writes the result of a single lookup
+	EncodeOne(w io.Writer, result LookupResult) error
+	// EncodeBatch writes the results of a batch lookup, preserving order
+	EncodeBatch(w io.Writer, results []LookupResult) error
+}
+
+// JSONEncoder writes a JSON object for single lookups and a JSON array for batches
+type JSONEncoder struct{}
+
+func (JSONEncoder) ContentType() string { return "application/json" }
+
+func (JSONEncoder) EncodeOne(w io.Writer, result LookupResult) error {
+	return json.NewEncoder(w).Encode(result)
+}
+
+func (JSONEncoder) EncodeBatch(w io.Writer, results []LookupResult) error {
+	if results == nil {
+		results = []LookupResult{}
+	}
+	return json.NewEncoder(w).Encode(results)
+}
+
+// NDJSONEncoder writes one JSON object per line
+type NDJSONEncoder struct{}
+
+func (NDJSONEncoder) ContentType() string { return "application/x-ndjson" }
+
+func (NDJSONEncoder) EncodeOne(w io.Writer, result LookupResult) error {
+	return json.NewEncoder(w).Encode(result)
+}
+
+func (NDJSONEncoder) EncodeBatch(w io.Writer, results []LookupResult) error {
+	enc := json.NewEncoder(w)
+	for _, result := range results {
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CSVEncoder writes a header row followed by one row per result
+type CSVEncoder struct{}
+
+var csvHeader = []string{"ip", "city", "country", "override", "error"}
+
+func (CSVEncoder) ContentType() string { return "text/csv" }
+
+func (e CSVEncoder) EncodeOne(w io.Writer, result LookupResult) error {
+	return e.EncodeBatch(w, []LookupResult{result})
+}
+
+func (CSVEncoder) EncodeBatch(w io.Writer, results []LookupResult) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, result := range results {
+		if err := cw.Write([]string{result.IP, result.City, result.Country, strconv.FormatBool(result.Override), result.Error}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// MessagePackEncoder writes a MessagePack map for single lookups and an array of maps for batches
+type MessagePackEncoder struct{}
+
+func (MessagePackEncoder) ContentType() string { return "application/msgpack" }
+
+func (MessagePackEncoder) EncodeOne(w io.Writer, result LookupResult) error {
+	return msgpack.NewEncoder(w).Encode(result)
+}
+
+func (MessagePackEncoder) EncodeBatch(w io.Writer, results []LookupResult) error {
+	if results == nil {
+		results = []LookupResult{}
+	}
+	return msgpack.NewEncoder(w).Encode(results)
+}
+
+// XMLEncoder writes a <result> element for single lookups and a <results> document for batches
+type XMLEncoder struct{}
+
+type xmlResults struct {
+	XMLName xml.Name       `xml:"results"`
+	Results []LookupResult `xml:"result"`
+}
+
+func (XMLEncoder) ContentType() string { return "application/xml" }
+
+func (XMLEncoder) EncodeOne(w io.Writer, result LookupResult) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(result)
+}
+
+func (XMLEncoder) EncodeBatch(w io.Writer, results []LookupResult) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(xmlResults{Results: results})
+}
@@ -1,21 +1,38 @@
 package finder
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 
+	"github.com/shaibs3/Torq/internal/logger"
 	"github.com/shaibs3/Torq/internal/lookup"
+	"go.uber.org/zap"
 )
 
+// MaxBatchSize is the maximum number of IP addresses accepted in one batch request
+const MaxBatchSize = 1000
+
+// maxBatchBodyBytes bounds the size of a batch request body
+const maxBatchBodyBytes = 1 << 20
+
 type IpFinder struct {
 	provider lookup.DbProvider
+	encoders *Encoders
 }
 
 func NewIpFinder(provider lookup.DbProvider) *IpFinder {
-	return &IpFinder{provider: provider}
+	return NewIpFinderWithEncoders(provider, DefaultEncoders())
+}
+
+// NewIpFinderWithEncoders creates an IpFinder that negotiates response formats from the given registry
+func NewIpFinderWithEncoders(provider lookup.DbProvider, encoders *Encoders) *IpFinder {
+	return &IpFinder{provider: provider, encoders: encoders}
 }
 
 // ValidateIP checks if the provided string is a valid IP address
@@ -37,28 +54,137 @@ func ValidateIP(ip string) error {
 	return nil
 }
 
+// Lookup resolves a validated IP address to its city and country using the configured provider
+func (ipF *IpFinder) Lookup(ctx context.Context, ip string) (string, string, error) {
+	return ipF.provider.Lookup(ctx, ip)
+}
+
+// LookupWithOverride is like Lookup and also reports whether a local override answered
+func (ipF *IpFinder) LookupWithOverride(ctx context.Context, ip string) (string, string, bool, error) {
+	return lookup.LookupWithOverride(ctx, ipF.provider, ip)
+}
+
 func (ipF *IpFinder) FindIpHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+	// The representation depends on Accept, so caches must key on it, 406s included
+	w.Header().Add("Vary", "Accept")
+	enc, err := ipF.encoders.Negotiate(r)
+	if err != nil {
+		writeError(w, http.StatusNotAcceptable, "not_acceptable", err.Error())
+		return
+	}
+
 	ip := r.URL.Query().Get("ip")
 
 	// Validate IP address
 	if err := ValidateIP(ip); err != nil {
-		http.Error(w, fmt.Sprintf(`{"error":"%s"}`, err.Error()), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "invalid_ip", err.Error())
+		return
+	}
+
+	city, country, overridden, err := ipF.LookupWithOverride(r.Context(), ip)
+	if err != nil {
+		logger.FromContext(r.Context()).Debug("lookup failed", zap.String("ip", ip), zap.Error(err))
+		writeError(w, http.StatusNotFound, "not_found", "IP not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", enc.ContentType())
+	w.WriteHeader(http.StatusOK)
+	if err := enc.EncodeOne(w, LookupResult{IP: ip, City: city, Country: country, Override: overridden}); err != nil {
+		logger.FromContext(r.Context()).Warn("failed to write response", zap.String("content_type", enc.ContentType()), zap.Error(err))
+	}
+}
+
+// BatchRequest is the body accepted by FindIpBatchHandler
+type BatchRequest struct {
+	IPs []string `json:"ips"`
+}
+
+// batchBody replaces the body of a batch request once it has been read and decoded,
+// so that the rate limit and quota middleware and the handler decode it only once.
+// Reading it still returns the original bytes.
+type batchBody struct {
+	io.Reader
+	io.Closer
+	req BatchRequest
+	err error
+}
+
+// ParseBatchRequest decodes the batch request body, reading at most 1MB, and keeps the
+// result in the request for later callers
+func ParseBatchRequest(r *http.Request) (BatchRequest, error) {
+	if body, ok := r.Body.(*batchBody); ok {
+		return body.req, body.err
+	}
+	if r.Body == nil {
+		return BatchRequest{}, errors.New("missing request body")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBatchBodyBytes))
+	body := &batchBody{Reader: io.MultiReader(bytes.NewReader(data), r.Body), Closer: r.Body, err: err}
+	if err == nil {
+		body.err = json.NewDecoder(bytes.NewReader(data)).Decode(&body.req)
+	}
+	r.Body = body
+	return body.req, body.err
+}
+
+// FindIpBatchHandler resolves every IP in the request body. Per-address failures are
+// reported in the result's error field rather than failing the whole batch.
+func (ipF *IpFinder) FindIpBatchHandler(w http.ResponseWriter, r *http.Request) {
+	// The representation depends on Accept, so caches must key on it, 406s included
+	w.Header().Add("Vary", "Accept")
+	enc, err := ipF.encoders.Negotiate(r)
+	if err != nil {
+		writeError(w, http.StatusNotAcceptable, "not_acceptable", err.Error())
 		return
 	}
 
-	city, country, err := ipF.provider.Lookup(context.Background(), ip)
+	req, err := ParseBatchRequest(r)
 	if err != nil {
-		http.Error(w, `{"error":"IP not found"}`, http.StatusNotFound)
+		writeError(w, http.StatusBadRequest, "invalid_request", "invalid request body")
+		return
+	}
+	if len(req.IPs) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request", "ips is required")
+		return
+	}
+	if len(req.IPs) > MaxBatchSize {
+		writeError(w, http.StatusBadRequest, "invalid_request", fmt.Sprintf("batch size exceeds limit of %d", MaxBatchSize))
 		return
 	}
 
-	resp := map[string]string{
-		"city":    city,
-		"country": country,
-		"ip":      ip,
+	log := logger.FromContext(r.Context())
+	results := make([]LookupResult, len(req.IPs))
+	for i, ip := range req.IPs {
+		results[i] = LookupResult{IP: ip}
+		if err := ValidateIP(ip); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		city, country, overridden, err := ipF.LookupWithOverride(r.Context(), ip)
+		if err != nil {
+			log.Debug("lookup failed", zap.String("ip", ip), zap.Error(err))
+			results[i].Error = "IP not found"
+			continue
+		}
+		results[i].City = city
+		results[i].Country = country
+		results[i].Override = overridden
 	}
-	jsonResp, _ := json.Marshal(resp)
+
+	w.Header().Set("Content-Type", enc.ContentType())
 	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write(jsonResp)
+	if err := enc.EncodeBatch(w, results); err != nil {
+		log.Warn("failed to write response", zap.String("content_type", enc.ContentType()), zap.Int("batch_size", len(results)), zap.Error(err))
+	}
+}
+
+// writeError writes a JSON error body with a machine-readable code and a human-readable
+// description, the same shape as the router's errors
+func writeError(w http.ResponseWriter, statusCode int, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": code, "error_description": description})
 }
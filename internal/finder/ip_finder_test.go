@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -206,7 +208,7 @@ func TestIpFinder_FindIpHandler_IPNotFound(t *testing.T) {
 	ipFinder.FindIpHandler(w, req)
 
 	assert.Equal(t, http.StatusNotFound, w.Code)
-	assert.Contains(t, w.Body.String(), "IP not found")
+	assert.JSONEq(t, `{"error": "not_found", "error_description": "IP not found"}`, w.Body.String())
 }
 
 func TestIpFinder_FindIpHandler_NoIPParameter(t *testing.T) {
@@ -222,5 +224,75 @@ func TestIpFinder_FindIpHandler_NoIPParameter(t *testing.T) {
 	ipFinder.FindIpHandler(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"error":"invalid_ip"`)
 	assert.Contains(t, w.Body.String(), "IP address is required")
 }
+
+// overridingProvider answers 10.0.0.1 from a local override
+type overridingProvider struct {
+	MockProvider
+}
+
+func (p *overridingProvider) LookupWithOverride(ctx context.Context, ip string) (string, string, bool, error) {
+	if ip == "10.0.0.1" {
+		return "Berlin", "Germany", true, nil
+	}
+	city, country, err := p.Lookup(ctx, ip)
+	return city, country, false, err
+}
+
+func TestIpFinder_ReportsOverrides(t *testing.T) {
+	provider := &overridingProvider{MockProvider{data: map[string]struct {
+		city    string
+		country string
+	}{"8.8.8.8": {"Mountain View", "USA"}}}}
+	ipFinder := NewIpFinder(provider)
+
+	req := httptest.NewRequest("GET", "/v1/find-country?ip=10.0.0.1", nil)
+	w := httptest.NewRecorder()
+	ipFinder.FindIpHandler(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"ip": "10.0.0.1", "city": "Berlin", "country": "Germany", "override": true}`, w.Body.String())
+
+	req = httptest.NewRequest("POST", "/v1/find-country/batch", strings.NewReader(`{"ips": ["10.0.0.1", "8.8.8.8"]}`))
+	w = httptest.NewRecorder()
+	ipFinder.FindIpBatchHandler(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[
+		{"ip": "10.0.0.1", "city": "Berlin", "country": "Germany", "override": true},
+		{"ip": "8.8.8.8", "city": "Mountain View", "country": "USA"}
+	]`, w.Body.String())
+}
+
+// countingReader counts the reads of a request body
+type countingReader struct {
+	*strings.Reader
+	reads int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	c.reads++
+	return c.Reader.Read(p)
+}
+
+func TestParseBatchRequest(t *testing.T) {
+	body := &countingReader{Reader: strings.NewReader(`{"ips": ["1.2.3.4", "5.6.7.8"]}`)}
+	req := httptest.NewRequest("POST", "/v1/find-country/batch", body)
+
+	parsed, err := ParseBatchRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.2.3.4", "5.6.7.8"}, parsed.IPs)
+	reads := body.reads
+
+	again, err := ParseBatchRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, parsed, again)
+	assert.Equal(t, reads, body.reads, "the body is read once")
+
+	raw, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"ips": ["1.2.3.4", "5.6.7.8"]}`, string(raw), "the body can still be read")
+
+	_, err = ParseBatchRequest(httptest.NewRequest("POST", "/v1/find-country/batch", strings.NewReader(`{"ips":`)))
+	assert.Error(t, err)
+}
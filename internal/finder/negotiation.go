@@ -0,0 +1,152 @@
+package finder
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrNotAcceptable is returned when no registered encoder satisfies the request
+type ErrNotAcceptable struct {
+	Supported []string
+}
+
+func (e *ErrNotAcceptable) Error() string {
+	return fmt.Sprintf("not acceptable, supported formats: %s", strings.Join(e.Supported, ", "))
+}
+
+// Encoders selects an Encoder for a request from the Accept header or the format query parameter
+type Encoders struct {
+	mu         sync.RWMutex
+	byFormat   map[string]Encoder
+	byMedia    map[string]Encoder
+	formats    []string
+	defaultEnc Encoder
+}
+
+// NewEncoders creates a registry with no encoders; the first one registered becomes the default
+func NewEncoders() *Encoders {
+	return &Encoders{
+		byFormat: make(map[string]Encoder),
+		byMedia:  make(map[string]Encoder),
+	}
+}
+
+// DefaultEncoders returns a registry with JSON (default), NDJSON, CSV, MessagePack and XML
+func DefaultEncoders() *Encoders {
+	e := NewEncoders()
+	e.Register("json", JSONEncoder{})
+	e.Register("ndjson", NDJSONEncoder{}, "application/jsonl")
+	e.Register("csv", CSVEncoder{})
+	e.Register("msgpack", MessagePackEncoder{}, "application/x-msgpack", "application/vnd.msgpack")
+	e.Register("xml", XMLEncoder{}, "text/xml")
+	return e
+}
+
+// Register adds an encoder under a format name (used by ?format=) and its content type,
+// plus any additional media types it should answer to in the Accept header
+func (e *Encoders) Register(format string, enc Encoder, aliases ...string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, exists := e.byFormat[format]; !exists {
+		e.formats = append(e.formats, format)
+	}
+	e.byFormat[format] = enc
+	e.byMedia[enc.ContentType()] = enc
+	for _, alias := range aliases {
+		e.byMedia[alias] = enc
+	}
+	if e.defaultEnc == nil {
+		e.defaultEnc = enc
+	}
+}
+
+// Negotiate picks the encoder for a request. The format query parameter overrides the
+// Accept header; a missing or wildcard Accept header selects the default encoder.
+func (e *Encoders) Negotiate(r *http.Request) (Encoder, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if format := r.URL.Query().Get("format"); format != "" {
+		if enc, ok := e.byFormat[strings.ToLower(format)]; ok {
+			return enc, nil
+		}
+		return nil, e.notAcceptable()
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return e.defaultEnc, nil
+	}
+
+	for _, mediaType := range parseAccept(accept) {
+		switch {
+		case mediaType == "*/*":
+			return e.defaultEnc, nil
+		case strings.HasSuffix(mediaType, "/*"):
+			prefix := strings.TrimSuffix(mediaType, "*")
+			for _, format := range e.formats {
+				enc := e.byFormat[format]
+				if strings.HasPrefix(enc.ContentType(), prefix) {
+					return enc, nil
+				}
+			}
+		default:
+			if enc, ok := e.byMedia[mediaType]; ok {
+				return enc, nil
+			}
+		}
+	}
+
+	return nil, e.notAcceptable()
+}
+
+func (e *Encoders) notAcceptable() error {
+	supported := make([]string, 0, len(e.formats))
+	for _, format := range e.formats {
+		supported = append(supported, e.byFormat[format].ContentType())
+	}
+	return &ErrNotAcceptable{Supported: supported}
+}
+
+// parseAccept returns the media types of an Accept header ordered by descending quality,
+// dropping those with q=0
+func parseAccept(header string) []string {
+	type candidate struct {
+		mediaType string
+		quality   float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{mediaType: mediaType, quality: quality})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+
+	mediaTypes := make([]string, len(candidates))
+	for i, c := range candidates {
+		mediaTypes[i] = c.mediaType
+	}
+	return mediaTypes
+}
@@ -0,0 +1,173 @@
+package finder
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func newTestFinder() *IpFinder {
+	return NewIpFinder(&MockProvider{
+		data: map[string]struct {
+			city    string
+			country string
+		}{
+			"1.2.3.4": {city: "New York", country: "USA"},
+			"5.6.7.8": {city: "London", country: "UK"},
+		},
+	})
+}
+
+func TestEncoders_Negotiate(t *testing.T) {
+	encoders := DefaultEncoders()
+
+	tests := []struct {
+		name        string
+		query       string
+		accept      string
+		contentType string
+		wantErr     bool
+	}{
+		{name: "no accept header", contentType: "application/json"},
+		{name: "wildcard", accept: "*/*", contentType: "application/json"},
+		{name: "ndjson", accept: "application/x-ndjson", contentType: "application/x-ndjson"},
+		{name: "csv with params", accept: "text/csv; charset=utf-8", contentType: "text/csv"},
+		{name: "msgpack alias", accept: "application/x-msgpack", contentType: "application/msgpack"},
+		{name: "quality ordering", accept: "application/json;q=0.5, application/xml", contentType: "application/xml"},
+		{name: "q=0 excluded", accept: "text/csv;q=0, application/*", contentType: "application/json"},
+		{name: "unsupported", accept: "image/png", wantErr: true},
+		{name: "format overrides accept", query: "format=csv", accept: "application/json", contentType: "text/csv"},
+		{name: "unsupported format", query: "format=yaml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/v1/find-country?"+tt.query, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			enc, err := encoders.Negotiate(req)
+			if tt.wantErr {
+				var notAcceptable *ErrNotAcceptable
+				assert.ErrorAs(t, err, &notAcceptable)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.contentType, enc.ContentType())
+		})
+	}
+}
+
+func TestIpFinder_FindIpHandler_Formats(t *testing.T) {
+	ipFinder := newTestFinder()
+
+	t.Run("csv", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/v1/find-country?ip=1.2.3.4&format=csv", nil)
+		w := httptest.NewRecorder()
+		ipFinder.FindIpHandler(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+		assert.Equal(t, "Accept", w.Header().Get("Vary"))
+		rows, err := csv.NewReader(w.Body).ReadAll()
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{{"ip", "city", "country", "override", "error"}, {"1.2.3.4", "New York", "USA", "false", ""}}, rows)
+	})
+
+	t.Run("msgpack", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/v1/find-country?ip=1.2.3.4", nil)
+		req.Header.Set("Accept", "application/msgpack")
+		w := httptest.NewRecorder()
+		ipFinder.FindIpHandler(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var result map[string]string
+		require.NoError(t, msgpack.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, "USA", result["country"])
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/v1/find-country?ip=1.2.3.4", nil)
+		req.Header.Set("Accept", "application/xml")
+		w := httptest.NewRecorder()
+		ipFinder.FindIpHandler(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var result LookupResult
+		require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, "New York", result.City)
+	})
+
+	t.Run("not acceptable", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/v1/find-country?ip=1.2.3.4", nil)
+		req.Header.Set("Accept", "image/png")
+		w := httptest.NewRecorder()
+		ipFinder.FindIpHandler(w, req)
+
+		assert.Equal(t, http.StatusNotAcceptable, w.Code)
+		assert.Equal(t, "Accept", w.Header().Get("Vary"))
+		assert.Contains(t, w.Body.String(), "application/x-ndjson")
+	})
+}
+
+func TestIpFinder_FindIpBatchHandler(t *testing.T) {
+	ipFinder := newTestFinder()
+
+	t.Run("json", func(t *testing.T) {
+		body := `{"ips": ["1.2.3.4", "9.9.9.9", "bad"]}`
+		req := httptest.NewRequest("POST", "/v1/find-country/batch", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		ipFinder.FindIpBatchHandler(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var results []LookupResult
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &results))
+		require.Len(t, results, 3)
+		assert.Equal(t, "USA", results[0].Country)
+		assert.Equal(t, "IP not found", results[1].Error)
+		assert.Contains(t, results[2].Error, "invalid IP address format")
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		body := `{"ips": ["1.2.3.4", "5.6.7.8"]}`
+		req := httptest.NewRequest("POST", "/v1/find-country/batch", strings.NewReader(body))
+		req.Header.Set("Accept", "application/x-ndjson")
+		w := httptest.NewRecorder()
+		ipFinder.FindIpBatchHandler(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "Accept", w.Header().Get("Vary"))
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		require.Len(t, lines, 2)
+		var second LookupResult
+		require.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+		assert.Equal(t, "London", second.City)
+	})
+
+	t.Run("not acceptable", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/v1/find-country/batch", strings.NewReader(`{"ips": ["1.2.3.4"]}`))
+		req.Header.Set("Accept", "image/png")
+		w := httptest.NewRecorder()
+		ipFinder.FindIpBatchHandler(w, req)
+
+		assert.Equal(t, http.StatusNotAcceptable, w.Code)
+		assert.Equal(t, "Accept", w.Header().Get("Vary"))
+	})
+
+	t.Run("invalid body", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/v1/find-country/batch", strings.NewReader(`{"ips": []}`))
+		w := httptest.NewRecorder()
+		ipFinder.FindIpBatchHandler(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
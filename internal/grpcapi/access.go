@@ -0,0 +1,192 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/shaibs3/Torq/internal/auth"
+	"github.com/shaibs3/Torq/internal/certs"
+	"github.com/shaibs3/Torq/internal/grpcapi/torqv1"
+	"github.com/shaibs3/Torq/internal/identity"
+	"github.com/shaibs3/Torq/internal/quota"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// route is the HTTP route a lookup method mirrors, whose scope, rate limit policy and
+// quota apply to the method. Methods without a route, such as health checks and
+// reflection, are neither authenticated, rate limited nor charged.
+type route struct {
+	request *http.Request
+	scope   auth.Scope
+}
+
+var routes = map[string]route{
+	torqv1.TorqService_FindCountry_FullMethodName:      {request: routeRequest(http.MethodGet, "/v1/find-country"), scope: auth.ScopeLookup},
+	torqv1.TorqService_FindCountryBatch_FullMethodName: {request: routeRequest(http.MethodPost, "/v1/find-country/batch"), scope: auth.ScopeBatch},
+}
+
+// routeRequest builds the request that rate limit policies are matched against
+func routeRequest(method, path string) *http.Request {
+	return &http.Request{Method: method, URL: &url.URL{Path: path}, Header: http.Header{}}
+}
+
+// authUnaryInterceptor attaches the caller's identity and checks its credential
+func (s *Server) authUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// authStreamInterceptor attaches the caller's identity and checks its credential once per stream
+func (s *Server) authStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+}
+
+// authorize identifies the caller like the HTTP identity and auth middleware: by its
+// verified client certificate or address, replaced by the API key ID or token subject
+// read from the x-api-key or authorization metadata when authentication is enabled
+func (s *Server) authorize(ctx context.Context, fullMethod string) (context.Context, error) {
+	r, ok := routes[fullMethod]
+	if !ok {
+		return ctx, nil
+	}
+	caller := identify(ctx)
+	ctx = identity.NewContext(ctx, caller)
+	if s.access.Auth == nil && s.access.JWT == nil {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	apiKey := firstValue(md, auth.APIKeyHeader)
+	principal, err := auth.Verify(auth.KeyFromHeaders(apiKey, firstValue(md, "authorization")), apiKey != "", s.access.Auth, s.access.JWT)
+	if errors.Is(err, auth.ErrMissingKey) {
+		return nil, status.Error(codes.Unauthenticated, "missing API key or bearer token")
+	}
+	if err != nil {
+		s.logger.Warn("credential rejected", zap.String("method", fullMethod), zap.String("client_id", caller.ID), zap.Error(err))
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if !principal.HasScope(r.scope) {
+		s.logger.Warn("caller lacks scope",
+			zap.String("client_id", principal.ID),
+			zap.String("auth_method", principal.Method),
+			zap.String("scope", string(r.scope)),
+			zap.String("method", fullMethod))
+		return nil, status.Error(codes.PermissionDenied, "the "+string(r.scope)+" scope is required")
+	}
+
+	caller.ID = principal.ID
+	caller.Method = principal.Method
+	caller.Scopes = make([]string, len(principal.Scopes))
+	for i, scope := range principal.Scopes {
+		caller.Scopes[i] = string(scope)
+	}
+	return ctx, nil
+}
+
+// identify names the caller by its verified client certificate, or else its address
+func identify(ctx context.Context) *identity.Identity {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return &identity.Identity{}
+	}
+	if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+		if name := certs.PeerName(&info.State); name != "" {
+			return &identity.Identity{ID: name, Method: "mtls", Certificate: name}
+		}
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	return &identity.Identity{ID: "ip-" + host, Method: "ip"}
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// quotaUnaryInterceptor charges one lookup against the caller's quotas, refunded when
+// the address is invalid or not found, as for HTTP requests rejected with a 4xx
+func (s *Server) quotaUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	caller, ok := identity.FromContext(ctx)
+	if _, routed := routes[info.FullMethod]; !routed || !ok || s.access.Quotas == nil {
+		return handler(ctx, req)
+	}
+
+	usage, err := s.access.Quotas.Consume(ctx, caller.ID, 1)
+	if errors.Is(err, quota.ErrQuotaExceeded) {
+		return nil, status.Error(codes.ResourceExhausted, "quota exceeded")
+	}
+	if err != nil {
+		// Quota accounting must not take the API down; admit the call
+		s.logger.Error("failed to consume quota", zap.String("client_id", caller.ID), zap.Error(err))
+		return handler(ctx, req)
+	}
+
+	resp, err := handler(ctx, req)
+	if code := status.Code(err); code == codes.InvalidArgument || code == codes.NotFound {
+		if err := s.access.Quotas.Refund(ctx, usage, 1); err != nil {
+			s.logger.Error("failed to refund quota", zap.String("client_id", caller.ID), zap.Error(err))
+		}
+	}
+	return resp, err
+}
+
+// quotaStreamInterceptor charges one lookup per message received on a stream, like
+// one lookup per IP of an HTTP batch
+func (s *Server) quotaStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	caller, ok := identity.FromContext(ss.Context())
+	if _, routed := routes[info.FullMethod]; !routed || !ok || s.access.Quotas == nil {
+		return handler(srv, ss)
+	}
+	return handler(srv, &quotaStream{ServerStream: ss, server: s, clientID: caller.ID})
+}
+
+type quotaStream struct {
+	grpc.ServerStream
+	server   *Server
+	clientID string
+}
+
+func (qs *quotaStream) RecvMsg(m any) error {
+	if err := qs.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	_, err := qs.server.access.Quotas.Consume(qs.Context(), qs.clientID, 1)
+	if errors.Is(err, quota.ErrQuotaExceeded) {
+		return status.Error(codes.ResourceExhausted, "quota exceeded")
+	}
+	if err != nil {
+		qs.server.logger.Error("failed to consume quota", zap.String("client_id", qs.clientID), zap.Error(err))
+	}
+	return nil
+}
+
+// contextStream replaces the context of a stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (cs *contextStream) Context() context.Context {
+	return cs.ctx
+}
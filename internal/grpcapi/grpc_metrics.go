@@ -0,0 +1,69 @@
+package grpcapi
+
+import (
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+type GRPCMetrics struct {
+	RequestDuration     metric.Float64Histogram
+	RequestCount        metric.Int64Counter
+	ActiveRequests      metric.Int64UpDownCounter
+	StreamMessages      metric.Int64Counter
+	RateLimitedRequests metric.Int64Counter
+}
+
+func NewGRPCMetrics(meter metric.Meter, logger *zap.Logger) *GRPCMetrics {
+	requestDuration, err := meter.Float64Histogram(
+		"grpc_server_handling_seconds",
+		metric.WithDescription("gRPC call duration in seconds"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		logger.Error("failed to create gRPC duration metric", zap.Error(err))
+	}
+
+	requestCount, err := meter.Int64Counter(
+		"grpc_server_handled_total",
+		metric.WithDescription("Total number of gRPC calls completed, by method and status code"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		logger.Error("failed to create gRPC request count metric", zap.Error(err))
+	}
+
+	activeRequests, err := meter.Int64UpDownCounter(
+		"grpc_server_in_flight",
+		metric.WithDescription("Number of gRPC calls currently in flight"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		logger.Error("failed to create gRPC active requests metric", zap.Error(err))
+	}
+
+	streamMessages, err := meter.Int64Counter(
+		"grpc_server_msg_received_total",
+		metric.WithDescription("Total number of messages received on gRPC streams"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		logger.Error("failed to create gRPC stream messages metric", zap.Error(err))
+	}
+
+	rateLimitedRequests, err := meter.Int64Counter(
+		"grpc_rate_limited_requests_total",
+		metric.WithDescription("Total number of gRPC requests that were rate limited"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		logger.Error("failed to create gRPC rate limited requests metric", zap.Error(err))
+	}
+
+	return &GRPCMetrics{
+		RequestDuration:     requestDuration,
+		RequestCount:        requestCount,
+		ActiveRequests:      activeRequests,
+		StreamMessages:      streamMessages,
+		RateLimitedRequests: rateLimitedRequests,
+	}
+}
@@ -0,0 +1,153 @@
+package grpcapi
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/shaibs3/Torq/internal/identity"
+	"github.com/shaibs3/Torq/internal/limiter"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// metricsUnaryInterceptor records duration, count and in-flight gauges for unary calls
+func (s *Server) metricsUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	s.trackInFlight(ctx, 1)
+	defer s.trackInFlight(ctx, -1)
+
+	resp, err := handler(ctx, req)
+
+	s.recordCall(ctx, info.FullMethod, "unary", start, err)
+	return resp, err
+}
+
+// metricsStreamInterceptor records duration, count and in-flight gauges for streaming calls
+func (s *Server) metricsStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx := ss.Context()
+	s.trackInFlight(ctx, 1)
+	defer s.trackInFlight(ctx, -1)
+
+	err := handler(srv, ss)
+
+	s.recordCall(ctx, info.FullMethod, "stream", start, err)
+	return err
+}
+
+// rateLimitUnaryInterceptor consumes one token per unary call from the limiter of the
+// rate limit policy of the mirrored HTTP route
+func (s *Server) rateLimitUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if rl := s.limiterFor(ctx, info.FullMethod); rl != nil {
+		if decision := rl.Decide(); !decision.Allowed {
+			s.recordRateLimited(ctx, info.FullMethod)
+			_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfter(decision.RetryAfter)))
+			return nil, status.Error(codes.ResourceExhausted, "Too Many Requests")
+		}
+	}
+	return handler(ctx, req)
+}
+
+// rateLimitStreamInterceptor consumes one token per message received on a stream,
+// so a batch of N addresses costs the same as N unary calls
+func (s *Server) rateLimitStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if _, ok := routes[info.FullMethod]; !ok {
+		return handler(srv, ss)
+	}
+	rl := s.limiterFor(ss.Context(), info.FullMethod)
+	return handler(srv, &rateLimitedStream{ServerStream: ss, server: s, method: info.FullMethod, limiter: rl})
+}
+
+// limiterFor returns the caller's limiter under the policy of the method's route, or nil
+// when the method is not rate limited
+func (s *Server) limiterFor(ctx context.Context, fullMethod string) limiter.RateLimiter {
+	r, ok := routes[fullMethod]
+	if !ok || s.access.Policies == nil {
+		return nil
+	}
+	policy := s.access.Policies.Match(r.request)
+	if policy == nil || policy.IsExempt(r.request) {
+		return nil
+	}
+	var clientID string
+	if caller, ok := identity.FromContext(ctx); ok {
+		clientID = caller.ID
+	}
+	return policy.LimiterFor(clientID)
+}
+
+// retryAfter rounds a duration up to whole seconds, as in the HTTP Retry-After header
+func retryAfter(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(max(d, 0).Seconds())))
+}
+
+type rateLimitedStream struct {
+	grpc.ServerStream
+	server  *Server
+	method  string
+	limiter limiter.RateLimiter
+}
+
+func (rs *rateLimitedStream) RecvMsg(m any) error {
+	if err := rs.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	ctx := rs.Context()
+	if rs.server.grpcMetrics.StreamMessages != nil {
+		rs.server.grpcMetrics.StreamMessages.Add(ctx, 1, metric.WithAttributes(attribute.String("method", rs.method)))
+	}
+	if rs.limiter == nil {
+		return nil
+	}
+	if decision := rs.limiter.Decide(); !decision.Allowed {
+		rs.server.recordRateLimited(ctx, rs.method)
+		_ = rs.SetHeader(metadata.Pairs("retry-after", retryAfter(decision.RetryAfter)))
+		return status.Error(codes.ResourceExhausted, "Too Many Requests")
+	}
+	return nil
+}
+
+func (s *Server) trackInFlight(ctx context.Context, delta int64) {
+	if s.grpcMetrics.ActiveRequests != nil {
+		s.grpcMetrics.ActiveRequests.Add(ctx, delta)
+	}
+}
+
+func (s *Server) recordCall(ctx context.Context, fullMethod, callType string, start time.Time, err error) {
+	duration := time.Since(start)
+	code := status.Code(err)
+
+	attrs := []attribute.KeyValue{
+		attribute.String("method", fullMethod),
+		attribute.String("type", callType),
+		attribute.String("code", code.String()),
+	}
+
+	if s.grpcMetrics.RequestDuration != nil {
+		s.grpcMetrics.RequestDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(attrs...))
+	}
+	if s.grpcMetrics.RequestCount != nil {
+		s.grpcMetrics.RequestCount.Add(ctx, 1, metric.WithAttributes(attrs...))
+	}
+
+	s.logger.Info("rpc completed",
+		zap.String("method", fullMethod),
+		zap.String("type", callType),
+		zap.String("code", code.String()),
+		zap.Duration("duration", duration),
+	)
+}
+
+func (s *Server) recordRateLimited(ctx context.Context, fullMethod string) {
+	if s.grpcMetrics.RateLimitedRequests != nil {
+		s.grpcMetrics.RateLimitedRequests.Add(ctx, 1, metric.WithAttributes(attribute.String("method", fullMethod)))
+	}
+}
@@ -0,0 +1,140 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+
+	"github.com/shaibs3/Torq/internal/auth"
+	"github.com/shaibs3/Torq/internal/finder"
+	"github.com/shaibs3/Torq/internal/grpcapi/torqv1"
+	"github.com/shaibs3/Torq/internal/limiter"
+	"github.com/shaibs3/Torq/internal/quota"
+	"github.com/shaibs3/Torq/internal/telemetry"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// Server exposes the IP lookup API over gRPC
+type Server struct {
+	torqv1.UnimplementedTorqServiceServer
+
+	ipFinder    *finder.IpFinder
+	access      Access
+	logger      *zap.Logger
+	grpcMetrics *GRPCMetrics
+	grpcServer  *grpc.Server
+	health      *health.Server
+}
+
+// Access holds what guards the HTTP API, so the lookup methods are subject to the same
+// authentication, scopes, rate limit policies and quotas. Nil fields disable their check.
+type Access struct {
+	Policies *limiter.PolicySet
+	Auth     *auth.Authenticator
+	JWT      *auth.JWTValidator
+	Quotas   *quota.Manager
+}
+
+// NewServer creates a gRPC server with the lookup service, health checking and reflection registered.
+// Extra options, such as transport credentials, are passed to the underlying grpc.Server.
+func NewServer(ipFinder *finder.IpFinder, access Access, telemetry *telemetry.Telemetry, logger *zap.Logger, opts ...grpc.ServerOption) *Server {
+	s := &Server{
+		ipFinder:    ipFinder,
+		access:      access,
+		logger:      logger.Named("grpc"),
+		grpcMetrics: NewGRPCMetrics(telemetry.Meter, logger.Named("metrics")),
+		health:      health.NewServer(),
+	}
+
+	// Apply interceptors in order: metrics -> identity and auth -> rate limiting -> quota -> handler
+	s.grpcServer = grpc.NewServer(append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.metricsUnaryInterceptor, s.authUnaryInterceptor, s.rateLimitUnaryInterceptor, s.quotaUnaryInterceptor),
+		grpc.ChainStreamInterceptor(s.metricsStreamInterceptor, s.authStreamInterceptor, s.rateLimitStreamInterceptor, s.quotaStreamInterceptor),
+	}, opts...)...)
+
+	torqv1.RegisterTorqServiceServer(s.grpcServer, s)
+	healthpb.RegisterHealthServer(s.grpcServer, s.health)
+	reflection.Register(s.grpcServer)
+
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	s.health.SetServingStatus(torqv1.TorqService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+
+	return s
+}
+
+// Serve accepts connections on the listener until Stop is called
+func (s *Server) Serve(lis net.Listener) error {
+	s.logger.Info("starting gRPC server", zap.String("addr", lis.Addr().String()))
+	return s.grpcServer.Serve(lis)
+}
+
+// Stop marks the server as not serving and drains in-flight calls, forcing
+// the remaining connections closed if ctx expires first
+func (s *Server) Stop(ctx context.Context) error {
+	s.health.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+		return ctx.Err()
+	}
+}
+
+// FindCountry looks up a single IP address
+func (s *Server) FindCountry(ctx context.Context, req *torqv1.FindCountryRequest) (*torqv1.FindCountryResponse, error) {
+	ip := req.GetIp()
+	if err := finder.ValidateIP(ip); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	city, country, overridden, err := s.ipFinder.LookupWithOverride(ctx, ip)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "IP not found")
+	}
+
+	return &torqv1.FindCountryResponse{Ip: ip, City: city, Country: country, Override: overridden}, nil
+}
+
+// FindCountryBatch answers every request received on the stream with one result
+func (s *Server) FindCountryBatch(stream torqv1.TorqService_FindCountryBatchServer) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		result := &torqv1.FindCountryResult{Ip: req.GetIp()}
+		if err := finder.ValidateIP(req.GetIp()); err != nil {
+			result.Error = err.Error()
+		} else if city, country, overridden, err := s.ipFinder.LookupWithOverride(stream.Context(), req.GetIp()); err != nil {
+			result.Error = "IP not found"
+		} else {
+			result.City = city
+			result.Country = country
+			result.Override = overridden
+		}
+
+		if err := stream.Send(result); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shaibs3/Torq/internal/auth"
+	"github.com/shaibs3/Torq/internal/finder"
+	"github.com/shaibs3/Torq/internal/grpcapi/torqv1"
+	"github.com/shaibs3/Torq/internal/limiter"
+	"github.com/shaibs3/Torq/internal/quota"
+	"github.com/shaibs3/Torq/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type mapProvider map[string][2]string
+
+func (m mapProvider) Lookup(ctx context.Context, ip string) (string, string, error) {
+	if rec, ok := m[ip]; ok {
+		return rec[0], rec[1], nil
+	}
+	return "", "", fmt.Errorf("IP not found")
+}
+
+func newTestClient(t *testing.T, rl limiter.RateLimiter) *grpc.ClientConn {
+	t.Helper()
+	return newTestClientWithAccess(t, Access{Policies: limiter.NewPolicySet(limiter.NewDefaultPolicy(rl))})
+}
+
+func newTestClientWithAccess(t *testing.T, access Access) *grpc.ClientConn {
+	t.Helper()
+	provider := mapProvider{"1.2.3.4": {"New York", "USA"}}
+	tel := &telemetry.Telemetry{Meter: noop.NewMeterProvider().Meter("test")}
+	srv := NewServer(finder.NewIpFinder(provider), access, tel, zap.NewNop())
+
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = srv.Stop(ctx)
+	})
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func newLimiter(t *testing.T, rps, burst int) limiter.RateLimiter {
+	start := time.Unix(0, 0)
+	return limiter.NewBurstRateLimiterWithTimeProvider(rps, burst, zap.NewNop(), func() time.Time { return start })
+}
+
+func TestServer_FindCountry(t *testing.T) {
+	client := torqv1.NewTorqServiceClient(newTestClient(t, newLimiter(t, 10, 10)))
+	ctx := context.Background()
+
+	resp, err := client.FindCountry(ctx, &torqv1.FindCountryRequest{Ip: "1.2.3.4"})
+	require.NoError(t, err)
+	assert.Equal(t, "New York", resp.GetCity())
+	assert.Equal(t, "USA", resp.GetCountry())
+
+	_, err = client.FindCountry(ctx, &torqv1.FindCountryRequest{Ip: "invalid-ip"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.FindCountry(ctx, &torqv1.FindCountryRequest{Ip: "9.9.9.9"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_FindCountryBatch(t *testing.T) {
+	client := torqv1.NewTorqServiceClient(newTestClient(t, newLimiter(t, 10, 10)))
+
+	stream, err := client.FindCountryBatch(context.Background())
+	require.NoError(t, err)
+	for _, ip := range []string{"1.2.3.4", "9.9.9.9", "bad"} {
+		require.NoError(t, stream.Send(&torqv1.FindCountryRequest{Ip: ip}))
+	}
+	require.NoError(t, stream.CloseSend())
+
+	var results []*torqv1.FindCountryResult
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		results = append(results, res)
+	}
+
+	require.Len(t, results, 3)
+	assert.Equal(t, "USA", results[0].GetCountry())
+	assert.Empty(t, results[0].GetError())
+	assert.Equal(t, "IP not found", results[1].GetError())
+	assert.Contains(t, results[2].GetError(), "invalid IP address format")
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	conn := newTestClient(t, newLimiter(t, 1, 2))
+	client := torqv1.NewTorqServiceClient(conn)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := client.FindCountry(ctx, &torqv1.FindCountryRequest{Ip: "1.2.3.4"})
+		require.NoError(t, err)
+	}
+	_, err := client.FindCountry(ctx, &torqv1.FindCountryRequest{Ip: "1.2.3.4"})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// Health checks are exempt from rate limiting
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+}
+
+func TestServer_Auth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf(`{"keys": [
+		{"id": "lookup-only", "key_hash": %q, "scopes": ["lookup"], "enabled": true},
+		{"id": "disabled", "key_hash": %q, "scopes": ["lookup", "batch"], "enabled": false}
+	]}`, auth.HashKey("lookup-secret"), auth.HashKey("disabled-secret"))), 0o600))
+	store, err := auth.NewFileKeyStore(path)
+	require.NoError(t, err)
+	authenticator, err := auth.NewAuthenticator(store, zap.NewNop())
+	require.NoError(t, err)
+	defer authenticator.Close() //nolint:errcheck
+
+	// A per-client policy proves the key ID reaches the rate limiter
+	factory, err := limiter.NewFactory(limiter.FactoryConfig{}, zap.NewNop())
+	require.NoError(t, err)
+	policies, err := limiter.BuildPolicies([]limiter.PolicyConfig{
+		{Name: "lookup", PathPrefix: "/v1/find-country", RPS: 1, Burst: 1, PerClient: true},
+	}, nil, factory, zap.NewNop())
+	require.NoError(t, err)
+
+	conn := newTestClientWithAccess(t, Access{Policies: limiter.NewPolicySet(policies...), Auth: authenticator})
+	client := torqv1.NewTorqServiceClient(conn)
+	withKey := func(key string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+	}
+	req := &torqv1.FindCountryRequest{Ip: "1.2.3.4"}
+
+	_, err = client.FindCountry(context.Background(), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.FindCountry(withKey("disabled-secret"), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "disabled keys are rejected")
+
+	bearer := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer lookup-secret")
+	_, err = client.FindCountry(bearer, req)
+	require.NoError(t, err)
+	_, err = client.FindCountry(withKey("lookup-secret"), req)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err), "the key has its own limiter, whatever the header")
+
+	stream, err := client.FindCountryBatch(withKey("lookup-secret"))
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "batches need the batch scope")
+
+	// Health checks stay anonymous
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+}
+
+func TestServer_Quota(t *testing.T) {
+	store, err := quota.NewFileStore(filepath.Join(t.TempDir(), "quota.json"), zap.NewNop())
+	require.NoError(t, err)
+	quotas := quota.NewManager(quota.Config{Default: quota.Limits{Daily: 3}}, store, zap.NewNop())
+	defer quotas.Close() //nolint:errcheck
+	client := torqv1.NewTorqServiceClient(newTestClientWithAccess(t, Access{Quotas: quotas}))
+	ctx := context.Background()
+
+	_, err = client.FindCountry(ctx, &torqv1.FindCountryRequest{Ip: "9.9.9.9"})
+	assert.Equal(t, codes.NotFound, status.Code(err), "unknown addresses are refunded")
+
+	stream, err := client.FindCountryBatch(ctx)
+	require.NoError(t, err)
+	for _, ip := range []string{"1.2.3.4", "9.9.9.9", "1.2.3.4"} {
+		require.NoError(t, stream.Send(&torqv1.FindCountryRequest{Ip: ip}))
+		_, err := stream.Recv()
+		require.NoError(t, err)
+	}
+	require.NoError(t, stream.CloseSend())
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+
+	_, err = client.FindCountry(ctx, &torqv1.FindCountryRequest{Ip: "1.2.3.4"})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "quota exceeded")
+}
@@ -0,0 +1,354 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.33.0
+// 	protoc        (unknown)
+// source: torq/v1/torq.proto
+
+package torqv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type FindCountryRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ip string `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
+}
+
+func (x *FindCountryRequest) Reset() {
+	*x = FindCountryRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_torq_v1_torq_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FindCountryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FindCountryRequest) ProtoMessage() {}
+
+func (x *FindCountryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_torq_v1_torq_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FindCountryRequest.ProtoReflect.Descriptor instead.
+func (*FindCountryRequest) Descriptor() ([]byte, []int) {
+	return file_torq_v1_torq_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *FindCountryRequest) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+type FindCountryResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ip      string `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
+	City    string `protobuf:"bytes,2,opt,name=city,proto3" json:"city,omitempty"`
+	Country string `protobuf:"bytes,3,opt,name=country,proto3" json:"country,omitempty"`
+	// override is set when a local override, rather than the IP database, answered.
+	Override bool `protobuf:"varint,4,opt,name=override,proto3" json:"override,omitempty"`
+}
+
+func (x *FindCountryResponse) Reset() {
+	*x = FindCountryResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_torq_v1_torq_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FindCountryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FindCountryResponse) ProtoMessage() {}
+
+func (x *FindCountryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_torq_v1_torq_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FindCountryResponse.ProtoReflect.Descriptor instead.
+func (*FindCountryResponse) Descriptor() ([]byte, []int) {
+	return file_torq_v1_torq_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *FindCountryResponse) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *FindCountryResponse) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *FindCountryResponse) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *FindCountryResponse) GetOverride() bool {
+	if x != nil {
+		return x.Override
+	}
+	return false
+}
+
+type FindCountryResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ip      string `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
+	City    string `protobuf:"bytes,2,opt,name=city,proto3" json:"city,omitempty"`
+	Country string `protobuf:"bytes,3,opt,name=country,proto3" json:"country,omitempty"`
+	// error is set when the lookup failed; city and country are then empty.
+	Error string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	// override is set when a local override, rather than the IP database, answered.
+	Override bool `protobuf:"varint,5,opt,name=override,proto3" json:"override,omitempty"`
+}
+
+func (x *FindCountryResult) Reset() {
+	*x = FindCountryResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_torq_v1_torq_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FindCountryResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FindCountryResult) ProtoMessage() {}
+
+func (x *FindCountryResult) ProtoReflect() protoreflect.Message {
+	mi := &file_torq_v1_torq_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FindCountryResult.ProtoReflect.Descriptor instead.
+func (*FindCountryResult) Descriptor() ([]byte, []int) {
+	return file_torq_v1_torq_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *FindCountryResult) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *FindCountryResult) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *FindCountryResult) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *FindCountryResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *FindCountryResult) GetOverride() bool {
+	if x != nil {
+		return x.Override
+	}
+	return false
+}
+
+var File_torq_v1_torq_proto protoreflect.FileDescriptor
+
+var file_torq_v1_torq_proto_rawDesc = []byte{
+	0x0a, 0x12, 0x74, 0x6f, 0x72, 0x71, 0x2f, 0x76, 0x31, 0x2f, 0x74, 0x6f, 0x72, 0x71, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x12, 0x07, 0x74, 0x6f, 0x72, 0x71, 0x2e, 0x76, 0x31, 0x22, 0x24, 0x0a,
+	0x12, 0x46, 0x69, 0x6e, 0x64, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x70, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x02, 0x69, 0x70, 0x22, 0x6f, 0x0a, 0x13, 0x46, 0x69, 0x6e, 0x64, 0x43, 0x6f, 0x75, 0x6e, 0x74,
+	0x72, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x70,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x70, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x69,
+	0x74, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x63, 0x69, 0x74, 0x79, 0x12, 0x18,
+	0x0a, 0x07, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x72, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x07, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x1a, 0x0a, 0x08, 0x6f, 0x76, 0x65, 0x72,
+	0x72, 0x69, 0x64, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x6f, 0x76, 0x65, 0x72,
+	0x72, 0x69, 0x64, 0x65, 0x22, 0x83, 0x01, 0x0a, 0x11, 0x46, 0x69, 0x6e, 0x64, 0x43, 0x6f, 0x75,
+	0x6e, 0x74, 0x72, 0x79, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x70,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x70, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x69,
+	0x74, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x63, 0x69, 0x74, 0x79, 0x12, 0x18,
+	0x0a, 0x07, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x72, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x07, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f,
+	0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x1a,
+	0x0a, 0x08, 0x6f, 0x76, 0x65, 0x72, 0x72, 0x69, 0x64, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x08, 0x6f, 0x76, 0x65, 0x72, 0x72, 0x69, 0x64, 0x65, 0x32, 0xa8, 0x01, 0x0a, 0x0b, 0x54,
+	0x6f, 0x72, 0x71, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x48, 0x0a, 0x0b, 0x46, 0x69,
+	0x6e, 0x64, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x1b, 0x2e, 0x74, 0x6f, 0x72, 0x71,
+	0x2e, 0x76, 0x31, 0x2e, 0x46, 0x69, 0x6e, 0x64, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x72, 0x79, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x74, 0x6f, 0x72, 0x71, 0x2e, 0x76, 0x31,
+	0x2e, 0x46, 0x69, 0x6e, 0x64, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4f, 0x0a, 0x10, 0x46, 0x69, 0x6e, 0x64, 0x43, 0x6f, 0x75, 0x6e,
+	0x74, 0x72, 0x79, 0x42, 0x61, 0x74, 0x63, 0x68, 0x12, 0x1b, 0x2e, 0x74, 0x6f, 0x72, 0x71, 0x2e,
+	0x76, 0x31, 0x2e, 0x46, 0x69, 0x6e, 0x64, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x74, 0x6f, 0x72, 0x71, 0x2e, 0x76, 0x31, 0x2e,
+	0x46, 0x69, 0x6e, 0x64, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x65, 0x73, 0x75, 0x6c,
+	0x74, 0x28, 0x01, 0x30, 0x01, 0x42, 0x38, 0x5a, 0x36, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
+	0x63, 0x6f, 0x6d, 0x2f, 0x73, 0x68, 0x61, 0x69, 0x62, 0x73, 0x33, 0x2f, 0x54, 0x6f, 0x72, 0x71,
+	0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x67, 0x72, 0x70, 0x63, 0x61, 0x70,
+	0x69, 0x2f, 0x74, 0x6f, 0x72, 0x71, 0x76, 0x31, 0x3b, 0x74, 0x6f, 0x72, 0x71, 0x76, 0x31, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_torq_v1_torq_proto_rawDescOnce sync.Once
+	file_torq_v1_torq_proto_rawDescData = file_torq_v1_torq_proto_rawDesc
+)
+
+func file_torq_v1_torq_proto_rawDescGZIP() []byte {
+	file_torq_v1_torq_proto_rawDescOnce.Do(func() {
+		file_torq_v1_torq_proto_rawDescData = protoimpl.X.CompressGZIP(file_torq_v1_torq_proto_rawDescData)
+	})
+	return file_torq_v1_torq_proto_rawDescData
+}
+
+var file_torq_v1_torq_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_torq_v1_torq_proto_goTypes = []interface{}{
+	(*FindCountryRequest)(nil),  // 0: torq.v1.FindCountryRequest
+	(*FindCountryResponse)(nil), // 1: torq.v1.FindCountryResponse
+	(*FindCountryResult)(nil),   // 2: torq.v1.FindCountryResult
+}
+var file_torq_v1_torq_proto_depIdxs = []int32{
+	0, // 0: torq.v1.TorqService.FindCountry:input_type -> torq.v1.FindCountryRequest
+	0, // 1: torq.v1.TorqService.FindCountryBatch:input_type -> torq.v1.FindCountryRequest
+	1, // 2: torq.v1.TorqService.FindCountry:output_type -> torq.v1.FindCountryResponse
+	2, // 3: torq.v1.TorqService.FindCountryBatch:output_type -> torq.v1.FindCountryResult
+	2, // [2:4] is the sub-list for method output_type
+	0, // [0:2] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_torq_v1_torq_proto_init() }
+func file_torq_v1_torq_proto_init() {
+	if File_torq_v1_torq_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_torq_v1_torq_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FindCountryRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_torq_v1_torq_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FindCountryResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_torq_v1_torq_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FindCountryResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_torq_v1_torq_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_torq_v1_torq_proto_goTypes,
+		DependencyIndexes: file_torq_v1_torq_proto_depIdxs,
+		MessageInfos:      file_torq_v1_torq_proto_msgTypes,
+	}.Build()
+	File_torq_v1_torq_proto = out.File
+	file_torq_v1_torq_proto_rawDesc = nil
+	file_torq_v1_torq_proto_goTypes = nil
+	file_torq_v1_torq_proto_depIdxs = nil
+}
@@ -0,0 +1,193 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: torq/v1/torq.proto
+
+package torqv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	TorqService_FindCountry_FullMethodName      = "/torq.v1.TorqService/FindCountry"
+	TorqService_FindCountryBatch_FullMethodName = "/torq.v1.TorqService/FindCountryBatch"
+)
+
+// TorqServiceClient is the client API for TorqService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// TorqService resolves IP addresses to their city and country.
+type TorqServiceClient interface {
+	// FindCountry looks up a single IP address.
+	FindCountry(ctx context.Context, in *FindCountryRequest, opts ...grpc.CallOption) (*FindCountryResponse, error)
+	// FindCountryBatch looks up a stream of IP addresses. One result is sent
+	// back for every request, in order. Lookup failures are reported per
+	// result and do not terminate the stream.
+	FindCountryBatch(ctx context.Context, opts ...grpc.CallOption) (TorqService_FindCountryBatchClient, error)
+}
+
+type torqServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTorqServiceClient(cc grpc.ClientConnInterface) TorqServiceClient {
+	return &torqServiceClient{cc}
+}
+
+func (c *torqServiceClient) FindCountry(ctx context.Context, in *FindCountryRequest, opts ...grpc.CallOption) (*FindCountryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FindCountryResponse)
+	err := c.cc.Invoke(ctx, TorqService_FindCountry_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *torqServiceClient) FindCountryBatch(ctx context.Context, opts ...grpc.CallOption) (TorqService_FindCountryBatchClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &TorqService_ServiceDesc.Streams[0], TorqService_FindCountryBatch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &torqServiceFindCountryBatchClient{ClientStream: stream}
+	return x, nil
+}
+
+type TorqService_FindCountryBatchClient interface {
+	Send(*FindCountryRequest) error
+	Recv() (*FindCountryResult, error)
+	grpc.ClientStream
+}
+
+type torqServiceFindCountryBatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *torqServiceFindCountryBatchClient) Send(m *FindCountryRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *torqServiceFindCountryBatchClient) Recv() (*FindCountryResult, error) {
+	m := new(FindCountryResult)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// TorqServiceServer is the server API for TorqService service.
+// All implementations must embed UnimplementedTorqServiceServer
+// for forward compatibility
+//
+// TorqService resolves IP addresses to their city and country.
+type TorqServiceServer interface {
+	// FindCountry looks up a single IP address.
+	FindCountry(context.Context, *FindCountryRequest) (*FindCountryResponse, error)
+	// FindCountryBatch looks up a stream of IP addresses. One result is sent
+	// back for every request, in order. Lookup failures are reported per
+	// result and do not terminate the stream.
+	FindCountryBatch(TorqService_FindCountryBatchServer) error
+	mustEmbedUnimplementedTorqServiceServer()
+}
+
+// UnimplementedTorqServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedTorqServiceServer struct {
+}
+
+func (UnimplementedTorqServiceServer) FindCountry(context.Context, *FindCountryRequest) (*FindCountryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindCountry not implemented")
+}
+func (UnimplementedTorqServiceServer) FindCountryBatch(TorqService_FindCountryBatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method FindCountryBatch not implemented")
+}
+func (UnimplementedTorqServiceServer) mustEmbedUnimplementedTorqServiceServer() {}
+
+// UnsafeTorqServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TorqServiceServer will
+// result in compilation errors.
+type UnsafeTorqServiceServer interface {
+	mustEmbedUnimplementedTorqServiceServer()
+}
+
+func RegisterTorqServiceServer(s grpc.ServiceRegistrar, srv TorqServiceServer) {
+	s.RegisterService(&TorqService_ServiceDesc, srv)
+}
+
+func _TorqService_FindCountry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindCountryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TorqServiceServer).FindCountry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TorqService_FindCountry_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TorqServiceServer).FindCountry(ctx, req.(*FindCountryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TorqService_FindCountryBatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TorqServiceServer).FindCountryBatch(&torqServiceFindCountryBatchServer{ServerStream: stream})
+}
+
+type TorqService_FindCountryBatchServer interface {
+	Send(*FindCountryResult) error
+	Recv() (*FindCountryRequest, error)
+	grpc.ServerStream
+}
+
+type torqServiceFindCountryBatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *torqServiceFindCountryBatchServer) Send(m *FindCountryResult) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *torqServiceFindCountryBatchServer) Recv() (*FindCountryRequest, error) {
+	m := new(FindCountryRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// TorqService_ServiceDesc is the grpc.ServiceDesc for TorqService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TorqService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "torq.v1.TorqService",
+	HandlerType: (*TorqServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "FindCountry",
+			Handler:    _TorqService_FindCountry_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "FindCountryBatch",
+			Handler:       _TorqService_FindCountryBatch_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "torq/v1/torq.proto",
+}
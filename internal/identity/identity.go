@@ -0,0 +1,38 @@
+package identity
+
+import "context"
+
+// Identity describes the caller of a request
+type Identity struct {
+	// ID identifies the caller for quotas, per-client rate limiting and logging
+	ID string
+	// Method records how the caller was identified, e.g. "api_key" or "ip"
+	Method string
+	// Scopes lists what an authenticated caller may access; empty for anonymous callers
+	Scopes []string
+	// Certificate is the SAN or CN of the caller's verified TLS client certificate, if any
+	Certificate string
+}
+
+// HasScope reports whether the caller was granted the scope
+func (id *Identity) HasScope(scope string) bool {
+	for _, s := range id.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the identity
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the identity stored in ctx, if any
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(contextKey{}).(*Identity)
+	return id, ok && id != nil
+}
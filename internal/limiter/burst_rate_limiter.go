@@ -2,6 +2,7 @@ package limiter
 
 import (
 	"go.uber.org/zap"
+	"math"
 	"sync"
 	"time"
 )
@@ -34,10 +35,18 @@ func NewBurstRateLimiterWithTimeProvider(rps int, burst int, logger *zap.Logger,
 }
 
 func (l *BurstRateLimiter) Allow() bool {
+	return l.Decide().Allowed
+}
+
+func (l *BurstRateLimiter) Decide() Decision {
+	return l.DecideN(1)
+}
+
+func (l *BurstRateLimiter) DecideN(n int) (decision Decision) {
 	l.mu.Lock()
 	defer func() {
 		if r := recover(); r != nil {
-			l.logger.Error("Panic in rate limiter Allow()", zap.Any("error", r))
+			l.logger.Error("Panic in rate limiter DecideN()", zap.Any("error", r))
 		}
 		l.mu.Unlock() // always unlock, even if panic
 	}()
@@ -52,11 +61,54 @@ func (l *BurstRateLimiter) Allow() bool {
 	}
 	l.lastRefill = now
 
-	if l.tokens >= 1 {
-		l.tokens -= 1
-		return true
+	cost := float64(n)
+	allowed := false
+	if l.tokens >= cost {
+		l.tokens -= cost
+		allowed = true
+	} else {
+		l.logger.Warn("Rate limit exceeded", zap.Float64("tokens", l.tokens), zap.Float64("burst", l.burst), zap.Int("cost", n))
 	}
 
-	l.logger.Warn("Rate limit exceeded", zap.Float64("tokens", l.tokens), zap.Float64("burst", l.burst))
-	return false
+	return Decision{
+		Allowed:    allowed,
+		Limit:      int(l.burst),
+		Remaining:  int(math.Floor(l.tokens)),
+		Reset:      l.timeUntil(l.burst),
+		RetryAfter: l.timeUntil(cost),
+	}
+}
+
+// timeUntil returns how long it takes for the bucket to refill to the given number of tokens
+func (l *BurstRateLimiter) timeUntil(tokens float64) time.Duration {
+	missing := tokens - l.tokens
+	if missing <= 0 || l.limit <= 0 {
+		return 0
+	}
+	return time.Duration(missing / l.limit * float64(time.Second))
+}
+
+func (l *BurstRateLimiter) Algorithm() Algorithm {
+	return AlgorithmTokenBucket
+}
+
+// Reconfigure changes the refill rate and capacity. Tokens earned at the old rate are
+// kept, up to the new burst.
+func (l *BurstRateLimiter) Reconfigure(spec LimitSpec) error {
+	if err := checkAlgorithm(spec, l.Algorithm()); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.timeProvider()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.lastRefill).Seconds()*l.limit)
+	l.lastRefill = now
+
+	l.limit = float64(spec.RPS)
+	l.burst = float64(spec.Burst)
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	return nil
 }
@@ -88,3 +88,72 @@ func TestBurstRateLimiter_Concurrent(t *testing.T) {
 	}
 	assert.Equal(t, 5, allowed, "should allow up to burst size concurrently")
 }
+
+func TestBurstRateLimiter_Decide(t *testing.T) {
+	ft := &fakeTime{current: time.Unix(0, 0)}
+	logger := zaptest.NewLogger(t)
+	limiter := NewBurstRateLimiterWithTimeProvider(2, 3, logger, ft.Now)
+
+	d := limiter.Decide()
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 3, d.Limit)
+	assert.Equal(t, 2, d.Remaining)
+	assert.Equal(t, 500*time.Millisecond, d.Reset)
+	assert.Zero(t, d.RetryAfter)
+
+	limiter.Decide()
+	d = limiter.Decide()
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining)
+	assert.Equal(t, 1500*time.Millisecond, d.Reset)
+	assert.Equal(t, 500*time.Millisecond, d.RetryAfter)
+
+	d = limiter.Decide()
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 500*time.Millisecond, d.RetryAfter)
+
+	// Half a token refilled: next token is 250ms away
+	ft.Advance(250 * time.Millisecond)
+	d = limiter.Decide()
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 250*time.Millisecond, d.RetryAfter)
+}
+
+func TestBurstRateLimiter_Reconfigure(t *testing.T) {
+	ft := &fakeTime{current: time.Unix(0, 0)}
+	limiter := NewBurstRateLimiterWithTimeProvider(1, 3, zaptest.NewLogger(t), ft.Now)
+
+	require.True(t, limiter.Allow())
+	require.True(t, limiter.Allow())
+
+	// The remaining token is kept; the bucket now refills faster up to a larger burst
+	require.NoError(t, Reconfigure(limiter, LimitSpec{RPS: 10, Burst: 20}))
+	d := limiter.Decide()
+	require.True(t, d.Allowed)
+	assert.Equal(t, 20, d.Limit)
+	assert.Equal(t, 0, d.Remaining)
+
+	ft.Advance(time.Second)
+	assert.Equal(t, 9, limiter.Decide().Remaining)
+
+	// Shrinking the burst caps the tokens already in the bucket
+	require.NoError(t, Reconfigure(limiter, LimitSpec{RPS: 1, Burst: 2}))
+	assert.Equal(t, 1, limiter.Decide().Remaining)
+}
+
+func TestReconfigure_Rejects(t *testing.T) {
+	ft := &fakeTime{current: time.Unix(0, 0)}
+	limiter := NewBurstRateLimiterWithTimeProvider(1, 3, zaptest.NewLogger(t), ft.Now)
+
+	assert.ErrorContains(t, Reconfigure(limiter, LimitSpec{RPS: 0, Burst: 3}), "must be positive")
+	assert.ErrorContains(t, Reconfigure(limiter, LimitSpec{Algorithm: AlgorithmGCRA, RPS: 1, Burst: 3}),
+		"cannot change rate limit algorithm from token_bucket to gcra")
+	assert.ErrorContains(t, Reconfigure(allowAll{}, LimitSpec{RPS: 1, Burst: 1}), "cannot be reconfigured")
+}
+
+// allowAll is a limiter without runtime reconfiguration
+type allowAll struct{}
+
+func (allowAll) Allow() bool          { return true }
+func (allowAll) Decide() Decision     { return Decision{Allowed: true} }
+func (allowAll) DecideN(int) Decision { return Decision{Allowed: true} }
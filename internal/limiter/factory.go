@@ -0,0 +1,199 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Backend identifies where rate limiter state is kept
+type Backend string
+
+const (
+	BackendMemory Backend = "memory"
+	BackendRedis  Backend = "redis"
+)
+
+// IsValid checks if the backend is supported
+func (b Backend) IsValid() bool {
+	switch b {
+	case BackendMemory, BackendRedis:
+		return true
+	default:
+		return false
+	}
+}
+
+// Algorithm identifies a rate limiting algorithm
+type Algorithm string
+
+const (
+	// AlgorithmTokenBucket refills rps tokens per second up to burst (BurstRateLimiter)
+	AlgorithmTokenBucket Algorithm = "token_bucket"
+	// AlgorithmGCRA admits the same rate and burst as a token bucket with even pacing
+	AlgorithmGCRA Algorithm = "gcra"
+	// AlgorithmSlidingWindowLog admits at most limit requests in any window, exactly
+	AlgorithmSlidingWindowLog Algorithm = "sliding_window_log"
+	// AlgorithmSlidingWindowCounter approximates a sliding window with two fixed windows
+	AlgorithmSlidingWindowCounter Algorithm = "sliding_window_counter"
+)
+
+// IsValid checks if the algorithm is supported
+func (a Algorithm) IsValid() bool {
+	switch a {
+	case AlgorithmTokenBucket, AlgorithmGCRA, AlgorithmSlidingWindowLog, AlgorithmSlidingWindowCounter:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsWindowed reports whether the algorithm is parameterised by limit and window rather than rps and burst
+func (a Algorithm) IsWindowed() bool {
+	return a == AlgorithmSlidingWindowLog || a == AlgorithmSlidingWindowCounter
+}
+
+// LimitSpec describes one limiter. Token bucket and GCRA use RPS and Burst;
+// the sliding window algorithms admit Limit requests per Window.
+type LimitSpec struct {
+	Name      string
+	Algorithm Algorithm
+	RPS       int
+	Burst     int
+	Limit     int
+	Window    time.Duration
+}
+
+// refillTime is how long an unused limiter takes to forget past requests: two windows,
+// as the sliding window counter weighs the previous one, or the time to refill a burst
+func (s LimitSpec) refillTime() time.Duration {
+	refill := 2 * s.Window
+	if s.RPS > 0 {
+		refill = max(refill, time.Duration(s.Burst)*time.Second/time.Duration(s.RPS))
+	}
+	return refill
+}
+
+// Validate checks that the parameters required by the algorithm are set
+func (s LimitSpec) Validate() error {
+	algorithm := s.Algorithm
+	if algorithm == "" {
+		algorithm = AlgorithmTokenBucket
+	}
+	if !algorithm.IsValid() {
+		return fmt.Errorf("unsupported rate limit algorithm: %s", algorithm)
+	}
+	if algorithm.IsWindowed() {
+		if s.Limit <= 0 || s.Window <= 0 {
+			return fmt.Errorf("limit and window must be positive for %s", algorithm)
+		}
+		return nil
+	}
+	if s.RPS <= 0 || s.Burst <= 0 {
+		return fmt.Errorf("rps and burst must be positive for %s", algorithm)
+	}
+	return nil
+}
+
+// FactoryConfig selects and configures the rate limiter backend
+type FactoryConfig struct {
+	Backend       Backend
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+	KeyPrefix     string
+	FailureMode   FailureMode
+}
+
+// Factory creates rate limiters for policies using the configured backend
+type Factory struct {
+	config FactoryConfig
+	client *redis.Client
+	logger *zap.Logger
+}
+
+func NewFactory(config FactoryConfig, logger *zap.Logger) (*Factory, error) {
+	logger = logger.Named("rate_limiter")
+	if config.Backend == "" {
+		config.Backend = BackendMemory
+	}
+	if !config.Backend.IsValid() {
+		return nil, fmt.Errorf("unsupported rate limit backend: %s", config.Backend)
+	}
+
+	f := &Factory{config: config, logger: logger}
+	if config.Backend != BackendRedis {
+		return f, nil
+	}
+
+	if config.FailureMode == "" {
+		f.config.FailureMode = FailureModeLocal
+	}
+	if !f.config.FailureMode.IsValid() {
+		return nil, fmt.Errorf("unsupported rate limit failure mode: %s", config.FailureMode)
+	}
+	if config.RedisAddr == "" {
+		return nil, fmt.Errorf("redis address is required for the redis rate limit backend")
+	}
+
+	f.client = redis.NewClient(&redis.Options{
+		Addr:     config.RedisAddr,
+		Password: config.RedisPassword,
+		DB:       config.RedisDB,
+	})
+
+	// The limiter degrades per its failure mode, so an unreachable store is not fatal at startup
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := f.client.Ping(ctx).Err(); err != nil {
+		logger.Warn("rate limit store unreachable at startup",
+			zap.String("addr", config.RedisAddr),
+			zap.String("failure_mode", string(f.config.FailureMode)),
+			zap.Error(err))
+	}
+
+	logger.Info("using redis rate limit backend",
+		zap.String("addr", config.RedisAddr),
+		zap.Int("db", config.RedisDB),
+		zap.String("key_prefix", config.KeyPrefix),
+		zap.String("failure_mode", string(f.config.FailureMode)))
+
+	return f, nil
+}
+
+// New creates the limiter described by spec
+func (f *Factory) New(spec LimitSpec) (RateLimiter, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	logger := f.logger.With(zap.String("policy", spec.Name))
+	if f.config.Backend == BackendRedis {
+		if spec.Algorithm != "" && spec.Algorithm != AlgorithmTokenBucket {
+			return nil, fmt.Errorf("rate limit algorithm %s is not supported by the redis backend", spec.Algorithm)
+		}
+		return NewRedisRateLimiterWithTimeProvider(f.client, f.config.KeyPrefix+spec.Name, spec.RPS, spec.Burst, f.config.FailureMode, logger, time.Now), nil
+	}
+
+	switch spec.Algorithm {
+	case AlgorithmGCRA:
+		return NewGCRARateLimiterWithTimeProvider(spec.RPS, spec.Burst, logger, time.Now), nil
+	case AlgorithmSlidingWindowLog:
+		return NewSlidingWindowLogRateLimiterWithTimeProvider(spec.Limit, spec.Window, logger, time.Now), nil
+	case AlgorithmSlidingWindowCounter:
+		return NewSlidingWindowCounterRateLimiterWithTimeProvider(spec.Limit, spec.Window, logger, time.Now), nil
+	default:
+		return NewBurstRateLimiterWithTimeProvider(spec.RPS, spec.Burst, logger, time.Now), nil
+	}
+}
+
+// Close releases the connection to the rate limit store, if any
+func (f *Factory) Close() error {
+	if f.client == nil {
+		return nil
+	}
+	return f.client.Close()
+}
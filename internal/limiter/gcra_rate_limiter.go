@@ -0,0 +1,103 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// GCRARateLimiter implements the generic cell rate algorithm. It admits the same
+// long-run rate and burst as a token bucket but tracks a single theoretical arrival
+// time, which spaces requests evenly once the burst allowance is used up.
+type GCRARateLimiter struct {
+	mu           sync.Mutex
+	tat          time.Time     // theoretical arrival time of the next request
+	interval     time.Duration // emission interval (1/rps)
+	burst        int
+	logger       *zap.Logger
+	timeProvider TimeProvider
+}
+
+func NewGCRARateLimiter(rps int, burst int, logger *zap.Logger) *GCRARateLimiter {
+	return NewGCRARateLimiterWithTimeProvider(rps, burst, logger.Named("rate_limiter"), time.Now)
+}
+
+func NewGCRARateLimiterWithTimeProvider(rps int, burst int, logger *zap.Logger, tp TimeProvider) *GCRARateLimiter {
+	return &GCRARateLimiter{
+		tat:          tp(),
+		interval:     time.Second / time.Duration(rps),
+		burst:        burst,
+		logger:       logger,
+		timeProvider: tp,
+	}
+}
+
+func (l *GCRARateLimiter) Allow() bool {
+	return l.Decide().Allowed
+}
+
+func (l *GCRARateLimiter) Decide() Decision {
+	return l.DecideN(1)
+}
+
+func (l *GCRARateLimiter) DecideN(n int) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.timeProvider()
+	tat := l.tat
+	if tat.Before(now) {
+		tat = now
+	}
+
+	// The bucket may run at most burst intervals ahead of real time
+	capacity := time.Duration(l.burst) * l.interval
+	newTat := tat.Add(time.Duration(n) * l.interval)
+	allowed := newTat.Sub(now) <= capacity
+	if allowed {
+		tat = newTat
+		l.tat = newTat
+	} else {
+		l.logger.Warn("Rate limit exceeded", zap.Duration("ahead", tat.Sub(now)), zap.Int("burst", l.burst), zap.Int("cost", n))
+	}
+
+	ahead := tat.Sub(now)
+	retryAfter := time.Duration(0)
+	if wait := tat.Add(time.Duration(n)*l.interval).Sub(now) - capacity; wait > 0 {
+		retryAfter = wait
+	}
+
+	return Decision{
+		Allowed:    allowed,
+		Limit:      l.burst,
+		Remaining:  int((capacity - ahead) / l.interval),
+		Reset:      ahead,
+		RetryAfter: retryAfter,
+	}
+}
+
+func (l *GCRARateLimiter) Algorithm() Algorithm {
+	return AlgorithmGCRA
+}
+
+// Reconfigure changes the rate and burst. Requests already admitted keep their place,
+// so the debt carried in the theoretical arrival time is honoured at the new rate.
+func (l *GCRARateLimiter) Reconfigure(spec LimitSpec) error {
+	if err := checkAlgorithm(spec, l.Algorithm()); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.timeProvider()
+	interval := time.Second / time.Duration(spec.RPS)
+	if l.tat.After(now) {
+		// Rescale the outstanding debt from old intervals to new ones
+		debt := float64(l.tat.Sub(now)) / float64(l.interval)
+		l.tat = now.Add(time.Duration(debt * float64(interval)))
+	}
+	l.interval = interval
+	l.burst = spec.Burst
+	return nil
+}
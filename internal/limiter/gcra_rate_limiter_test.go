@@ -0,0 +1,78 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestGCRARateLimiter_Burst(t *testing.T) {
+	ft := &fakeTime{current: time.Unix(0, 0)}
+	limiter := NewGCRARateLimiterWithTimeProvider(2, 3, zaptest.NewLogger(t), ft.Now)
+
+	d := limiter.Decide()
+	require.True(t, d.Allowed)
+	assert.Equal(t, 3, d.Limit)
+	assert.Equal(t, 2, d.Remaining)
+	require.True(t, limiter.Allow())
+	require.True(t, limiter.Allow())
+
+	d = limiter.Decide()
+	assert.False(t, d.Allowed, "should not allow more than burst")
+	assert.Equal(t, 0, d.Remaining)
+	assert.Equal(t, 500*time.Millisecond, d.RetryAfter)
+	assert.Equal(t, 1500*time.Millisecond, d.Reset)
+}
+
+func TestGCRARateLimiter_Pacing(t *testing.T) {
+	ft := &fakeTime{current: time.Unix(0, 0)}
+	limiter := NewGCRARateLimiterWithTimeProvider(2, 3, zaptest.NewLogger(t), ft.Now)
+
+	for i := 0; i < 3; i++ {
+		require.True(t, limiter.Allow())
+	}
+
+	// Once the burst is spent, one request is admitted per emission interval
+	ft.Advance(499 * time.Millisecond)
+	assert.False(t, limiter.Allow())
+	ft.Advance(time.Millisecond)
+	assert.True(t, limiter.Allow())
+	assert.False(t, limiter.Allow())
+
+	// A long idle period restores the burst but never more than it
+	ft.Advance(time.Minute)
+	for i := 0; i < 3; i++ {
+		assert.True(t, limiter.Allow())
+	}
+	assert.False(t, limiter.Allow())
+}
+
+func TestGCRARateLimiter_DecideN(t *testing.T) {
+	ft := &fakeTime{current: time.Unix(0, 0)}
+	limiter := NewGCRARateLimiterWithTimeProvider(2, 3, zaptest.NewLogger(t), ft.Now)
+
+	assert.False(t, limiter.DecideN(4).Allowed)
+	assert.True(t, limiter.DecideN(2).Allowed)
+	d := limiter.DecideN(2)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 500*time.Millisecond, d.RetryAfter)
+}
+
+func TestGCRARateLimiter_Reconfigure(t *testing.T) {
+	ft := &fakeTime{current: time.Unix(0, 0)}
+	limiter := NewGCRARateLimiterWithTimeProvider(1, 2, zaptest.NewLogger(t), ft.Now)
+
+	require.True(t, limiter.Allow())
+	require.True(t, limiter.Allow())
+	require.False(t, limiter.Allow())
+
+	// Two requests of debt at 1 rps become two requests of debt at 4 rps
+	require.NoError(t, Reconfigure(limiter, LimitSpec{Algorithm: AlgorithmGCRA, RPS: 4, Burst: 4}))
+	d := limiter.Decide()
+	require.True(t, d.Allowed)
+	assert.Equal(t, 4, d.Limit)
+	assert.Equal(t, 1, d.Remaining)
+}
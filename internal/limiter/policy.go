@@ -0,0 +1,400 @@
+package limiter
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultExemptPaths are never rate limited by the default policy
+var DefaultExemptPaths = []string{"/metrics", "/health/live", "/health/ready", "/openapi.json"}
+
+// DefaultPolicyName is the name of the catch-all policy built from RPS_LIMIT and RPS_BURST
+const DefaultPolicyName = "default"
+
+// CostFunc returns how many tokens a request consumes
+type CostFunc func(r *http.Request) int
+
+// PolicyConfig is the declarative form of a Policy, as read from configuration
+type PolicyConfig struct {
+	Name        string   `json:"name"`
+	PathPrefix  string   `json:"path_prefix"`
+	Methods     []string `json:"methods,omitempty"`
+	Algorithm   string   `json:"algorithm,omitempty"`
+	RPS         int      `json:"rps,omitempty"`
+	Burst       int      `json:"burst,omitempty"`
+	Limit       int      `json:"limit,omitempty"`
+	Window      string   `json:"window,omitempty"`
+	Cost        string   `json:"cost,omitempty"`
+	ExemptPaths []string `json:"exempt_paths,omitempty"`
+	PerClient   bool     `json:"per_client,omitempty"`
+}
+
+// Policy applies its own limiter and cost function to requests matching a path prefix and method set
+type Policy struct {
+	Name        string
+	PathPrefix  string
+	Methods     []string
+	ExemptPaths []string
+	Limiter     RateLimiter
+	Cost        CostFunc
+	// PerClient gives every caller its own limiter with the policy's parameters
+	PerClient bool
+
+	// config and spec are what the policy was built from; spec changes on Reconfigure
+	config     PolicyConfig
+	spec       LimitSpec
+	newLimiter func(clientID string) (RateLimiter, error)
+	// idleTTL is how long a client's limiter is kept unused; by then it has refilled,
+	// so a new one behaves the same
+	idleTTL      time.Duration
+	maxClients   int
+	timeProvider TimeProvider
+	mu           sync.Mutex
+	clients      map[string]*list.Element
+	order        *list.List // most recently used first
+}
+
+// DefaultMaxClientLimiters caps the per-client limiters a policy keeps. Beyond it the
+// least recently used one is dropped, so callers rotating through addresses cannot
+// grow memory without bound.
+const DefaultMaxClientLimiters = 100_000
+
+// minClientIdleTTL is the shortest time a client's limiter is kept unused
+const minClientIdleTTL = 10 * time.Minute
+
+type clientLimiter struct {
+	clientID string
+	limiter  RateLimiter
+	lastUsed time.Time
+}
+
+// LimiterFor returns the limiter that applies to the client. Policies that are not
+// per-client, or requests without a client ID, share Limiter. Client limiters unused
+// for longer than it takes them to refill are dropped.
+func (p *Policy) LimiterFor(clientID string) RateLimiter {
+	if !p.PerClient || clientID == "" || p.newLimiter == nil {
+		return p.Limiter
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.clients == nil {
+		p.clients = make(map[string]*list.Element)
+		p.order = list.New()
+	}
+	now := p.now()
+	defer p.evict(now)
+
+	if elem, ok := p.clients[clientID]; ok {
+		entry := elem.Value.(*clientLimiter)
+		entry.lastUsed = now
+		p.order.MoveToFront(elem)
+		return entry.limiter
+	}
+	rl, err := p.newLimiter(clientID)
+	if err != nil {
+		return p.Limiter
+	}
+	p.clients[clientID] = p.order.PushFront(&clientLimiter{clientID: clientID, limiter: rl, lastUsed: now})
+	return rl
+}
+
+// clientSpec is the policy's spec for one client's limiter
+func (p *Policy) clientSpec(clientID string) LimitSpec {
+	spec := p.spec
+	spec.Name = p.spec.Name + ":" + clientID
+	return spec
+}
+
+// reconfigure applies spec to the shared limiter and to every client's limiter, keeping
+// the requests they have counted. A client limiter that cannot take it is dropped, and
+// the client gets a new one on its next request.
+func (p *Policy) reconfigure(spec LimitSpec) error {
+	if err := Reconfigure(p.Limiter, spec); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.spec = spec
+	if p.PerClient {
+		p.idleTTL = spec.refillTime()
+	}
+	for clientID, elem := range p.clients {
+		if err := Reconfigure(elem.Value.(*clientLimiter).limiter, p.clientSpec(clientID)); err != nil {
+			p.order.Remove(elem)
+			delete(p.clients, clientID)
+		}
+	}
+	return nil
+}
+
+// ClientLimiters returns the number of per-client limiters held
+func (p *Policy) ClientLimiters() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.clients)
+}
+
+// evict drops the least recently used client limiters beyond the cap, and those idle
+// for longer than idleTTL
+func (p *Policy) evict(now time.Time) {
+	maxClients := p.maxClients
+	if maxClients <= 0 {
+		maxClients = DefaultMaxClientLimiters
+	}
+	idleTTL := max(p.idleTTL, minClientIdleTTL)
+	for elem := p.order.Back(); elem != nil; elem = p.order.Back() {
+		entry := elem.Value.(*clientLimiter)
+		if p.order.Len() <= maxClients && now.Sub(entry.lastUsed) <= idleTTL {
+			return
+		}
+		p.order.Remove(elem)
+		delete(p.clients, entry.clientID)
+	}
+}
+
+func (p *Policy) now() time.Time {
+	if p.timeProvider != nil {
+		return p.timeProvider()
+	}
+	return time.Now()
+}
+
+// Matches reports whether the policy governs the request
+func (p *Policy) Matches(r *http.Request) bool {
+	if !strings.HasPrefix(r.URL.Path, p.PathPrefix) {
+		return false
+	}
+	if len(p.Methods) == 0 {
+		return true
+	}
+	for _, method := range p.Methods {
+		if strings.EqualFold(method, r.Method) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsExempt reports whether the request path is excluded from this policy
+func (p *Policy) IsExempt(r *http.Request) bool {
+	for _, path := range p.ExemptPaths {
+		if r.URL.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// CostOf returns the number of tokens the request consumes, at least one
+func (p *Policy) CostOf(r *http.Request) int {
+	if p.Cost == nil {
+		return 1
+	}
+	if cost := p.Cost(r); cost > 1 {
+		return cost
+	}
+	return 1
+}
+
+// PolicySet resolves the policy that applies to a request. Policies with a longer
+// path prefix take precedence; ties are broken by configuration order.
+type PolicySet struct {
+	policies []*Policy
+}
+
+func NewPolicySet(policies ...*Policy) *PolicySet {
+	sorted := make([]*Policy, len(policies))
+	copy(sorted, policies)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].PathPrefix) > len(sorted[j].PathPrefix)
+	})
+	return &PolicySet{policies: sorted}
+}
+
+// Match returns the policy for the request, or nil when no policy applies
+func (ps *PolicySet) Match(r *http.Request) *Policy {
+	for _, policy := range ps.policies {
+		if policy.Matches(r) {
+			return policy
+		}
+	}
+	return nil
+}
+
+// Policies returns the policies in match order
+func (ps *PolicySet) Policies() []*Policy {
+	return ps.policies
+}
+
+// PlanReconfigure checks that the rates of configs can be applied in place to the
+// policies built from them, and returns the function that applies them, including to
+// per-client limiters, keeping the requests already counted. Nothing changes until it
+// is called, so a caller can check all its changes before making any. Only rps,
+// burst, limit and window can change this way; the function is nil when a policy was
+// added, removed or changed otherwise, which needs a restart.
+func (ps *PolicySet) PlanReconfigure(configs []PolicyConfig) (func() error, error) {
+	built := make(map[string]*Policy)
+	for _, policy := range ps.policies {
+		if policy.config.Name != "" {
+			built[policy.config.Name] = policy
+		}
+	}
+	if len(configs) != len(built) {
+		return nil, nil
+	}
+	specs := make(map[*Policy]LimitSpec, len(configs))
+	for _, cfg := range configs {
+		policy, ok := built[cfg.Name]
+		if !ok || !reflect.DeepEqual(withoutRates(cfg), withoutRates(policy.config)) {
+			return nil, nil
+		}
+		spec, err := cfg.limitSpec()
+		if err != nil {
+			return nil, err
+		}
+		if err := CheckReconfigure(policy.Limiter, spec); err != nil {
+			return nil, fmt.Errorf("rate limit policy %q: %w", cfg.Name, err)
+		}
+		specs[policy] = spec
+	}
+	return func() error {
+		var errs []error
+		for policy, spec := range specs {
+			if err := policy.reconfigure(spec); err != nil {
+				errs = append(errs, fmt.Errorf("rate limit policy %q: %w", policy.Name, err))
+			}
+		}
+		return errors.Join(errs...)
+	}, nil
+}
+
+// withoutRates clears the settings of a policy config that PlanReconfigure can change
+func withoutRates(cfg PolicyConfig) PolicyConfig {
+	cfg.RPS, cfg.Burst, cfg.Limit, cfg.Window = 0, 0, 0, ""
+	return cfg
+}
+
+// NewDefaultPolicy creates the catch-all policy that exempts health, metrics and docs endpoints
+func NewDefaultPolicy(rl RateLimiter) *Policy {
+	return &Policy{
+		Name:        DefaultPolicyName,
+		PathPrefix:  "/",
+		ExemptPaths: DefaultExemptPaths,
+		Limiter:     rl,
+	}
+}
+
+// ParsePolicyConfigs parses the JSON array of policies from RATE_LIMIT_POLICIES
+func ParsePolicyConfigs(configJSON string) ([]PolicyConfig, error) {
+	if strings.TrimSpace(configJSON) == "" {
+		return nil, nil
+	}
+	var configs []PolicyConfig
+	if err := json.Unmarshal([]byte(configJSON), &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse rate limit policies JSON: %w", err)
+	}
+	return configs, nil
+}
+
+// limitSpec returns the limiter parameters of the policy
+func (cfg PolicyConfig) limitSpec() (LimitSpec, error) {
+	spec := LimitSpec{
+		Name:      cfg.Name,
+		Algorithm: Algorithm(cfg.Algorithm),
+		RPS:       cfg.RPS,
+		Burst:     cfg.Burst,
+		Limit:     cfg.Limit,
+	}
+	if cfg.Window != "" {
+		window, err := time.ParseDuration(cfg.Window)
+		if err != nil {
+			return LimitSpec{}, fmt.Errorf("rate limit policy %q: invalid window: %w", cfg.Name, err)
+		}
+		spec.Window = window
+	}
+	return spec, nil
+}
+
+// BuildPolicies creates a Policy with its own limiter from the factory for every config.
+// Cost names are resolved against costFuncs; an empty cost name means one token per request.
+func BuildPolicies(configs []PolicyConfig, costFuncs map[string]CostFunc, factory *Factory, logger *zap.Logger) ([]*Policy, error) {
+	policies := make([]*Policy, 0, len(configs))
+	seen := make(map[string]bool)
+
+	for i, cfg := range configs {
+		if cfg.Name == "" {
+			return nil, fmt.Errorf("rate limit policy %d: name is required", i)
+		}
+		if seen[cfg.Name] {
+			return nil, fmt.Errorf("rate limit policy %q: duplicate name", cfg.Name)
+		}
+		seen[cfg.Name] = true
+
+		if !strings.HasPrefix(cfg.PathPrefix, "/") {
+			return nil, fmt.Errorf("rate limit policy %q: path_prefix must start with /", cfg.Name)
+		}
+
+		spec, err := cfg.limitSpec()
+		if err != nil {
+			return nil, err
+		}
+		rl, err := factory.New(spec)
+		if err != nil {
+			return nil, fmt.Errorf("rate limit policy %q: %w", cfg.Name, err)
+		}
+
+		var cost CostFunc
+		if cfg.Cost != "" {
+			fn, ok := costFuncs[cfg.Cost]
+			if !ok {
+				return nil, fmt.Errorf("rate limit policy %q: unknown cost function %q", cfg.Name, cfg.Cost)
+			}
+			cost = fn
+		}
+
+		policy := &Policy{
+			Name:        cfg.Name,
+			PathPrefix:  cfg.PathPrefix,
+			Methods:     cfg.Methods,
+			ExemptPaths: cfg.ExemptPaths,
+			Limiter:     rl,
+			Cost:        cost,
+			PerClient:   cfg.PerClient,
+			config:      cfg,
+			spec:        spec,
+		}
+		if cfg.PerClient {
+			policy.idleTTL = spec.refillTime()
+			// The spec has been validated above, so per-client limiters cannot fail to
+			// build. LimiterFor holds the policy's lock, under which spec may change.
+			policy.newLimiter = func(clientID string) (RateLimiter, error) {
+				return factory.New(policy.clientSpec(clientID))
+			}
+		}
+		policies = append(policies, policy)
+
+		logger.Info("rate limit policy configured",
+			zap.String("policy", cfg.Name),
+			zap.String("path_prefix", cfg.PathPrefix),
+			zap.Strings("methods", cfg.Methods),
+			zap.String("algorithm", cfg.Algorithm),
+			zap.Int("rps", cfg.RPS),
+			zap.Int("burst", cfg.Burst),
+			zap.Int("limit", cfg.Limit),
+			zap.String("window", cfg.Window),
+			zap.String("cost", cfg.Cost),
+			zap.Bool("per_client", cfg.PerClient))
+	}
+
+	return policies, nil
+}
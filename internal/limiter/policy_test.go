@@ -0,0 +1,142 @@
+package limiter
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestParsePolicyConfigs(t *testing.T) {
+	configs, err := ParsePolicyConfigs(`[{"name": "batch", "path_prefix": "/v1/find-country/batch", "methods": ["POST"], "rps": 100, "burst": 1000, "cost": "batch_ips"}]`)
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+	assert.Equal(t, "batch_ips", configs[0].Cost)
+
+	configs, err = ParsePolicyConfigs("")
+	require.NoError(t, err)
+	assert.Empty(t, configs)
+
+	_, err = ParsePolicyConfigs("{not json")
+	assert.Error(t, err)
+}
+
+func TestBuildPolicies_Validation(t *testing.T) {
+	costFuncs := map[string]CostFunc{"batch_ips": nil}
+
+	tests := []struct {
+		name   string
+		config PolicyConfig
+		errMsg string
+	}{
+		{name: "missing name", config: PolicyConfig{PathPrefix: "/", RPS: 1, Burst: 1}, errMsg: "name is required"},
+		{name: "bad prefix", config: PolicyConfig{Name: "p", PathPrefix: "admin", RPS: 1, Burst: 1}, errMsg: "path_prefix"},
+		{name: "zero rps", config: PolicyConfig{Name: "p", PathPrefix: "/", Burst: 1}, errMsg: "positive"},
+		{name: "sliding window without limit", config: PolicyConfig{Name: "p", PathPrefix: "/", Algorithm: "sliding_window_log", Window: "1m"}, errMsg: "limit and window"},
+		{name: "bad window", config: PolicyConfig{Name: "p", PathPrefix: "/", Algorithm: "sliding_window_log", Limit: 10, Window: "soon"}, errMsg: "invalid window"},
+		{name: "unknown algorithm", config: PolicyConfig{Name: "p", PathPrefix: "/", Algorithm: "leaky", RPS: 1, Burst: 1}, errMsg: "unsupported rate limit algorithm"},
+		{name: "unknown cost", config: PolicyConfig{Name: "p", PathPrefix: "/", RPS: 1, Burst: 1, Cost: "bytes"}, errMsg: "unknown cost function"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := BuildPolicies([]PolicyConfig{tt.config}, costFuncs, newMemoryFactory(t), zap.NewNop())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+
+	_, err := BuildPolicies([]PolicyConfig{
+		{Name: "p", PathPrefix: "/a", RPS: 1, Burst: 1},
+		{Name: "p", PathPrefix: "/b", RPS: 1, Burst: 1},
+	}, costFuncs, newMemoryFactory(t), zap.NewNop())
+	assert.ErrorContains(t, err, "duplicate name")
+}
+
+func TestPolicySet_Match(t *testing.T) {
+	policies, err := BuildPolicies([]PolicyConfig{
+		{Name: "admin", PathPrefix: "/admin", RPS: 1, Burst: 1},
+		{Name: "batch", PathPrefix: "/v1/find-country/batch", Methods: []string{"POST"}, RPS: 1, Burst: 1},
+	}, nil, newMemoryFactory(t), zap.NewNop())
+	require.NoError(t, err)
+	set := NewPolicySet(append(policies, NewDefaultPolicy(nil))...)
+
+	tests := []struct {
+		method string
+		path   string
+		policy string
+		exempt bool
+	}{
+		{method: "POST", path: "/v1/find-country/batch", policy: "batch"},
+		{method: "GET", path: "/v1/find-country/batch", policy: DefaultPolicyName},
+		{method: "GET", path: "/v1/find-country", policy: DefaultPolicyName},
+		{method: "DELETE", path: "/admin/cache", policy: "admin"},
+		{method: "GET", path: "/metrics", policy: DefaultPolicyName, exempt: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			policy := set.Match(req)
+			require.NotNil(t, policy)
+			assert.Equal(t, tt.policy, policy.Name)
+			assert.Equal(t, tt.exempt, policy.IsExempt(req))
+		})
+	}
+}
+
+func newMemoryFactory(t *testing.T) *Factory {
+	t.Helper()
+	factory, err := NewFactory(FactoryConfig{}, zap.NewNop())
+	require.NoError(t, err)
+	return factory
+}
+
+func TestPolicy_LimiterFor(t *testing.T) {
+	policies, err := BuildPolicies([]PolicyConfig{
+		{Name: "shared", PathPrefix: "/a", RPS: 1, Burst: 1},
+		{Name: "per-client", PathPrefix: "/b", RPS: 1, Burst: 1, PerClient: true},
+	}, nil, newMemoryFactory(t), zap.NewNop())
+	require.NoError(t, err)
+	shared, perClient := policies[0], policies[1]
+
+	assert.Same(t, shared.Limiter, shared.LimiterFor("alice"))
+
+	require.True(t, perClient.LimiterFor("alice").Allow())
+	assert.False(t, perClient.LimiterFor("alice").Allow())
+	assert.True(t, perClient.LimiterFor("bob").Allow(), "each client has its own bucket")
+	assert.True(t, perClient.LimiterFor("").Allow(), "anonymous requests share the policy limiter")
+}
+
+func TestPolicy_LimiterForEviction(t *testing.T) {
+	policies, err := BuildPolicies([]PolicyConfig{
+		{Name: "per-client", PathPrefix: "/", RPS: 1, Burst: 1200, PerClient: true},
+	}, nil, newMemoryFactory(t), zap.NewNop())
+	require.NoError(t, err)
+	policy := policies[0]
+	now := time.Unix(0, 0)
+	policy.timeProvider = func() time.Time { return now }
+	policy.maxClients = 3
+
+	alice := policy.LimiterFor("alice")
+	for _, client := range []string{"bob", "carol"} {
+		policy.LimiterFor(client)
+	}
+	assert.Same(t, alice, policy.LimiterFor("alice"))
+	assert.Equal(t, 3, policy.ClientLimiters())
+
+	policy.LimiterFor("dave")
+	assert.Equal(t, 3, policy.ClientLimiters(), "the least recently used client is dropped beyond the cap")
+	assert.Same(t, alice, policy.LimiterFor("alice"))
+
+	// Idle limiters are kept until a full burst has refilled: 1200 tokens at 1 per second
+	now = now.Add(19 * time.Minute)
+	policy.LimiterFor("alice")
+	assert.Equal(t, 3, policy.ClientLimiters())
+	now = now.Add(2 * time.Minute)
+	assert.Same(t, alice, policy.LimiterFor("alice"))
+	assert.Equal(t, 1, policy.ClientLimiters(), "clients idle for longer are dropped")
+}
@@ -1,5 +1,69 @@
 package limiter
 
+import (
+	"fmt"
+	"time"
+)
+
 type RateLimiter interface {
 	Allow() bool
+	// Decide consumes a token like Allow and reports the resulting limiter state
+	Decide() Decision
+	// DecideN consumes n tokens at once; the request is rejected unless all n are available
+	DecideN(n int) Decision
+}
+
+// Decision is the outcome of a rate limit check together with the state needed
+// to populate RateLimit-* and Retry-After response headers
+type Decision struct {
+	Allowed bool
+	// Limit is the maximum number of requests that can be made in a burst
+	Limit int
+	// Remaining is the number of whole tokens left after this decision
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until enough tokens for the request are available; zero when they are available now
+	RetryAfter time.Duration
+}
+
+// Reconfigurable is implemented by limiters whose parameters can change at runtime
+// without losing the state of their buckets or windows
+type Reconfigurable interface {
+	// Algorithm is the algorithm the limiter implements
+	Algorithm() Algorithm
+	// Reconfigure applies the rate parameters of spec. The algorithm cannot change.
+	Reconfigure(spec LimitSpec) error
+}
+
+// CheckReconfigure reports whether Reconfigure would apply spec to rl, without changing it
+func CheckReconfigure(rl RateLimiter, spec LimitSpec) error {
+	r, ok := rl.(Reconfigurable)
+	if !ok {
+		return fmt.Errorf("rate limiter %T cannot be reconfigured", rl)
+	}
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+	return checkAlgorithm(spec, r.Algorithm())
+}
+
+// Reconfigure applies spec to rl in place, or fails when rl does not support it
+func Reconfigure(rl RateLimiter, spec LimitSpec) error {
+	if err := CheckReconfigure(rl, spec); err != nil {
+		return err
+	}
+	return rl.(Reconfigurable).Reconfigure(spec)
+}
+
+// checkAlgorithm rejects a spec for a different algorithm than the limiter implements
+func checkAlgorithm(spec LimitSpec, want Algorithm) error {
+	algorithm := spec.Algorithm
+	if algorithm == "" {
+		algorithm = AlgorithmTokenBucket
+	}
+	if algorithm != want {
+		return fmt.Errorf("cannot change rate limit algorithm from %s to %s at runtime", want, algorithm)
+	}
+	return nil
 }
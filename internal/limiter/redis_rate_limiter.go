@@ -0,0 +1,206 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// FailureMode decides what a distributed limiter does when its store is unreachable
+type FailureMode string
+
+const (
+	// FailureModeLocal falls back to an in-process token bucket with the same parameters
+	FailureModeLocal FailureMode = "local"
+	// FailureModeOpen admits every request
+	FailureModeOpen FailureMode = "open"
+	// FailureModeClosed rejects every request
+	FailureModeClosed FailureMode = "closed"
+)
+
+// IsValid checks if the failure mode is supported
+func (m FailureMode) IsValid() bool {
+	switch m {
+	case FailureModeLocal, FailureModeOpen, FailureModeClosed:
+		return true
+	default:
+		return false
+	}
+}
+
+// tokenBucketScript refills and consumes a token bucket stored in a hash in one atomic step.
+// It reads the store's clock, so replicas with skewed clocks still refill the shared
+// bucket at the same rate. Effects replication lets it write after calling TIME.
+// KEYS[1] bucket key; ARGV rate (tokens/s), burst, cost, ttl (ms).
+// Returns {allowed (0|1), remaining tokens as a string}.
+var tokenBucketScript = redis.NewScript(`
+redis.replicate_commands()
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+  tokens = burst
+  ts = now
+end
+
+if now > ts then
+  tokens = math.min(burst, tokens + (now - ts) / 1000 * rate)
+  ts = now
+end
+
+local allowed = 0
+if tokens >= cost then
+  tokens = tokens - cost
+  allowed = 1
+end
+
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(ts))
+redis.call('PEXPIRE', KEYS[1], ttl)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisRateLimiter is a token bucket shared by all replicas through a Redis-compatible store
+type RedisRateLimiter struct {
+	client redis.Scripter
+	key    string
+	// mu guards limit and burst, which Reconfigure may change
+	mu          sync.RWMutex
+	limit       float64
+	burst       float64
+	timeout     time.Duration
+	failureMode FailureMode
+	fallback    *BurstRateLimiter
+	logger      *zap.Logger
+	// storeDown is set while the store is unreachable, so only changes are logged
+	storeDown atomic.Bool
+}
+
+func NewRedisRateLimiter(client redis.Scripter, key string, rps int, burst int, failureMode FailureMode, logger *zap.Logger) *RedisRateLimiter {
+	return NewRedisRateLimiterWithTimeProvider(client, key, rps, burst, failureMode, logger.Named("redis_rate_limiter"), time.Now)
+}
+
+// NewRedisRateLimiterWithTimeProvider uses tp for the local fallback; the shared bucket
+// always follows the store's clock
+func NewRedisRateLimiterWithTimeProvider(client redis.Scripter, key string, rps int, burst int, failureMode FailureMode, logger *zap.Logger, tp TimeProvider) *RedisRateLimiter {
+	return &RedisRateLimiter{
+		client:      client,
+		key:         key,
+		limit:       float64(rps),
+		burst:       float64(burst),
+		timeout:     100 * time.Millisecond,
+		failureMode: failureMode,
+		fallback:    NewBurstRateLimiterWithTimeProvider(rps, burst, logger, tp),
+		logger:      logger,
+	}
+}
+
+func (l *RedisRateLimiter) Allow() bool {
+	return l.Decide().Allowed
+}
+
+func (l *RedisRateLimiter) Decide() Decision {
+	return l.DecideN(1)
+}
+
+func (l *RedisRateLimiter) DecideN(n int) Decision {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+
+	ttl := l.fullRefill() + time.Second
+	res, err := tokenBucketScript.Run(ctx, l.client, []string{l.key},
+		l.limit, l.burst, n, ttl.Milliseconds()).Slice()
+	if err == nil && len(res) != 2 {
+		err = fmt.Errorf("unexpected script result length %d", len(res))
+	}
+	if err != nil {
+		return l.onStoreError(n, err)
+	}
+
+	allowed, _ := res[0].(int64)
+	tokensStr, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return l.onStoreError(n, fmt.Errorf("invalid token count %q: %w", tokensStr, err))
+	}
+	if l.storeDown.CompareAndSwap(true, false) {
+		l.logger.Info("rate limit store available again", zap.String("key", l.key))
+	}
+
+	if allowed != 1 {
+		l.logger.Warn("Rate limit exceeded", zap.String("key", l.key), zap.Float64("tokens", tokens), zap.Int("cost", n))
+	}
+
+	return Decision{
+		Allowed:    allowed == 1,
+		Limit:      int(l.burst),
+		Remaining:  int(math.Floor(tokens)),
+		Reset:      l.timeUntil(tokens, l.burst),
+		RetryAfter: l.timeUntil(tokens, float64(n)),
+	}
+}
+
+// onStoreError applies the failure mode when the store could not be reached. The
+// outage is logged once when it starts rather than on every request.
+func (l *RedisRateLimiter) onStoreError(n int, err error) Decision {
+	if l.storeDown.CompareAndSwap(false, true) {
+		l.logger.Error("rate limit store unavailable", zap.String("key", l.key), zap.String("failure_mode", string(l.failureMode)), zap.Error(err))
+	} else {
+		l.logger.Debug("rate limit store still unavailable", zap.String("key", l.key), zap.Error(err))
+	}
+
+	switch l.failureMode {
+	case FailureModeOpen:
+		return Decision{Allowed: true, Limit: int(l.burst), Remaining: int(l.burst)}
+	case FailureModeClosed:
+		return Decision{Allowed: false, Limit: int(l.burst), Reset: l.fullRefill(), RetryAfter: time.Second}
+	default:
+		return l.fallback.DecideN(n)
+	}
+}
+
+func (l *RedisRateLimiter) fullRefill() time.Duration {
+	return l.timeUntil(0, l.burst)
+}
+
+func (l *RedisRateLimiter) timeUntil(have, want float64) time.Duration {
+	missing := want - have
+	if missing <= 0 || l.limit <= 0 {
+		return 0
+	}
+	return time.Duration(missing / l.limit * float64(time.Second))
+}
+
+func (l *RedisRateLimiter) Algorithm() Algorithm {
+	return AlgorithmTokenBucket
+}
+
+// Reconfigure changes the rate and burst. The shared bucket keeps its tokens, capped
+// at the new burst on the next request; the local fallback is reconfigured too.
+func (l *RedisRateLimiter) Reconfigure(spec LimitSpec) error {
+	if err := checkAlgorithm(spec, l.Algorithm()); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = float64(spec.RPS)
+	l.burst = float64(spec.Burst)
+	return l.fallback.Reconfigure(spec)
+}
@@ -0,0 +1,147 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newRedisLimiter(t *testing.T, mode FailureMode, ft *fakeTime) (*RedisRateLimiter, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	mr.SetTime(ft.Now())
+	return NewRedisRateLimiterWithTimeProvider(client, "test:bucket", 2, 3, mode, zaptest.NewLogger(t), ft.Now), mr
+}
+
+func TestRedisRateLimiter_BurstAndRefill(t *testing.T) {
+	ft := &fakeTime{current: time.Unix(0, 0)}
+	limiter, mr := newRedisLimiter(t, FailureModeLocal, ft)
+
+	require.True(t, limiter.Allow())
+	require.True(t, limiter.Allow())
+	d := limiter.Decide()
+	require.True(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining)
+	assert.Equal(t, 3, d.Limit)
+	assert.Equal(t, 1500*time.Millisecond, d.Reset)
+
+	d = limiter.Decide()
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 500*time.Millisecond, d.RetryAfter)
+
+	// The bucket refills by the store's clock
+	mr.SetTime(time.Unix(1, 0))
+	assert.True(t, limiter.Allow())
+	assert.True(t, limiter.Allow())
+	assert.False(t, limiter.Allow())
+}
+
+func TestRedisRateLimiter_IgnoresReplicaClock(t *testing.T) {
+	ft := &fakeTime{current: time.Unix(0, 0)}
+	first, mr := newRedisLimiter(t, FailureModeLocal, ft)
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	skewed := &fakeTime{current: time.Unix(0, 0).Add(time.Hour)}
+	second := NewRedisRateLimiterWithTimeProvider(client, "test:bucket", 2, 3, FailureModeLocal, zaptest.NewLogger(t), skewed.Now)
+
+	require.True(t, first.DecideN(3).Allowed)
+	assert.False(t, second.Allow(), "a replica whose clock runs ahead does not refill the bucket")
+}
+
+func TestRedisRateLimiter_SharedAcrossInstances(t *testing.T) {
+	ft := &fakeTime{current: time.Unix(0, 0)}
+	first, mr := newRedisLimiter(t, FailureModeLocal, ft)
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	second := NewRedisRateLimiterWithTimeProvider(client, "test:bucket", 2, 3, FailureModeLocal, zaptest.NewLogger(t), ft.Now)
+
+	require.True(t, first.Allow())
+	require.True(t, second.Allow())
+	require.True(t, first.Allow())
+	assert.False(t, second.Allow(), "replicas should share one bucket")
+}
+
+func TestRedisRateLimiter_DecideN(t *testing.T) {
+	ft := &fakeTime{current: time.Unix(0, 0)}
+	limiter, _ := newRedisLimiter(t, FailureModeLocal, ft)
+
+	assert.False(t, limiter.DecideN(4).Allowed, "cost above burst is never admitted")
+	d := limiter.DecideN(3)
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining)
+}
+
+func TestRedisRateLimiter_FailureModes(t *testing.T) {
+	tests := []struct {
+		mode    FailureMode
+		allowed []bool
+	}{
+		{mode: FailureModeLocal, allowed: []bool{true, true, true, false}},
+		{mode: FailureModeOpen, allowed: []bool{true, true, true, true}},
+		{mode: FailureModeClosed, allowed: []bool{false, false, false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			ft := &fakeTime{current: time.Unix(0, 0)}
+			limiter, mr := newRedisLimiter(t, tt.mode, ft)
+			mr.Close()
+
+			for i, want := range tt.allowed {
+				assert.Equal(t, want, limiter.Allow(), "request %d", i)
+			}
+		})
+	}
+}
+
+func TestRedisRateLimiter_LogsOutageOnce(t *testing.T) {
+	ft := &fakeTime{current: time.Unix(0, 0)}
+	mr := miniredis.RunT(t)
+	mr.SetTime(ft.Now())
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	core, logs := observer.New(zap.InfoLevel)
+	limiter := NewRedisRateLimiterWithTimeProvider(client, "test:bucket", 100, 100, FailureModeOpen, zap.New(core), ft.Now)
+
+	require.True(t, limiter.Allow())
+	mr.SetError("LOADING Redis is loading the dataset in memory")
+	for range 5 {
+		require.True(t, limiter.Allow())
+	}
+	mr.SetError("")
+	require.True(t, limiter.Allow())
+
+	var messages []string
+	for _, entry := range logs.All() {
+		messages = append(messages, entry.Message)
+	}
+	assert.Equal(t, []string{"rate limit store unavailable", "rate limit store available again"}, messages)
+}
+
+func TestRedisRateLimiter_Reconfigure(t *testing.T) {
+	ft := &fakeTime{current: time.Unix(0, 0)}
+	limiter, mr := newRedisLimiter(t, FailureModeLocal, ft)
+
+	require.True(t, limiter.Allow())
+	require.NoError(t, Reconfigure(limiter, LimitSpec{RPS: 10, Burst: 10}))
+
+	d := limiter.Decide()
+	require.True(t, d.Allowed)
+	assert.Equal(t, 10, d.Limit)
+	assert.Equal(t, 1, d.Remaining, "the shared bucket keeps its tokens")
+
+	// The local fallback uses the new parameters too
+	mr.Close()
+	assert.Equal(t, 10, limiter.Decide().Limit)
+}
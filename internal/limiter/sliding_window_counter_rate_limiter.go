@@ -0,0 +1,152 @@
+package limiter
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SlidingWindowCounterRateLimiter approximates a sliding window by weighting the previous
+// fixed window's count by how much of it still overlaps the sliding window. It uses
+// constant memory and avoids the double burst a fixed window allows at its edges.
+type SlidingWindowCounterRateLimiter struct {
+	mu           sync.Mutex
+	windowStart  time.Time
+	current      int
+	previous     int
+	limit        int
+	window       time.Duration
+	logger       *zap.Logger
+	timeProvider TimeProvider
+}
+
+func NewSlidingWindowCounterRateLimiter(limit int, window time.Duration, logger *zap.Logger) *SlidingWindowCounterRateLimiter {
+	return NewSlidingWindowCounterRateLimiterWithTimeProvider(limit, window, logger.Named("rate_limiter"), time.Now)
+}
+
+func NewSlidingWindowCounterRateLimiterWithTimeProvider(limit int, window time.Duration, logger *zap.Logger, tp TimeProvider) *SlidingWindowCounterRateLimiter {
+	return &SlidingWindowCounterRateLimiter{
+		windowStart:  tp(),
+		limit:        limit,
+		window:       window,
+		logger:       logger,
+		timeProvider: tp,
+	}
+}
+
+func (l *SlidingWindowCounterRateLimiter) Allow() bool {
+	return l.Decide().Allowed
+}
+
+func (l *SlidingWindowCounterRateLimiter) Decide() Decision {
+	return l.DecideN(1)
+}
+
+func (l *SlidingWindowCounterRateLimiter) DecideN(n int) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.timeProvider()
+	l.advance(now)
+
+	elapsed := now.Sub(l.windowStart)
+	weight := 1 - float64(elapsed)/float64(l.window)
+	estimate := float64(l.previous)*weight + float64(l.current)
+
+	allowed := estimate+float64(n) <= float64(l.limit)
+	if allowed {
+		l.current += n
+		estimate += float64(n)
+	} else {
+		l.logger.Warn("Rate limit exceeded", zap.Float64("estimate", estimate), zap.Int("limit", l.limit), zap.Int("cost", n))
+	}
+
+	remaining := int(math.Floor(float64(l.limit) - estimate))
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	windowEnd := l.windowStart.Add(l.window).Sub(now)
+	decision := Decision{
+		Allowed:   allowed,
+		Limit:     l.limit,
+		Remaining: remaining,
+	}
+	switch {
+	case l.current > 0:
+		// The current count decays over the whole of the next window
+		decision.Reset = windowEnd + l.window
+	case l.previous > 0:
+		decision.Reset = windowEnd
+	}
+	decision.RetryAfter = l.retryAfter(n, elapsed, windowEnd)
+
+	return decision
+}
+
+// advance rolls the fixed windows forward so that windowStart <= now < windowStart+window
+func (l *SlidingWindowCounterRateLimiter) advance(now time.Time) {
+	elapsed := now.Sub(l.windowStart)
+	if elapsed < l.window {
+		return
+	}
+	windows := elapsed / l.window
+	if windows == 1 {
+		l.previous = l.current
+	} else {
+		l.previous = 0
+	}
+	l.current = 0
+	l.windowStart = l.windowStart.Add(windows * l.window)
+}
+
+// retryAfter estimates when n more requests fit, assuming no other traffic
+func (l *SlidingWindowCounterRateLimiter) retryAfter(n int, elapsed, windowEnd time.Duration) time.Duration {
+	room := float64(l.limit - l.current - n)
+	estimate := float64(l.previous)*(1-float64(elapsed)/float64(l.window)) + float64(l.current)
+	if estimate+float64(n) <= float64(l.limit) {
+		return 0
+	}
+	if room >= 0 && l.previous > 0 {
+		// Wait until the previous window's weight has decayed enough
+		fraction := 1 - room/float64(l.previous)
+		return time.Duration(fraction*float64(l.window)) - elapsed
+	}
+	// The current window's own count is too high: wait for it to become the previous
+	// window and decay by the amount needed
+	if l.current == 0 {
+		return windowEnd
+	}
+	fraction := 1 - float64(l.limit-n)/float64(l.current)
+	if fraction < 0 {
+		fraction = 0
+	}
+	return windowEnd + time.Duration(fraction*float64(l.window))
+}
+
+func (l *SlidingWindowCounterRateLimiter) Algorithm() Algorithm {
+	return AlgorithmSlidingWindowCounter
+}
+
+// Reconfigure changes the limit and window. A new window length restarts the fixed
+// windows now, carrying the current count over as the previous window so it still decays.
+func (l *SlidingWindowCounterRateLimiter) Reconfigure(spec LimitSpec) error {
+	if err := checkAlgorithm(spec, l.Algorithm()); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if spec.Window != l.window {
+		now := l.timeProvider()
+		l.advance(now)
+		l.windowStart = now
+		l.previous = l.current
+		l.current = 0
+		l.window = spec.Window
+	}
+	l.limit = spec.Limit
+	return nil
+}
@@ -0,0 +1,81 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestSlidingWindowCounterRateLimiter_Limit(t *testing.T) {
+	ft := &fakeTime{current: time.Unix(0, 0)}
+	limiter := NewSlidingWindowCounterRateLimiterWithTimeProvider(4, time.Minute, zaptest.NewLogger(t), ft.Now)
+
+	for i := 0; i < 4; i++ {
+		require.True(t, limiter.Allow())
+	}
+	d := limiter.Decide()
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining)
+	assert.Equal(t, 4, d.Limit)
+	assert.Equal(t, 2*time.Minute, d.Reset)
+}
+
+func TestSlidingWindowCounterRateLimiter_WeightsPreviousWindow(t *testing.T) {
+	ft := &fakeTime{current: time.Unix(0, 0)}
+	limiter := NewSlidingWindowCounterRateLimiterWithTimeProvider(4, time.Minute, zaptest.NewLogger(t), ft.Now)
+
+	for i := 0; i < 4; i++ {
+		require.True(t, limiter.Allow())
+	}
+
+	// A quarter into the next window, the previous window still weighs 3 requests
+	ft.Advance(75 * time.Second)
+	d := limiter.Decide()
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining)
+
+	d = limiter.Decide()
+	assert.False(t, d.Allowed, "a fixed window would have admitted this request")
+	assert.Equal(t, 15*time.Second, d.RetryAfter)
+
+	ft.Advance(15 * time.Second)
+	assert.True(t, limiter.Allow())
+}
+
+func TestSlidingWindowCounterRateLimiter_IdleResets(t *testing.T) {
+	ft := &fakeTime{current: time.Unix(0, 0)}
+	limiter := NewSlidingWindowCounterRateLimiterWithTimeProvider(2, time.Minute, zaptest.NewLogger(t), ft.Now)
+
+	require.True(t, limiter.Allow())
+	require.True(t, limiter.Allow())
+	assert.False(t, limiter.Allow())
+
+	ft.Advance(5 * time.Minute)
+	assert.True(t, limiter.Allow())
+	assert.True(t, limiter.Allow())
+	assert.False(t, limiter.Allow())
+}
+
+func TestSlidingWindowCounterRateLimiter_Reconfigure(t *testing.T) {
+	ft := &fakeTime{current: time.Unix(0, 0)}
+	limiter := NewSlidingWindowCounterRateLimiterWithTimeProvider(2, time.Minute, zaptest.NewLogger(t), ft.Now)
+
+	require.True(t, limiter.Allow())
+	require.True(t, limiter.Allow())
+	require.False(t, limiter.Allow())
+
+	spec := LimitSpec{Algorithm: AlgorithmSlidingWindowCounter, Limit: 3, Window: time.Minute}
+	require.NoError(t, Reconfigure(limiter, spec))
+	assert.True(t, limiter.Allow(), "the higher limit applies to the current window")
+	assert.False(t, limiter.Allow())
+
+	// A new window length carries the count over as the previous window
+	spec.Window = 10 * time.Second
+	require.NoError(t, Reconfigure(limiter, spec))
+	assert.False(t, limiter.Allow())
+	ft.Advance(10 * time.Second)
+	assert.True(t, limiter.Allow())
+}
@@ -0,0 +1,104 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SlidingWindowLogRateLimiter admits at most limit requests in any window-long interval
+// by remembering the time of every admitted request. It is exact, at the cost of
+// memory proportional to limit.
+type SlidingWindowLogRateLimiter struct {
+	mu           sync.Mutex
+	log          []time.Time // admission times, oldest first
+	limit        int
+	window       time.Duration
+	logger       *zap.Logger
+	timeProvider TimeProvider
+}
+
+func NewSlidingWindowLogRateLimiter(limit int, window time.Duration, logger *zap.Logger) *SlidingWindowLogRateLimiter {
+	return NewSlidingWindowLogRateLimiterWithTimeProvider(limit, window, logger.Named("rate_limiter"), time.Now)
+}
+
+func NewSlidingWindowLogRateLimiterWithTimeProvider(limit int, window time.Duration, logger *zap.Logger, tp TimeProvider) *SlidingWindowLogRateLimiter {
+	return &SlidingWindowLogRateLimiter{
+		log:          make([]time.Time, 0, limit),
+		limit:        limit,
+		window:       window,
+		logger:       logger,
+		timeProvider: tp,
+	}
+}
+
+func (l *SlidingWindowLogRateLimiter) Allow() bool {
+	return l.Decide().Allowed
+}
+
+func (l *SlidingWindowLogRateLimiter) Decide() Decision {
+	return l.DecideN(1)
+}
+
+func (l *SlidingWindowLogRateLimiter) DecideN(n int) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.timeProvider()
+
+	// Drop admissions that have left the window
+	cutoff := now.Add(-l.window)
+	expired := 0
+	for expired < len(l.log) && !l.log[expired].After(cutoff) {
+		expired++
+	}
+	l.log = append(l.log[:0], l.log[expired:]...)
+
+	allowed := len(l.log)+n <= l.limit
+	if allowed {
+		for i := 0; i < n; i++ {
+			l.log = append(l.log, now)
+		}
+	} else {
+		l.logger.Warn("Rate limit exceeded", zap.Int("in_window", len(l.log)), zap.Int("limit", l.limit), zap.Int("cost", n))
+	}
+
+	// A limit lowered by Reconfigure can be below the admissions still logged
+	decision := Decision{
+		Allowed:   allowed,
+		Limit:     l.limit,
+		Remaining: max(0, l.limit-len(l.log)),
+	}
+	if len(l.log) > 0 {
+		decision.Reset = l.log[len(l.log)-1].Add(l.window).Sub(now)
+	}
+	// Enough room for n more requests once the oldest entries expire
+	if excess := len(l.log) + n - l.limit; excess > 0 {
+		if excess <= len(l.log) {
+			decision.RetryAfter = l.log[excess-1].Add(l.window).Sub(now)
+		} else {
+			decision.RetryAfter = decision.Reset
+		}
+	}
+
+	return decision
+}
+
+func (l *SlidingWindowLogRateLimiter) Algorithm() Algorithm {
+	return AlgorithmSlidingWindowLog
+}
+
+// Reconfigure changes the limit and window. Admissions already logged count against
+// the new limit for as long as they fall inside the new window.
+func (l *SlidingWindowLogRateLimiter) Reconfigure(spec LimitSpec) error {
+	if err := checkAlgorithm(spec, l.Algorithm()); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = spec.Limit
+	l.window = spec.Window
+	return nil
+}
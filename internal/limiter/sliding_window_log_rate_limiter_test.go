@@ -0,0 +1,78 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestSlidingWindowLogRateLimiter_Limit(t *testing.T) {
+	ft := &fakeTime{current: time.Unix(0, 0)}
+	limiter := NewSlidingWindowLogRateLimiterWithTimeProvider(3, time.Minute, zaptest.NewLogger(t), ft.Now)
+
+	require.True(t, limiter.Allow())
+	ft.Advance(20 * time.Second)
+	require.True(t, limiter.Allow())
+	require.True(t, limiter.Allow())
+
+	d := limiter.Decide()
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining)
+	assert.Equal(t, 40*time.Second, d.RetryAfter, "the first request leaves the window after 40s")
+	assert.Equal(t, time.Minute, d.Reset)
+}
+
+func TestSlidingWindowLogRateLimiter_NoEdgeBurst(t *testing.T) {
+	ft := &fakeTime{current: time.Unix(0, 0)}
+	limiter := NewSlidingWindowLogRateLimiterWithTimeProvider(3, time.Minute, zaptest.NewLogger(t), ft.Now)
+
+	// Spend the quota at the end of one minute...
+	ft.Advance(59 * time.Second)
+	for i := 0; i < 3; i++ {
+		require.True(t, limiter.Allow())
+	}
+
+	// ...and the start of the next minute still sees it
+	ft.Advance(2 * time.Second)
+	assert.False(t, limiter.Allow())
+
+	ft.Advance(58 * time.Second)
+	assert.True(t, limiter.Allow())
+}
+
+func TestSlidingWindowLogRateLimiter_DecideN(t *testing.T) {
+	ft := &fakeTime{current: time.Unix(0, 0)}
+	limiter := NewSlidingWindowLogRateLimiterWithTimeProvider(3, time.Minute, zaptest.NewLogger(t), ft.Now)
+
+	assert.False(t, limiter.DecideN(4).Allowed)
+	d := limiter.DecideN(2)
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 1, d.Remaining)
+	assert.False(t, limiter.DecideN(2).Allowed)
+	assert.True(t, limiter.DecideN(1).Allowed)
+}
+
+func TestSlidingWindowLogRateLimiter_Reconfigure(t *testing.T) {
+	ft := &fakeTime{current: time.Unix(0, 0)}
+	limiter := NewSlidingWindowLogRateLimiterWithTimeProvider(5, time.Minute, zaptest.NewLogger(t), ft.Now)
+
+	for i := 0; i < 4; i++ {
+		require.True(t, limiter.Allow())
+	}
+
+	// The logged admissions exceed the lower limit until they leave the window
+	require.NoError(t, Reconfigure(limiter, LimitSpec{Algorithm: AlgorithmSlidingWindowLog, Limit: 2, Window: time.Minute}))
+	d := limiter.Decide()
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 2, d.Limit)
+	assert.Equal(t, 0, d.Remaining)
+	assert.Equal(t, time.Minute, d.RetryAfter)
+
+	ft.Advance(time.Minute)
+	d = limiter.Decide()
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 1, d.Remaining)
+}
@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type contextKey struct{}
+
+// requestLogger is a request-scoped logger and the fields it adds to the base logger
+type requestLogger struct {
+	logger *zap.Logger
+	fields []zap.Field
+}
+
+// NewContext returns a copy of ctx carrying base enriched with the request-scoped fields
+func NewContext(ctx context.Context, base *zap.Logger, fields ...zap.Field) context.Context {
+	return context.WithValue(ctx, contextKey{}, &requestLogger{logger: base.With(fields...), fields: fields})
+}
+
+// FromContext returns the request-scoped logger stored in ctx, or a no-op logger when there is none
+func FromContext(ctx context.Context) *zap.Logger {
+	if rl, ok := ctx.Value(contextKey{}).(*requestLogger); ok {
+		return rl.logger
+	}
+	return zap.NewNop()
+}
+
+// WithContext adds the request-scoped fields in ctx, such as the request ID, to a
+// component's own logger. It returns l unchanged outside a request.
+func WithContext(ctx context.Context, l *zap.Logger) *zap.Logger {
+	if rl, ok := ctx.Value(contextKey{}).(*requestLogger); ok && len(rl.fields) > 0 {
+		return l.With(rl.fields...)
+	}
+	return l
+}
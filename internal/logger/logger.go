@@ -6,6 +6,13 @@ import (
 )
 
 func NewLogger(environment, logLevel string) (*zap.Logger, error) {
+	logger, _, err := NewAtomicLogger(environment, logLevel)
+	return logger, err
+}
+
+// NewAtomicLogger creates the logger like NewLogger and also returns its level, which
+// can be changed while the logger is in use
+func NewAtomicLogger(environment, logLevel string) (*zap.Logger, zap.AtomicLevel, error) {
 	var config zap.Config
 
 	switch environment {
@@ -28,8 +35,8 @@ func NewLogger(environment, logLevel string) (*zap.Logger, error) {
 	// Build logger
 	logger, err := config.Build()
 	if err != nil {
-		return nil, err
+		return nil, config.Level, err
 	}
 
-	return logger, nil
+	return logger, config.Level, nil
 }
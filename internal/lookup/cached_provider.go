@@ -0,0 +1,132 @@
+package lookup
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CachedProvider keeps recent successful lookups in memory. Once size entries are
+// cached the least recently used one is evicted, and entries expire after ttl.
+// Failed lookups are not cached, so new data is found as soon as it is loaded.
+type CachedProvider struct {
+	provider     DbProvider
+	size         int
+	ttl          time.Duration
+	mu           sync.Mutex
+	entries      map[string]*list.Element
+	order        *list.List // most recently used first
+	timeProvider func() time.Time
+}
+
+type cacheEntry struct {
+	ip      string
+	city    string
+	country string
+	expires time.Time
+}
+
+func NewCachedProvider(provider DbProvider, size int, ttl time.Duration) *CachedProvider {
+	return NewCachedProviderWithTimeProvider(provider, size, ttl, time.Now)
+}
+
+func NewCachedProviderWithTimeProvider(provider DbProvider, size int, ttl time.Duration, tp func() time.Time) *CachedProvider {
+	return &CachedProvider{
+		provider:     provider,
+		size:         size,
+		ttl:          ttl,
+		entries:      make(map[string]*list.Element, size),
+		order:        list.New(),
+		timeProvider: tp,
+	}
+}
+
+func (p *CachedProvider) Lookup(ctx context.Context, ip string) (string, string, error) {
+	span := trace.SpanFromContext(ctx)
+	if city, country, ok := p.get(ip); ok {
+		span.SetAttributes(attribute.Bool("torq.lookup.cache_hit", true))
+		return city, country, nil
+	}
+	span.SetAttributes(attribute.Bool("torq.lookup.cache_hit", false))
+
+	city, country, err := p.provider.Lookup(ctx, ip)
+	if err != nil {
+		return "", "", err
+	}
+	p.put(ip, city, country)
+	return city, country, nil
+}
+
+func (p *CachedProvider) get(ip string) (string, string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	elem, ok := p.entries[ip]
+	if !ok {
+		return "", "", false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !p.timeProvider().Before(entry.expires) {
+		p.order.Remove(elem)
+		delete(p.entries, ip)
+		return "", "", false
+	}
+	p.order.MoveToFront(elem)
+	return entry.city, entry.country, true
+}
+
+func (p *CachedProvider) put(ip, city, country string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	expires := p.timeProvider().Add(p.ttl)
+	if elem, ok := p.entries[ip]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.city, entry.country, entry.expires = city, country, expires
+		p.order.MoveToFront(elem)
+		return
+	}
+	p.entries[ip] = p.order.PushFront(&cacheEntry{ip: ip, city: city, country: country, expires: expires})
+	if p.order.Len() > p.size {
+		oldest := p.order.Back()
+		p.order.Remove(oldest)
+		delete(p.entries, oldest.Value.(*cacheEntry).ip)
+	}
+}
+
+// Len returns the number of cached lookups, including expired ones not yet evicted
+func (p *CachedProvider) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.order.Len()
+}
+
+// Purge empties the cache and returns the number of entries removed
+func (p *CachedProvider) Purge() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := p.order.Len()
+	p.entries = make(map[string]*list.Element, p.size)
+	p.order.Init()
+	return n
+}
+
+// Dataset describes the wrapped provider's data
+func (p *CachedProvider) Dataset(ctx context.Context) (Dataset, error) {
+	return DescribeDataset(ctx, p.provider)
+}
+
+// Rows lists the wrapped provider's rows
+func (p *CachedProvider) Rows(ctx context.Context, fn func(ip, city, country string) error) error {
+	return ListRows(ctx, p.provider, fn)
+}
+
+// Close releases the wrapped provider's resources
+func (p *CachedProvider) Close() error {
+	return CloseProvider(p.provider)
+}
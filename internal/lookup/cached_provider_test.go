@@ -0,0 +1,69 @@
+package lookup
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingProvider resolves every IP to France and counts the lookups that reach it
+type countingProvider struct {
+	lookups int
+}
+
+func (p *countingProvider) Lookup(ctx context.Context, ip string) (string, string, error) {
+	p.lookups++
+	if ip == "0.0.0.0" {
+		return "", "", fmt.Errorf("IP not found")
+	}
+	return "Paris", "France", nil
+}
+
+func TestCachedProvider(t *testing.T) {
+	now := time.Unix(0, 0)
+	backend := &countingProvider{}
+	cache := NewCachedProviderWithTimeProvider(backend, 2, time.Minute, func() time.Time { return now })
+	ctx := context.Background()
+
+	lookup := func(ip string) {
+		t.Helper()
+		_, country, err := cache.Lookup(ctx, ip)
+		require.NoError(t, err)
+		assert.Equal(t, "France", country)
+	}
+
+	lookup("1.1.1.1")
+	lookup("1.1.1.1")
+	assert.Equal(t, 1, backend.lookups, "the second lookup is served from the cache")
+
+	// Failures are not cached
+	_, _, err := cache.Lookup(ctx, "0.0.0.0")
+	require.Error(t, err)
+	_, _, err = cache.Lookup(ctx, "0.0.0.0")
+	require.Error(t, err)
+	assert.Equal(t, 3, backend.lookups)
+
+	// The least recently used entry is evicted
+	lookup("2.2.2.2")
+	lookup("1.1.1.1")
+	lookup("3.3.3.3")
+	assert.Equal(t, 2, cache.Len())
+	lookup("1.1.1.1")
+	assert.Equal(t, 5, backend.lookups)
+	lookup("2.2.2.2")
+	assert.Equal(t, 6, backend.lookups, "2.2.2.2 was evicted")
+
+	// Entries expire after the TTL
+	now = now.Add(time.Minute)
+	lookup("1.1.1.1")
+	assert.Equal(t, 7, backend.lookups)
+
+	assert.Equal(t, 2, cache.Purge())
+	assert.Equal(t, 0, cache.Len())
+	lookup("1.1.1.1")
+	assert.Equal(t, 8, backend.lookups)
+}
@@ -1,21 +1,29 @@
 package lookup
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/csv"
+	"encoding/hex"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
 	"sync"
 	"time"
 
+	"github.com/shaibs3/Torq/internal/logger"
 	"go.opentelemetry.io/otel/metric"
 	"go.uber.org/zap"
 )
 
 type CSVProvider struct {
-	data   map[string]record
-	mu     sync.RWMutex
-	logger *zap.Logger
+	data     map[string]record
+	mu       sync.RWMutex
+	version  string
+	loadedAt time.Time
+	logger   *zap.Logger
 }
 
 type record struct {
@@ -36,14 +44,16 @@ func NewCSVProvider(config DbProviderConfig, logger *zap.Logger, meter metric.Me
 	}
 	csvLogger.Info("initializing CSV provider", zap.String("path", path))
 
-	file, err := os.Open(path) // #nosec G304
+	content, err := os.ReadFile(path) // #nosec G304
 	if err != nil {
 		csvLogger.Error("failed to open CSV file", zap.Error(err), zap.String("path", path))
 		return nil, fmt.Errorf("failed to open CSV file: %w", err)
 	}
-	defer file.Close() //nolint:errcheck
+	// The content hash identifies the dataset across reloads and replicas
+	sum := sha256.Sum256(content)
+	version := hex.EncodeToString(sum[:6])
 
-	reader := csv.NewReader(file)
+	reader := csv.NewReader(bytes.NewReader(content))
 	rows, err := reader.ReadAll()
 	if err != nil {
 		csvLogger.Error("failed to read CSV file", zap.Error(err), zap.String("path", path))
@@ -72,35 +82,74 @@ func NewCSVProvider(config DbProviderConfig, logger *zap.Logger, meter metric.Me
 		zap.Int("total_rows", len(rows)),
 		zap.Int("valid_rows", validRows),
 		zap.Int("skipped_rows", skippedRows),
-		zap.Int("unique_ips", len(data)))
+		zap.Int("unique_ips", len(data)),
+		zap.String("version", version))
+	if skippedRows > 0 {
+		csvLogger.Warn("skipped rows without ip, city and country; run torq validate-data for details",
+			zap.String("path", path),
+			zap.Int("skipped_rows", skippedRows))
+	}
 
 	return &CSVProvider{
-		data:   data,
-		logger: csvLogger,
+		data:     data,
+		version:  version,
+		loadedAt: time.Now(),
+		logger:   csvLogger,
 	}, nil
 }
 
 func (p *CSVProvider) Lookup(ctx context.Context, ip string) (string, string, error) {
 	start := time.Now()
+	log := logger.WithContext(ctx, p.logger)
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	p.logger.Debug("looking up IP", zap.String("ip", ip))
+	log.Debug("looking up IP", zap.String("ip", ip))
 
 	rec, ok := p.data[ip]
 	if !ok {
-		IncLookupErrors(context.Background())
+		IncLookupErrors(ctx)
 		RecordLookupDuration(ctx, time.Since(start).Seconds())
-		p.logger.Debug("IP not found in database", zap.String("ip", ip))
+		log.Debug("IP not found in database", zap.String("ip", ip))
 		return "", "", fmt.Errorf("IP not found")
 	}
 
 	RecordLookupDuration(ctx, time.Since(start).Seconds())
 
-	p.logger.Debug("IP lookup successful",
+	log.Debug("IP lookup successful",
 		zap.String("ip", ip),
 		zap.String("city", rec.city),
 		zap.String("country", rec.country))
 
 	return rec.city, rec.country, nil
 }
+
+// Dataset reports the number of IP addresses loaded and a hash of the file's content
+func (p *CSVProvider) Dataset(ctx context.Context) (Dataset, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return Dataset{
+		Type:     DbTypeCSV,
+		Records:  int64(len(p.data)),
+		Version:  p.version,
+		LoadedAt: p.loadedAt,
+	}, nil
+}
+
+// Rows calls fn with every loaded row ordered by ip. A row repeated in the file is
+// listed once, with the location that lookups return.
+func (p *CSVProvider) Rows(ctx context.Context, fn func(ip, city, country string) error) error {
+	p.mu.RLock()
+	ips := slices.Sorted(maps.Keys(p.data))
+	records := make([]record, len(ips))
+	for i, ip := range ips {
+		records[i] = p.data[ip]
+	}
+	p.mu.RUnlock()
+	for i, ip := range ips {
+		if err := fn(ip, records[i].city, records[i].country); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -82,3 +82,46 @@ func TestCSVProvider_Lookup_NotFound(t *testing.T) {
 	_, _, err = provider.Lookup(context.Background(), "8.8.8.8")
 	assert.Error(t, err)
 }
+
+func TestCSVProvider_Dataset(t *testing.T) {
+	path := createTempCSV(t, "1.2.3.4,New York,USA\n5.6.7.8,London,UK\n1.2.3.4,Boston,USA\n")
+	defer os.Remove(path) //nolint:errcheck
+
+	config := DbProviderConfig{DbType: DbTypeCSV, ExtraDetails: map[string]interface{}{"file_path": path}}
+	provider, err := NewCSVProvider(config, zap.NewNop(), nil)
+	require.NoError(t, err)
+
+	dataset, err := DescribeDataset(context.Background(), NewSwappableProvider(provider))
+	require.NoError(t, err)
+	assert.Equal(t, DbTypeCSV, dataset.Type)
+	assert.Equal(t, int64(2), dataset.Records, "records counts unique IPs")
+	assert.Len(t, dataset.Version, 12)
+	assert.False(t, dataset.LoadedAt.IsZero())
+
+	same, err := NewCSVProvider(config, zap.NewNop(), nil)
+	require.NoError(t, err)
+	again, err := same.Dataset(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, dataset.Version, again.Version, "the version depends only on the content")
+}
+
+func TestCSVProvider_Rows(t *testing.T) {
+	path := createTempCSV(t, "5.6.7.8,London,UK\n1.2.3.4,New York,USA\n1.2.3.4,Boston,USA\n")
+	defer os.Remove(path) //nolint:errcheck
+
+	config := DbProviderConfig{DbType: DbTypeCSV, ExtraDetails: map[string]interface{}{"file_path": path}}
+	provider, err := NewCSVProvider(config, zap.NewNop(), nil)
+	require.NoError(t, err)
+
+	var rows [][]string
+	err = ListRows(context.Background(), NewSwappableProvider(provider), func(ip, city, country string) error {
+		rows = append(rows, []string{ip, city, country})
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"1.2.3.4", "Boston", "USA"}, {"5.6.7.8", "London", "UK"}}, rows,
+		"rows are ordered by ip and a repeated ip has the location lookups return")
+
+	err = ListRows(context.Background(), &countingProvider{}, func(ip, city, country string) error { return nil })
+	assert.ErrorContains(t, err, "cannot list its rows")
+}
@@ -0,0 +1,46 @@
+package lookup
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Dataset describes the data a provider serves
+type Dataset struct {
+	Type DbType `json:"type"`
+	// Records is the number of IP addresses the provider can resolve
+	Records int64 `json:"records"`
+	// Version identifies the data, e.g. a content hash; empty when the source has none
+	Version  string    `json:"version,omitempty"`
+	LoadedAt time.Time `json:"loaded_at"`
+}
+
+// DatasetDescriber is implemented by providers that can describe their data
+type DatasetDescriber interface {
+	Dataset(ctx context.Context) (Dataset, error)
+}
+
+// DescribeDataset returns the dataset served by provider
+func DescribeDataset(ctx context.Context, provider DbProvider) (Dataset, error) {
+	describer, ok := provider.(DatasetDescriber)
+	if !ok {
+		return Dataset{}, fmt.Errorf("provider %T cannot describe its dataset", provider)
+	}
+	return describer.Dataset(ctx)
+}
+
+// RowLister is implemented by providers that can list every row they serve
+type RowLister interface {
+	// Rows calls fn with each row and stops at the first error fn returns
+	Rows(ctx context.Context, fn func(ip, city, country string) error) error
+}
+
+// ListRows calls fn with every row served by provider
+func ListRows(ctx context.Context, provider DbProvider, fn func(ip, city, country string) error) error {
+	lister, ok := provider.(RowLister)
+	if !ok {
+		return fmt.Errorf("provider %T cannot list its rows", provider)
+	}
+	return lister.Rows(ctx, fn)
+}
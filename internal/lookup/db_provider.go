@@ -5,3 +5,18 @@ import "context"
 type DbProvider interface {
 	Lookup(ctx context.Context, ip string) (city string, country string, err error)
 }
+
+// OverrideReporter is implemented by providers that can report whether a local
+// override, rather than the IP database, answered a lookup
+type OverrideReporter interface {
+	LookupWithOverride(ctx context.Context, ip string) (city string, country string, overridden bool, err error)
+}
+
+// LookupWithOverride resolves ip through provider and reports whether a local override answered
+func LookupWithOverride(ctx context.Context, provider DbProvider, ip string) (string, string, bool, error) {
+	if reporter, ok := provider.(OverrideReporter); ok {
+		return reporter.LookupWithOverride(ctx, ip)
+	}
+	city, country, err := provider.Lookup(ctx, ip)
+	return city, country, false, err
+}
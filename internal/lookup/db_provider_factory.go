@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/shaibs3/Torq/internal/redact"
 	"github.com/shaibs3/Torq/internal/telemetry"
 	"go.opentelemetry.io/otel/metric"
 	"go.uber.org/zap"
@@ -29,7 +30,7 @@ func NewDbProviderFactory(logger *zap.Logger, tel *telemetry.Telemetry) *DbProvi
 
 func (f *DbProviderFactory) CreateProvider(configJSON string) (DbProvider, error) {
 	var config DbProviderConfig
-	f.logger.Info("parsing configuration", zap.String("configJSON", configJSON))
+	f.logger.Info("parsing configuration", redact.JSONField("configJSON", configJSON))
 
 	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
 		return nil, fmt.Errorf("failed to parse database configuration JSON: %w", err)
@@ -37,7 +38,7 @@ func (f *DbProviderFactory) CreateProvider(configJSON string) (DbProvider, error
 
 	f.logger.Info("creating database provider",
 		zap.String("db_type", config.DbType.String()),
-		zap.Any("extra_details", config.ExtraDetails))
+		redact.MapField("extra_details", config.ExtraDetails))
 
 	// Validate database type
 	if !config.DbType.IsValid() {
@@ -51,12 +52,18 @@ func (f *DbProviderFactory) CreateProvider(configJSON string) (DbProvider, error
 	} else {
 		telemetryMeter = nil
 	}
+	var provider DbProvider
+	var err error
 	switch config.DbType {
 	case DbTypeCSV:
-		return NewCSVProvider(config, f.logger, telemetryMeter)
+		provider, err = NewCSVProvider(config, f.logger, telemetryMeter)
 	case DbTypePostgres:
-		return NewPostgresProvider(config, f.logger, telemetryMeter)
+		provider, err = NewPostgresProvider(config, f.logger, telemetryMeter)
 	default:
 		return nil, fmt.Errorf("unsupported database type: %s", config.DbType)
 	}
+	if err != nil {
+		return nil, err
+	}
+	return NewTracedProvider(provider, config.DbType, f.telemetry.Tracer()), nil
 }
@@ -5,14 +5,19 @@ import (
 	"database/sql"
 	"fmt"
 	_ "github.com/lib/pq"
+	"github.com/shaibs3/Torq/internal/logger"
+	"github.com/shaibs3/Torq/internal/redact"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"time"
 )
 
 type PostgresProvider struct {
-	db     *sql.DB
-	logger *zap.Logger
+	db          *sql.DB
+	connectedAt time.Time
+	logger      *zap.Logger
 }
 
 func NewPostgresProvider(config DbProviderConfig, logger *zap.Logger, meter metric.Meter) (*PostgresProvider, error) {
@@ -21,11 +26,17 @@ func NewPostgresProvider(config DbProviderConfig, logger *zap.Logger, meter metr
 	}
 	pgLogger := logger.Named("postgres")
 
-	connStr, ok := config.ExtraDetails["conn_str"].(string)
-	if !ok {
-		return nil, fmt.Errorf("conn_str is required for Postgres provider")
+	// conn_str_file, e.g. a mounted Kubernetes secret, keeps the password out of IP_DB_CONFIG
+	inline, _ := config.ExtraDetails["conn_str"].(string)
+	file, _ := config.ExtraDetails["conn_str_file"].(string)
+	connStr, err := redact.Resolve(inline, file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read conn_str_file: %w", err)
+	}
+	if connStr == "" {
+		return nil, fmt.Errorf("conn_str or conn_str_file is required for Postgres provider")
 	}
-	pgLogger.Info("initializing Postgres provider", zap.String("conn_str", connStr))
+	pgLogger.Info("initializing Postgres provider", redact.URLField("conn_str", connStr))
 
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
@@ -40,27 +51,72 @@ func NewPostgresProvider(config DbProviderConfig, logger *zap.Logger, meter metr
 
 	pgLogger.Info("Postgres provider initialized successfully")
 	return &PostgresProvider{
-		db:     db,
-		logger: pgLogger,
+		db:          db,
+		connectedAt: time.Now(),
+		logger:      pgLogger,
 	}, nil
 }
 
+// Dataset counts the rows of ip_locations. The table is queried live, so LoadedAt is
+// when the provider connected and there is no version.
+func (p *PostgresProvider) Dataset(ctx context.Context) (Dataset, error) {
+	var records int64
+	if err := p.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM ip_locations").Scan(&records); err != nil {
+		return Dataset{}, fmt.Errorf("failed to count ip_locations: %w", err)
+	}
+	return Dataset{Type: DbTypePostgres, Records: records, LoadedAt: p.connectedAt}, nil
+}
+
+// Rows calls fn with every row of ip_locations ordered by ip, e.g. to validate the
+// table, and stops at the first error fn returns. NULL columns are passed as "".
+func (p *PostgresProvider) Rows(ctx context.Context, fn func(ip, city, country string) error) error {
+	rows, err := p.db.QueryContext(ctx, "SELECT ip, city, country FROM ip_locations ORDER BY ip")
+	if err != nil {
+		return fmt.Errorf("failed to query ip_locations: %w", err)
+	}
+	defer rows.Close() //nolint:errcheck
+	for rows.Next() {
+		var ip, city, country sql.NullString
+		if err := rows.Scan(&ip, &city, &country); err != nil {
+			return fmt.Errorf("failed to read ip_locations: %w", err)
+		}
+		if err := fn(ip.String, city.String, country.String); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read ip_locations: %w", err)
+	}
+	return nil
+}
+
+// Close closes the connection pool once in-flight queries finish
+func (p *PostgresProvider) Close() error {
+	return p.db.Close()
+}
+
 func (p *PostgresProvider) Lookup(ctx context.Context, ip string) (string, string, error) {
 	start := time.Now()
-	p.logger.Debug("looking up IP", zap.String("ip", ip))
+	log := logger.WithContext(ctx, p.logger)
+	log.Debug("looking up IP", zap.String("ip", ip))
 	var city, country string
 	query := "SELECT city, country FROM ip_locations WHERE ip = $1"
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("db.system.name", "postgresql"),
+		attribute.String("db.operation.name", "SELECT"),
+		attribute.String("db.collection.name", "ip_locations"),
+		attribute.String("db.query.text", query))
 	err := p.db.QueryRowContext(ctx, query, ip).Scan(&city, &country)
 	if err != nil {
 		IncLookupErrors(ctx)
 		RecordLookupDuration(ctx, time.Since(start).Seconds())
-		p.logger.Error("IP not found in database", zap.String("ip", ip), zap.Error(err))
+		log.Error("IP not found in database", zap.String("ip", ip), zap.Error(err))
 		return "", "", fmt.Errorf("IP not found: %w", err)
 	}
 
 	RecordLookupDuration(ctx, time.Since(start).Seconds())
 
-	p.logger.Debug("IP lookup successful",
+	log.Debug("IP lookup successful",
 		zap.String("ip", ip),
 		zap.String("city", city),
 		zap.String("country", country))
@@ -0,0 +1,90 @@
+package lookup
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// SwappableProvider forwards lookups to a provider that can be replaced at runtime.
+// Lookups in flight finish on the provider they started with.
+type SwappableProvider struct {
+	current atomic.Pointer[generation]
+}
+
+// generation is one provider and the lookups in flight on it. Lookups hold mu for
+// reading; Swap takes it for writing once, so it returns when they have finished,
+// and marks the generation retired so late arrivals move on to its successor.
+type generation struct {
+	provider DbProvider
+	mu       sync.RWMutex
+	retired  bool
+}
+
+func NewSwappableProvider(provider DbProvider) *SwappableProvider {
+	p := &SwappableProvider{}
+	p.current.Store(&generation{provider: provider})
+	return p
+}
+
+// acquire returns the current generation held for reading; the caller releases it
+func (p *SwappableProvider) acquire() *generation {
+	for {
+		g := p.current.Load()
+		g.mu.RLock()
+		if !g.retired {
+			return g
+		}
+		g.mu.RUnlock()
+	}
+}
+
+func (p *SwappableProvider) Lookup(ctx context.Context, ip string) (string, string, error) {
+	g := p.acquire()
+	defer g.mu.RUnlock()
+	return g.provider.Lookup(ctx, ip)
+}
+
+// Current returns the provider lookups are sent to
+func (p *SwappableProvider) Current() DbProvider {
+	return p.current.Load().provider
+}
+
+// Swap sends new lookups to provider and returns the provider it replaced once the
+// lookups in flight on it have finished, so the caller can close it with CloseProvider
+func (p *SwappableProvider) Swap(provider DbProvider) DbProvider {
+	old := p.current.Swap(&generation{provider: provider})
+	old.mu.Lock()
+	old.retired = true
+	old.mu.Unlock()
+	return old.provider
+}
+
+// Dataset describes the current provider's data
+func (p *SwappableProvider) Dataset(ctx context.Context) (Dataset, error) {
+	g := p.acquire()
+	defer g.mu.RUnlock()
+	return DescribeDataset(ctx, g.provider)
+}
+
+// Rows lists the current provider's rows
+func (p *SwappableProvider) Rows(ctx context.Context, fn func(ip, city, country string) error) error {
+	g := p.acquire()
+	defer g.mu.RUnlock()
+	return ListRows(ctx, g.provider, fn)
+}
+
+// Close releases the current provider's resources
+func (p *SwappableProvider) Close() error {
+	return CloseProvider(p.Current())
+}
+
+// CloseProvider releases the resources held by a provider, such as a connection pool,
+// when it has any
+func CloseProvider(provider DbProvider) error {
+	if closer, ok := provider.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package lookup
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticProvider resolves every IP to the same country and records whether it was closed
+type staticProvider struct {
+	country string
+	closed  bool
+}
+
+func (p *staticProvider) Lookup(ctx context.Context, ip string) (string, string, error) {
+	return "", p.country, nil
+}
+
+func (p *staticProvider) Close() error {
+	p.closed = true
+	return nil
+}
+
+func TestSwappableProvider(t *testing.T) {
+	first := &staticProvider{country: "France"}
+	second := &staticProvider{country: "Germany"}
+	provider := NewSwappableProvider(first)
+
+	_, country, err := provider.Lookup(context.Background(), "1.2.3.4")
+	require.NoError(t, err)
+	assert.Equal(t, "France", country)
+
+	old := provider.Swap(second)
+	assert.Same(t, first, old)
+	_, country, err = provider.Lookup(context.Background(), "1.2.3.4")
+	require.NoError(t, err)
+	assert.Equal(t, "Germany", country)
+
+	require.NoError(t, CloseProvider(old))
+	assert.True(t, first.closed)
+	assert.False(t, second.closed)
+
+	require.NoError(t, provider.Close())
+	assert.True(t, second.closed)
+}
+
+// blockingProvider answers a lookup once it is released
+type blockingProvider struct {
+	started  chan struct{}
+	release  chan struct{}
+	closed   atomic.Bool
+	lookedUp atomic.Bool
+}
+
+func (p *blockingProvider) Lookup(ctx context.Context, ip string) (string, string, error) {
+	close(p.started)
+	<-p.release
+	if p.closed.Load() {
+		return "", "", errors.New("database is closed")
+	}
+	p.lookedUp.Store(true)
+	return "", "France", nil
+}
+
+func (p *blockingProvider) Close() error {
+	p.closed.Store(true)
+	return nil
+}
+
+func TestSwappableProvider_SwapWaitsForLookupsInFlight(t *testing.T) {
+	first := &blockingProvider{started: make(chan struct{}), release: make(chan struct{})}
+	provider := NewSwappableProvider(first)
+
+	lookupErr := make(chan error, 1)
+	go func() {
+		_, _, err := provider.Lookup(context.Background(), "1.2.3.4")
+		lookupErr <- err
+	}()
+	<-first.started
+
+	swapped := make(chan DbProvider, 1)
+	go func() {
+		swapped <- provider.Swap(&staticProvider{country: "Germany"})
+	}()
+	select {
+	case <-swapped:
+		t.Fatal("Swap returned while a lookup was in flight on the old provider")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(first.release)
+	old := <-swapped
+	require.NoError(t, CloseProvider(old))
+	require.NoError(t, <-lookupErr)
+	assert.True(t, first.lookedUp.Load())
+
+	_, country, err := provider.Lookup(context.Background(), "1.2.3.4")
+	require.NoError(t, err)
+	assert.Equal(t, "Germany", country)
+}
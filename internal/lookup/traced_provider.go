@@ -0,0 +1,53 @@
+package lookup
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracedProvider wraps a DbProvider in a child span per lookup. Providers add their
+// own attributes, such as the SQL statement, to the span found in the context.
+type TracedProvider struct {
+	provider DbProvider
+	dbType   DbType
+	tracer   trace.Tracer
+}
+
+func NewTracedProvider(provider DbProvider, dbType DbType, tracer trace.Tracer) *TracedProvider {
+	return &TracedProvider{provider: provider, dbType: dbType, tracer: tracer}
+}
+
+func (p *TracedProvider) Lookup(ctx context.Context, ip string) (string, string, error) {
+	ctx, span := p.tracer.Start(ctx, "DbProvider.Lookup",
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attribute.String("torq.db_type", p.dbType.String())))
+	defer span.End()
+
+	city, country, err := p.provider.Lookup(ctx, ip)
+	span.SetAttributes(attribute.Bool("torq.lookup.found", err == nil))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return "", "", err
+	}
+	span.SetAttributes(attribute.String("torq.lookup.country", country))
+	return city, country, nil
+}
+
+// Close releases the wrapped provider's resources
+func (p *TracedProvider) Close() error {
+	return CloseProvider(p.provider)
+}
+
+// Dataset describes the wrapped provider's data
+func (p *TracedProvider) Dataset(ctx context.Context) (Dataset, error) {
+	return DescribeDataset(ctx, p.provider)
+}
+
+// Rows lists the wrapped provider's rows
+func (p *TracedProvider) Rows(ctx context.Context, fn func(ip, city, country string) error) error {
+	return ListRows(ctx, p.provider, fn)
+}
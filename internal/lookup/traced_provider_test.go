@@ -0,0 +1,46 @@
+package lookup
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type stubProvider map[string]string
+
+func (s stubProvider) Lookup(ctx context.Context, ip string) (string, string, error) {
+	country, ok := s[ip]
+	if !ok {
+		return "", "", fmt.Errorf("IP not found")
+	}
+	return "Paris", country, nil
+}
+
+func TestTracedProvider(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)).Tracer("test")
+	provider := NewTracedProvider(stubProvider{"1.2.3.4": "France"}, DbTypeCSV, tracer)
+
+	city, country, err := provider.Lookup(context.Background(), "1.2.3.4")
+	require.NoError(t, err)
+	assert.Equal(t, "Paris", city)
+	assert.Equal(t, "France", country)
+
+	_, _, err = provider.Lookup(context.Background(), "5.6.7.8")
+	assert.Error(t, err)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 2)
+	assert.Equal(t, "DbProvider.Lookup", ended[0].Name())
+	assert.Contains(t, ended[0].Attributes(), attribute.String("torq.db_type", "csv"))
+	assert.Contains(t, ended[0].Attributes(), attribute.Bool("torq.lookup.found", true))
+	assert.Contains(t, ended[1].Attributes(), attribute.Bool("torq.lookup.found", false))
+	assert.Equal(t, codes.Error, ended[1].Status().Code)
+}
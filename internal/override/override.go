@@ -0,0 +1,121 @@
+package override
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for an override ID that does not exist
+	ErrNotFound = errors.New("override not found")
+	// ErrInvalid is wrapped by errors caused by an invalid override
+	ErrInvalid = errors.New("invalid override")
+	// ErrConflict is returned when another override already covers the same range
+	ErrConflict = errors.New("override already exists")
+)
+
+// Spec is the editable part of an override
+type Spec struct {
+	// CIDR is the range the override covers; a single address covers only itself
+	CIDR    string `json:"cidr"`
+	City    string `json:"city"`
+	Country string `json:"country"`
+	// Note says why the override exists, e.g. "Berlin office VPN egress"
+	Note string `json:"note,omitempty"`
+}
+
+// Override maps an IP range to a location, taking precedence over the provider's data
+type Override struct {
+	ID string `json:"id"`
+	Spec
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Normalize validates the spec and returns it with its range in canonical form,
+// e.g. "10.1.2.3/16" becomes "10.1.0.0/16" and "10.1.2.3" becomes "10.1.2.3/32"
+func (s Spec) Normalize() (Spec, error) {
+	prefix, err := parseRange(s.CIDR)
+	if err != nil {
+		return Spec{}, err
+	}
+	s.CIDR = prefix.String()
+	s.City = strings.TrimSpace(s.City)
+	s.Country = strings.TrimSpace(s.Country)
+	if s.Country == "" {
+		return Spec{}, fmt.Errorf("%w: country is required", ErrInvalid)
+	}
+	return s, nil
+}
+
+func parseRange(cidr string) (netip.Prefix, error) {
+	cidr = strings.TrimSpace(cidr)
+	if cidr == "" {
+		return netip.Prefix{}, fmt.Errorf("%w: cidr is required", ErrInvalid)
+	}
+	if !strings.Contains(cidr, "/") {
+		addr, err := netip.ParseAddr(cidr)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("%w: invalid IP address %q", ErrInvalid, cidr)
+		}
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("%w: invalid CIDR %q", ErrInvalid, cidr)
+	}
+	if prefix.Addr().Is4In6() {
+		if prefix.Bits() < 96 {
+			return netip.Prefix{}, fmt.Errorf("%w: invalid CIDR %q", ErrInvalid, cidr)
+		}
+		prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+	}
+	return prefix.Masked(), nil
+}
+
+// file is the on-disk format of the overrides file
+type file struct {
+	Overrides []Override `json:"overrides"`
+}
+
+// LoadFile reads and validates an overrides file. A missing file holds no overrides.
+func LoadFile(path string) ([]Override, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- the path comes from the operator's configuration
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read overrides file: %w", err)
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse overrides file: %w", err)
+	}
+	ids := make(map[string]bool, len(f.Overrides))
+	ranges := make(map[string]bool, len(f.Overrides))
+	for i, o := range f.Overrides {
+		if o.ID == "" {
+			return nil, fmt.Errorf("override %d: id is required", i)
+		}
+		if ids[o.ID] {
+			return nil, fmt.Errorf("override %s: duplicate id", o.ID)
+		}
+		spec, err := o.Spec.Normalize()
+		if err != nil {
+			return nil, fmt.Errorf("override %s: %w", o.ID, err)
+		}
+		if ranges[spec.CIDR] {
+			return nil, fmt.Errorf("override %s: %w for %s", o.ID, ErrConflict, spec.CIDR)
+		}
+		ids[o.ID] = true
+		ranges[spec.CIDR] = true
+		f.Overrides[i].Spec = spec
+	}
+	return f.Overrides, nil
+}
@@ -0,0 +1,305 @@
+package override
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shaibs3/Torq/internal/lookup"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Provider answers lookups for IPs covered by a local override and forwards the rest
+// to the wrapped provider. When ranges overlap the most specific one wins. Overrides
+// are persisted to a JSON file and every change is appended to an audit file.
+type Provider struct {
+	provider  lookup.DbProvider
+	path      string
+	auditPath string
+
+	mu        sync.RWMutex
+	overrides []Override // in creation order
+	matchers  []matcher  // most specific range first
+
+	timeProvider func() time.Time
+	logger       *zap.Logger
+}
+
+type matcher struct {
+	prefix  netip.Prefix
+	city    string
+	country string
+}
+
+// NewProvider loads the overrides in path, which need not exist yet. An empty
+// auditPath keeps the audit trail at DefaultAuditPath(path).
+func NewProvider(provider lookup.DbProvider, path, auditPath string, logger *zap.Logger) (*Provider, error) {
+	return NewProviderWithTimeProvider(provider, path, auditPath, logger, time.Now)
+}
+
+func NewProviderWithTimeProvider(provider lookup.DbProvider, path, auditPath string, logger *zap.Logger, tp func() time.Time) (*Provider, error) {
+	if path == "" {
+		return nil, fmt.Errorf("a file is required for overrides")
+	}
+	if auditPath == "" {
+		auditPath = DefaultAuditPath(path)
+	}
+	overrides, err := LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &Provider{
+		provider:     provider,
+		path:         path,
+		auditPath:    auditPath,
+		timeProvider: tp,
+		logger:       logger.Named("overrides"),
+	}
+	p.set(overrides)
+	p.logger.Info("overrides loaded", zap.String("path", path), zap.Int("overrides", len(overrides)))
+	return p, nil
+}
+
+func (p *Provider) Lookup(ctx context.Context, ip string) (string, string, error) {
+	city, country, _, err := p.LookupWithOverride(ctx, ip)
+	return city, country, err
+}
+
+// LookupWithOverride resolves ip and reports whether an override answered
+func (p *Provider) LookupWithOverride(ctx context.Context, ip string) (string, string, bool, error) {
+	span := trace.SpanFromContext(ctx)
+	if city, country, ok := p.match(ip); ok {
+		span.SetAttributes(attribute.Bool("torq.lookup.override", true))
+		return city, country, true, nil
+	}
+	span.SetAttributes(attribute.Bool("torq.lookup.override", false))
+
+	city, country, err := p.provider.Lookup(ctx, ip)
+	return city, country, false, err
+}
+
+func (p *Provider) match(ip string) (string, string, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", "", false
+	}
+	addr = addr.Unmap()
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, m := range p.matchers {
+		if m.prefix.Contains(addr) {
+			return m.city, m.country, true
+		}
+	}
+	return "", "", false
+}
+
+// List returns every override in creation order
+func (p *Provider) List() []Override {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]Override{}, p.overrides...)
+}
+
+// Get returns the override with the given ID
+func (p *Provider) Get(id string) (Override, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	i := p.index(id)
+	if i < 0 {
+		return Override{}, ErrNotFound
+	}
+	return p.overrides[i], nil
+}
+
+// Create adds an override, attributing the change to the caller in ctx
+func (p *Provider) Create(ctx context.Context, spec Spec) (Override, error) {
+	spec, err := spec.Normalize()
+	if err != nil {
+		return Override{}, err
+	}
+	id, err := newID()
+	if err != nil {
+		return Override{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.checkConflict(spec.CIDR, ""); err != nil {
+		return Override{}, err
+	}
+	now := p.timeProvider().UTC()
+	created := Override{ID: id, Spec: spec, CreatedAt: now, UpdatedAt: now}
+	if err := p.commit(append(append([]Override{}, p.overrides...), created)); err != nil {
+		return Override{}, err
+	}
+	p.audit(newAuditEntry(ctx, now, ActionCreate, id, nil, &created))
+	return created, nil
+}
+
+// Update replaces the spec of an override, attributing the change to the caller in ctx
+func (p *Provider) Update(ctx context.Context, id string, spec Spec) (Override, error) {
+	spec, err := spec.Normalize()
+	if err != nil {
+		return Override{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i := p.index(id)
+	if i < 0 {
+		return Override{}, ErrNotFound
+	}
+	if err := p.checkConflict(spec.CIDR, id); err != nil {
+		return Override{}, err
+	}
+	now := p.timeProvider().UTC()
+	before := p.overrides[i]
+	updated := before
+	updated.Spec = spec
+	updated.UpdatedAt = now
+	next := append([]Override{}, p.overrides...)
+	next[i] = updated
+	if err := p.commit(next); err != nil {
+		return Override{}, err
+	}
+	p.audit(newAuditEntry(ctx, now, ActionUpdate, id, &before, &updated))
+	return updated, nil
+}
+
+// Delete removes an override, attributing the change to the caller in ctx
+func (p *Provider) Delete(ctx context.Context, id string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i := p.index(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	before := p.overrides[i]
+	next := append(append([]Override{}, p.overrides[:i]...), p.overrides[i+1:]...)
+	if err := p.commit(next); err != nil {
+		return err
+	}
+	p.audit(newAuditEntry(ctx, p.timeProvider(), ActionDelete, id, &before, nil))
+	return nil
+}
+
+// Audit returns the last limit changes, most recent first
+func (p *Provider) Audit(limit int) ([]AuditEntry, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return readAudit(p.auditPath, limit)
+}
+
+// Dataset describes the wrapped provider's data
+func (p *Provider) Dataset(ctx context.Context) (lookup.Dataset, error) {
+	return lookup.DescribeDataset(ctx, p.provider)
+}
+
+// Close releases the wrapped provider's resources
+func (p *Provider) Close() error {
+	return lookup.CloseProvider(p.provider)
+}
+
+func (p *Provider) index(id string) int {
+	for i, o := range p.overrides {
+		if o.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkConflict rejects a range already covered by an override other than id
+func (p *Provider) checkConflict(cidr, id string) error {
+	for _, o := range p.overrides {
+		if o.CIDR == cidr && o.ID != id {
+			return fmt.Errorf("%w for %s: %s", ErrConflict, cidr, o.ID)
+		}
+	}
+	return nil
+}
+
+// commit persists overrides and then serves them. The caller holds p.mu.
+func (p *Provider) commit(overrides []Override) error {
+	if err := p.save(overrides); err != nil {
+		return err
+	}
+	p.set(overrides)
+	return nil
+}
+
+// set serves overrides. The caller holds p.mu or owns p.
+func (p *Provider) set(overrides []Override) {
+	matchers := make([]matcher, 0, len(overrides))
+	for _, o := range overrides {
+		// Overrides were normalized when loaded or created
+		prefix := netip.MustParsePrefix(o.CIDR)
+		matchers = append(matchers, matcher{prefix: prefix, city: o.City, country: o.Country})
+	}
+	sort.SliceStable(matchers, func(i, j int) bool {
+		return matchers[i].prefix.Bits() > matchers[j].prefix.Bits()
+	})
+	p.overrides = overrides
+	p.matchers = matchers
+}
+
+// save writes overrides to a temporary file and renames it over the overrides file,
+// so readers never see a partial write
+func (p *Provider) save(overrides []Override) error {
+	data, err := json.MarshalIndent(file{Overrides: overrides}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode overrides: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p.path), filepath.Base(p.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to save overrides: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to save overrides: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save overrides: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), p.path); err != nil {
+		return fmt.Errorf("failed to save overrides: %w", err)
+	}
+	return nil
+}
+
+// audit logs a change and appends it to the audit file. The change is already
+// persisted, so a failed append is logged rather than returned.
+func (p *Provider) audit(entry AuditEntry) {
+	p.logger.Info("override changed",
+		zap.String("action", string(entry.Action)),
+		zap.String("override_id", entry.OverrideID),
+		zap.String("actor", entry.Actor),
+		zap.String("request_id", entry.RequestID))
+	if err := appendAudit(p.auditPath, entry); err != nil {
+		p.logger.Error("failed to record override change in the audit file",
+			zap.String("path", p.auditPath),
+			zap.String("override_id", entry.OverrideID),
+			zap.Error(err))
+	}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate override ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,182 @@
+package override
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shaibs3/Torq/internal/identity"
+	"github.com/shaibs3/Torq/internal/lookup"
+	"github.com/shaibs3/Torq/internal/requestid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// staticProvider resolves every IP but 8.8.8.8 to Paris, France
+type staticProvider struct{}
+
+func (staticProvider) Lookup(ctx context.Context, ip string) (string, string, error) {
+	if ip == "8.8.8.8" {
+		return "", "", fmt.Errorf("IP not found")
+	}
+	return "Paris", "France", nil
+}
+
+func newTestProvider(t *testing.T) (*Provider, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "overrides.json")
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	p, err := NewProviderWithTimeProvider(staticProvider{}, path, "", zap.NewNop(), func() time.Time { return now })
+	require.NoError(t, err)
+	return p, path
+}
+
+func TestSpec_Normalize(t *testing.T) {
+	tests := []struct {
+		cidr    string
+		want    string
+		wantErr bool
+	}{
+		{cidr: "10.1.2.3/16", want: "10.1.0.0/16"},
+		{cidr: "10.1.2.3", want: "10.1.2.3/32"},
+		{cidr: " 2001:db8::1/32 ", want: "2001:db8::/32"},
+		{cidr: "::ffff:10.0.0.0/104", want: "10.0.0.0/8"},
+		{cidr: "::ffff:10.0.0.1", want: "10.0.0.1/32"},
+		{cidr: "", wantErr: true},
+		{cidr: "10.0.0.0/33", wantErr: true},
+		{cidr: "not-an-ip", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cidr, func(t *testing.T) {
+			spec, err := Spec{CIDR: tt.cidr, Country: "Germany"}.Normalize()
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalid)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, spec.CIDR)
+		})
+	}
+
+	_, err := Spec{CIDR: "10.0.0.0/8", Country: " "}.Normalize()
+	require.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestProvider_Lookup(t *testing.T) {
+	p, _ := newTestProvider(t)
+	ctx := context.Background()
+
+	_, err := p.Create(ctx, Spec{CIDR: "10.0.0.0/8", City: "Tel Aviv", Country: "Israel"})
+	require.NoError(t, err)
+	_, err = p.Create(ctx, Spec{CIDR: "10.1.0.0/16", City: "Berlin", Country: "Germany"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		ip         string
+		city       string
+		overridden bool
+	}{
+		{ip: "10.2.3.4", city: "Tel Aviv", overridden: true},
+		{ip: "10.1.3.4", city: "Berlin", overridden: true},
+		{ip: "::ffff:10.1.3.4", city: "Berlin", overridden: true},
+		{ip: "1.2.3.4", city: "Paris"},
+	}
+	for _, tt := range tests {
+		city, _, overridden, err := lookup.LookupWithOverride(ctx, p, tt.ip)
+		require.NoError(t, err, tt.ip)
+		assert.Equal(t, tt.city, city, tt.ip)
+		assert.Equal(t, tt.overridden, overridden, tt.ip)
+	}
+
+	_, _, overridden, err := p.LookupWithOverride(ctx, "8.8.8.8")
+	require.Error(t, err)
+	assert.False(t, overridden)
+}
+
+func TestProvider_CRUD(t *testing.T) {
+	p, path := newTestProvider(t)
+	ctx := identity.NewContext(requestid.NewContext(context.Background(), "req-1"), &identity.Identity{ID: "ops"})
+
+	created, err := p.Create(ctx, Spec{CIDR: "203.0.113.7/24", City: "Berlin", Country: "Germany", Note: "office VPN"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+	assert.Equal(t, "203.0.113.0/24", created.CIDR)
+
+	_, err = p.Create(ctx, Spec{CIDR: "203.0.113.0/24", Country: "France"})
+	require.ErrorIs(t, err, ErrConflict)
+
+	updated, err := p.Update(ctx, created.ID, Spec{CIDR: "203.0.113.0/25", City: "Munich", Country: "Germany"})
+	require.NoError(t, err)
+	assert.Equal(t, "Munich", updated.City)
+	assert.Equal(t, created.CreatedAt, updated.CreatedAt)
+
+	got, err := p.Get(created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, updated, got)
+
+	// Changes survive a restart
+	reloaded, err := NewProvider(staticProvider{}, path, "", zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, []Override{updated}, reloaded.List())
+
+	require.NoError(t, p.Delete(ctx, created.ID))
+	assert.Empty(t, p.List())
+	require.ErrorIs(t, p.Delete(ctx, created.ID), ErrNotFound)
+	_, err = p.Update(ctx, created.ID, Spec{CIDR: "10.0.0.0/8", Country: "Israel"})
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// Every change is audited, most recent first
+	entries, err := p.Audit(10)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, []Action{ActionDelete, ActionUpdate, ActionCreate},
+		[]Action{entries[0].Action, entries[1].Action, entries[2].Action})
+	for _, entry := range entries {
+		assert.Equal(t, "ops", entry.Actor)
+		assert.Equal(t, "req-1", entry.RequestID)
+		assert.Equal(t, created.ID, entry.OverrideID)
+	}
+	assert.Nil(t, entries[2].Before)
+	assert.Equal(t, "Berlin", entries[1].Before.City)
+	assert.Equal(t, "Munich", entries[1].After.City)
+	assert.Nil(t, entries[0].After)
+
+	entries, err = p.Audit(1)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, ActionDelete, entries[0].Action)
+	assert.FileExists(t, DefaultAuditPath(path))
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(overrides ...Override) string {
+		path := filepath.Join(dir, "overrides.json")
+		data, err := json.Marshal(file{Overrides: overrides})
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+		return path
+	}
+
+	overrides, err := LoadFile(filepath.Join(dir, "missing.json"))
+	require.NoError(t, err)
+	assert.Empty(t, overrides)
+
+	overrides, err = LoadFile(write(Override{ID: "a", Spec: Spec{CIDR: "10.0.0.1", Country: "Israel"}}))
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1/32", overrides[0].CIDR)
+
+	_, err = LoadFile(write(Override{Spec: Spec{CIDR: "10.0.0.0/8", Country: "Israel"}}))
+	assert.ErrorContains(t, err, "id is required")
+	_, err = LoadFile(write(
+		Override{ID: "a", Spec: Spec{CIDR: "10.0.0.0/8", Country: "Israel"}},
+		Override{ID: "b", Spec: Spec{CIDR: "10.1.2.3/8", Country: "Germany"}}))
+	assert.ErrorIs(t, err, ErrConflict)
+	_, err = LoadFile(write(Override{ID: "a", Spec: Spec{CIDR: "bogus", Country: "Israel"}}))
+	assert.ErrorIs(t, err, ErrInvalid)
+}
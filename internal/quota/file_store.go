@@ -0,0 +1,199 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fileStoreFlushInterval is how often dirty counters are written to disk
+const fileStoreFlushInterval = 5 * time.Second
+
+// FileStore keeps counters in memory and periodically persists them to a JSON file.
+// Counters for periods that have ended are dropped on flush.
+type FileStore struct {
+	mu           sync.Mutex
+	path         string
+	usage        map[string]map[string]int64 // client ID -> period key -> used
+	dirty        bool
+	logger       *zap.Logger
+	timeProvider func() time.Time
+	stop         chan struct{}
+	done         chan struct{}
+}
+
+func NewFileStore(path string, logger *zap.Logger) (*FileStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("file_path is required for the file quota store")
+	}
+	logger = logger.Named("quota_file_store")
+
+	s := &FileStore{
+		path:         path,
+		usage:        make(map[string]map[string]int64),
+		logger:       logger,
+		timeProvider: time.Now,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		logger.Info("quota file not found, starting with empty usage", zap.String("path", path))
+	case err != nil:
+		return nil, fmt.Errorf("failed to read quota file: %w", err)
+	default:
+		if err := json.Unmarshal(data, &s.usage); err != nil {
+			return nil, fmt.Errorf("failed to parse quota file: %w", err)
+		}
+		logger.Info("quota usage loaded", zap.String("path", path), zap.Int("clients", len(s.usage)))
+	}
+
+	go s.flushLoop()
+	return s, nil
+}
+
+// setTimeProvider makes pruning follow the manager's clock, so counters the
+// manager still charges are never dropped as ended periods
+func (s *FileStore) setTimeProvider(tp func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timeProvider = tp
+}
+
+func (s *FileStore) Consume(ctx context.Context, clientID string, n int64, windows []Window) ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counters := s.usage[clientID]
+	for _, w := range windows {
+		if counters[w.Key]+n > w.Limit {
+			return nil, ErrQuotaExceeded
+		}
+	}
+
+	if counters == nil {
+		counters = make(map[string]int64)
+		s.usage[clientID] = counters
+	}
+	used := make([]int64, len(windows))
+	for i, w := range windows {
+		counters[w.Key] += n
+		used[i] = counters[w.Key]
+	}
+	s.dirty = true
+	return used, nil
+}
+
+func (s *FileStore) Refund(ctx context.Context, clientID string, n int64, periodKeys []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counters := s.usage[clientID]
+	for _, key := range periodKeys {
+		if used, ok := counters[key]; ok {
+			counters[key] = max(used-n, 0)
+			s.dirty = true
+		}
+	}
+	return nil
+}
+
+func (s *FileStore) Get(ctx context.Context, clientID string, periodKey string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage[clientID][periodKey], nil
+}
+
+// Close stops the flush loop and writes the final state to disk
+func (s *FileStore) Close() error {
+	close(s.stop)
+	<-s.done
+	return s.Flush()
+}
+
+// Flush writes counters to disk if they changed since the last flush. When the
+// write fails the counters stay pending, so the next flush retries them.
+func (s *FileStore) Flush() error {
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	s.prune(s.timeProvider())
+	data, err := json.Marshal(s.usage)
+	s.dirty = false
+	s.mu.Unlock()
+	if err == nil {
+		err = s.write(data)
+	}
+	if err != nil {
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// write replaces the file atomically so a crash never leaves a truncated file behind
+func (s *FileStore) write(data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create quota temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write quota file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write quota file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace quota file: %w", err)
+	}
+	return nil
+}
+
+// prune drops counters for periods other than the current day and month
+func (s *FileStore) prune(now time.Time) {
+	current := map[string]bool{
+		PeriodDaily.key(now):   true,
+		PeriodMonthly.key(now): true,
+	}
+	for clientID, counters := range s.usage {
+		for key := range counters {
+			if !current[key] {
+				delete(counters, key)
+			}
+		}
+		if len(counters) == 0 {
+			delete(s.usage, clientID)
+		}
+	}
+}
+
+func (s *FileStore) flushLoop() {
+	defer close(s.done)
+	ticker := time.NewTicker(fileStoreFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				s.logger.Error("failed to flush quota usage", zap.Error(err))
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package quota
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	_ "github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// PostgresStore keeps counters in the quota_usage table, shared by all replicas
+type PostgresStore struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+const createQuotaTable = `CREATE TABLE IF NOT EXISTS quota_usage (
+    client_id VARCHAR(255) NOT NULL,
+    period VARCHAR(32) NOT NULL,
+    used BIGINT NOT NULL,
+    PRIMARY KEY (client_id, period)
+)`
+
+// consumeQuery increments a counter only if the result stays within the limit.
+// No row is returned when the limit would be exceeded.
+const consumeQuery = `INSERT INTO quota_usage (client_id, period, used) VALUES ($1, $2, $3)
+ON CONFLICT (client_id, period) DO UPDATE SET used = quota_usage.used + EXCLUDED.used
+WHERE quota_usage.used + EXCLUDED.used <= $4
+RETURNING used`
+
+// refundQuery gives back consumed lookups without going below zero
+const refundQuery = `UPDATE quota_usage SET used = GREATEST(used - $3, 0) WHERE client_id = $1 AND period = $2`
+
+func NewPostgresStore(connStr string, logger *zap.Logger) (*PostgresStore, error) {
+	if connStr == "" {
+		return nil, fmt.Errorf("conn_str or conn_str_file is required for the postgres quota store")
+	}
+	logger = logger.Named("quota_postgres_store")
+
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Postgres connection: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping Postgres: %w", err)
+	}
+	if _, err := db.Exec(createQuotaTable); err != nil {
+		return nil, fmt.Errorf("failed to create quota_usage table: %w", err)
+	}
+
+	logger.Info("Postgres quota store initialized successfully")
+	return &PostgresStore{db: db, logger: logger}, nil
+}
+
+func (s *PostgresStore) Consume(ctx context.Context, clientID string, n int64, windows []Window) ([]int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin quota transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	used := make([]int64, len(windows))
+	for i, w := range windows {
+		err := tx.QueryRowContext(ctx, consumeQuery, clientID, w.Key, n, w.Limit).Scan(&used[i])
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrQuotaExceeded
+		}
+		if err != nil {
+			s.logger.Error("failed to update quota usage", zap.String("client_id", clientID), zap.Error(err))
+			return nil, fmt.Errorf("failed to update quota usage: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit quota usage: %w", err)
+	}
+	return used, nil
+}
+
+func (s *PostgresStore) Refund(ctx context.Context, clientID string, n int64, periodKeys []string) error {
+	for _, key := range periodKeys {
+		if _, err := s.db.ExecContext(ctx, refundQuery, clientID, key, n); err != nil {
+			return fmt.Errorf("failed to refund quota usage: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *PostgresStore) Get(ctx context.Context, clientID string, periodKey string) (int64, error) {
+	var used int64
+	err := s.db.QueryRowContext(ctx, "SELECT used FROM quota_usage WHERE client_id = $1 AND period = $2", clientID, periodKey).Scan(&used)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read quota usage: %w", err)
+	}
+	return used, nil
+}
+
+func (s *PostgresStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,268 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/shaibs3/Torq/internal/identity"
+	"go.uber.org/zap"
+)
+
+// ErrQuotaExceeded is returned when consuming would exceed a daily or monthly quota
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Period is a calendar period over which usage accumulates
+type Period string
+
+const (
+	PeriodDaily   Period = "daily"
+	PeriodMonthly Period = "monthly"
+)
+
+// Periods lists the supported periods in the order they are checked
+var Periods = []Period{PeriodDaily, PeriodMonthly}
+
+// key returns the storage key of the period containing t, e.g. "daily:2024-01-15"
+func (p Period) key(t time.Time) string {
+	t = t.UTC()
+	if p == PeriodDaily {
+		return "daily:" + t.Format("2006-01-02")
+	}
+	return "monthly:" + t.Format("2006-01")
+}
+
+// resetAt returns the start of the next period after t
+func (p Period) resetAt(t time.Time) time.Time {
+	t = t.UTC()
+	if p == PeriodDaily {
+		return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Limits are the maximum lookups per period; zero means unlimited
+type Limits struct {
+	Daily   int64 `json:"daily,omitempty"`
+	Monthly int64 `json:"monthly,omitempty"`
+}
+
+func (l Limits) forPeriod(p Period) int64 {
+	if p == PeriodDaily {
+		return l.Daily
+	}
+	return l.Monthly
+}
+
+// StoreConfig selects where usage is persisted
+type StoreConfig struct {
+	Type     string `json:"type"`
+	FilePath string `json:"file_path,omitempty"`
+	ConnStr  string `json:"conn_str,omitempty"`
+	// ConnStrFile names a file holding the connection string and takes precedence over ConnStr
+	ConnStrFile string `json:"conn_str_file,omitempty"`
+}
+
+// Config is the JSON form of QUOTA_CONFIG
+type Config struct {
+	Store StoreConfig `json:"store"`
+	// Default applies to every client without an override
+	Default Limits `json:"default"`
+	// Overrides holds per-client limits keyed by client ID
+	Overrides map[string]Limits `json:"overrides,omitempty"`
+}
+
+// ParseConfig parses QUOTA_CONFIG; an empty string disables quotas and returns nil
+func ParseConfig(configJSON string) (*Config, error) {
+	if strings.TrimSpace(configJSON) == "" {
+		return nil, nil
+	}
+	var cfg Config
+	if err := json.Unmarshal([]byte(configJSON), &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse quota configuration JSON: %w", err)
+	}
+	return &cfg, nil
+}
+
+// Window is one period's limit and storage key for a consume call
+type Window struct {
+	Key   string
+	Limit int64
+}
+
+// PeriodUsage reports consumption within one period
+type PeriodUsage struct {
+	Used      int64     `json:"used"`
+	Limit     int64     `json:"limit"`
+	Remaining int64     `json:"remaining"`
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+// Usage reports a client's consumption for every period with a limit
+type Usage struct {
+	ClientID string                 `json:"client_id"`
+	Periods  map[Period]PeriodUsage `json:"periods"`
+
+	// periodKeys are the counters charged by Consume, for Refund
+	periodKeys []string
+}
+
+// Manager enforces long-horizon quotas per client on top of a persistent Store
+type Manager struct {
+	store        Store
+	config       Config
+	logger       *zap.Logger
+	timeProvider func() time.Time
+}
+
+func NewManager(config Config, store Store, logger *zap.Logger) *Manager {
+	return NewManagerWithTimeProvider(config, store, logger, time.Now)
+}
+
+// timeAwareStore is implemented by stores that expire counters by the clock
+type timeAwareStore interface {
+	setTimeProvider(tp func() time.Time)
+}
+
+func NewManagerWithTimeProvider(config Config, store Store, logger *zap.Logger, tp func() time.Time) *Manager {
+	if s, ok := store.(timeAwareStore); ok {
+		s.setTimeProvider(tp)
+	}
+	return &Manager{
+		store:        store,
+		config:       config,
+		logger:       logger.Named("quota"),
+		timeProvider: tp,
+	}
+}
+
+// LimitsFor returns the limits that apply to a client
+func (m *Manager) LimitsFor(clientID string) Limits {
+	if limits, ok := m.config.Overrides[clientID]; ok {
+		return limits
+	}
+	return m.config.Default
+}
+
+// Consume records n lookups for the client, or returns ErrQuotaExceeded without
+// recording anything if any period would go over its limit
+func (m *Manager) Consume(ctx context.Context, clientID string, n int64) (*Usage, error) {
+	now := m.timeProvider()
+	limits := m.LimitsFor(clientID)
+
+	var windows []Window
+	var periods []Period
+	for _, period := range Periods {
+		limit := limits.forPeriod(period)
+		if limit <= 0 {
+			continue
+		}
+		if n > limit {
+			return m.exceeded(ctx, clientID, n, now)
+		}
+		windows = append(windows, Window{Key: period.key(now), Limit: limit})
+		periods = append(periods, period)
+	}
+	if len(windows) == 0 {
+		return &Usage{ClientID: clientID, Periods: map[Period]PeriodUsage{}}, nil
+	}
+
+	used, err := m.store.Consume(ctx, clientID, n, windows)
+	if errors.Is(err, ErrQuotaExceeded) {
+		return m.exceeded(ctx, clientID, n, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &Usage{ClientID: clientID, Periods: make(map[Period]PeriodUsage, len(periods))}
+	for i, period := range periods {
+		usage.Periods[period] = newPeriodUsage(used[i], windows[i].Limit, period.resetAt(now))
+		usage.periodKeys = append(usage.periodKeys, windows[i].Key)
+	}
+	return usage, nil
+}
+
+// Refund gives back n lookups charged by the Consume that returned usage, for
+// requests that were rejected after the charge. The counters of the periods charged
+// are decremented even if a new period has started since.
+func (m *Manager) Refund(ctx context.Context, usage *Usage, n int64) error {
+	if usage == nil || len(usage.periodKeys) == 0 {
+		return nil
+	}
+	return m.store.Refund(ctx, usage.ClientID, n, usage.periodKeys)
+}
+
+// Usage returns the client's current consumption
+func (m *Manager) Usage(ctx context.Context, clientID string) (*Usage, error) {
+	return m.usage(ctx, clientID, m.timeProvider())
+}
+
+// exceeded logs a rejected consume and returns the client's usage with ErrQuotaExceeded
+func (m *Manager) exceeded(ctx context.Context, clientID string, n int64, now time.Time) (*Usage, error) {
+	m.logger.Warn("quota exceeded", zap.String("client_id", clientID), zap.Int64("cost", n))
+	usage, err := m.usage(ctx, clientID, now)
+	if err != nil {
+		return nil, err
+	}
+	return usage, ErrQuotaExceeded
+}
+
+// usage reads consumption for every limited period
+func (m *Manager) usage(ctx context.Context, clientID string, now time.Time) (*Usage, error) {
+	limits := m.LimitsFor(clientID)
+	usage := &Usage{ClientID: clientID, Periods: make(map[Period]PeriodUsage)}
+
+	for _, period := range Periods {
+		limit := limits.forPeriod(period)
+		if limit <= 0 {
+			continue
+		}
+		used, err := m.store.Get(ctx, clientID, period.key(now))
+		if err != nil {
+			return nil, err
+		}
+		usage.Periods[period] = newPeriodUsage(used, limit, period.resetAt(now))
+	}
+	return usage, nil
+}
+
+// Close flushes and releases the store
+func (m *Manager) Close() error {
+	return m.store.Close()
+}
+
+func newPeriodUsage(used, limit int64, resetsAt time.Time) PeriodUsage {
+	remaining := limit - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	return PeriodUsage{Used: used, Limit: limit, Remaining: remaining, ResetsAt: resetsAt}
+}
+
+// UsageHandler reports the calling client's consumption
+func (m *Manager) UsageHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	caller, ok := identity.FromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "caller could not be identified"})
+		return
+	}
+
+	usage, err := m.Usage(r.Context(), caller.ID)
+	if err != nil {
+		m.logger.Error("failed to read quota usage", zap.String("client_id", caller.ID), zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "failed to read usage"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(usage)
+}
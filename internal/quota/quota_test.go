@@ -0,0 +1,195 @@
+package quota
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeTime struct {
+	current time.Time
+}
+
+func (f *fakeTime) Now() time.Time {
+	return f.current
+}
+
+func newTestManager(t *testing.T, path string, ft *fakeTime, config Config) *Manager {
+	t.Helper()
+	store, err := NewFileStore(path, zap.NewNop())
+	require.NoError(t, err)
+	return NewManagerWithTimeProvider(config, store, zap.NewNop(), ft.Now)
+}
+
+func TestManager_DailyLimit(t *testing.T) {
+	ft := &fakeTime{current: time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC)}
+	m := newTestManager(t, filepath.Join(t.TempDir(), "quota.json"), ft, Config{Default: Limits{Daily: 3, Monthly: 10}})
+	defer m.Close() //nolint:errcheck
+	ctx := context.Background()
+
+	usage, err := m.Consume(ctx, "client", 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), usage.Periods[PeriodDaily].Remaining)
+	assert.Equal(t, time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC), usage.Periods[PeriodDaily].ResetsAt)
+
+	usage, err = m.Consume(ctx, "client", 2)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Equal(t, int64(2), usage.Periods[PeriodDaily].Used, "rejected requests are not counted")
+
+	_, err = m.Consume(ctx, "client", 1)
+	require.NoError(t, err)
+
+	// The daily quota resets at midnight UTC, the monthly one keeps counting
+	ft.current = ft.current.Add(time.Hour)
+	usage, err = m.Consume(ctx, "client", 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), usage.Periods[PeriodDaily].Used)
+	assert.Equal(t, int64(6), usage.Periods[PeriodMonthly].Used)
+}
+
+func TestManager_MonthlyLimitAndOverrides(t *testing.T) {
+	ft := &fakeTime{current: time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)}
+	m := newTestManager(t, filepath.Join(t.TempDir(), "quota.json"), ft, Config{
+		Default:   Limits{Monthly: 2},
+		Overrides: map[string]Limits{"paid": {Monthly: 100}},
+	})
+	defer m.Close() //nolint:errcheck
+	ctx := context.Background()
+
+	_, err := m.Consume(ctx, "free", 2)
+	require.NoError(t, err)
+	_, err = m.Consume(ctx, "free", 1)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	_, err = m.Consume(ctx, "paid", 50)
+	require.NoError(t, err)
+
+	// Larger than the whole quota: rejected without touching the store
+	_, err = m.Consume(ctx, "free", 3)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	ft.current = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	usage, err := m.Consume(ctx, "free", 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), usage.Periods[PeriodMonthly].Used)
+	assert.NotContains(t, usage.Periods, PeriodDaily, "periods without a limit are not reported")
+}
+
+func TestManager_SurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quota.json")
+	ft := &fakeTime{current: time.Now()}
+	config := Config{Default: Limits{Daily: 5}}
+	ctx := context.Background()
+
+	m := newTestManager(t, path, ft, config)
+	_, err := m.Consume(ctx, "client", 4)
+	require.NoError(t, err)
+	require.NoError(t, m.Close())
+
+	m = newTestManager(t, path, ft, config)
+	defer m.Close() //nolint:errcheck
+	usage, err := m.Usage(ctx, "client")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), usage.Periods[PeriodDaily].Used)
+
+	_, err = m.Consume(ctx, "client", 2)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+}
+
+func TestManager_Refund(t *testing.T) {
+	ft := &fakeTime{current: time.Date(2024, 1, 15, 23, 30, 0, 0, time.UTC)}
+	m := newTestManager(t, filepath.Join(t.TempDir(), "quota.json"), ft, Config{Default: Limits{Daily: 3, Monthly: 10}})
+	defer m.Close() //nolint:errcheck
+	ctx := context.Background()
+
+	_, err := m.Consume(ctx, "client", 1)
+	require.NoError(t, err)
+	charged, err := m.Consume(ctx, "client", 2)
+	require.NoError(t, err)
+
+	// The refund goes to the periods charged, even after the day has ended
+	ft.current = ft.current.Add(time.Hour)
+	require.NoError(t, m.Refund(ctx, charged, 2))
+	usage, err := m.Usage(ctx, "client")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), usage.Periods[PeriodDaily].Used)
+	assert.Equal(t, int64(1), usage.Periods[PeriodMonthly].Used)
+
+	ft.current = ft.current.Add(-time.Hour)
+	usage, err = m.Usage(ctx, "client")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), usage.Periods[PeriodDaily].Used)
+
+	require.NoError(t, m.Refund(ctx, charged, 5), "counters stop at zero")
+	usage, err = m.Usage(ctx, "client")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), usage.Periods[PeriodDaily].Used)
+	assert.NoError(t, m.Refund(ctx, nil, 1))
+}
+
+func TestFileStore_FlushFailureKeepsCounters(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "missing")
+	path := filepath.Join(dir, "quota.json")
+	store, err := NewFileStore(path, zap.NewNop())
+	require.NoError(t, err)
+	key := PeriodDaily.key(time.Now())
+	_, err = store.Consume(context.Background(), "client", 4, []Window{{Key: key, Limit: 10}})
+	require.NoError(t, err)
+
+	assert.Error(t, store.Flush(), "the directory does not exist")
+	require.NoError(t, os.Mkdir(dir, 0o700))
+	require.NoError(t, store.Close(), "the next flush writes the pending counters")
+
+	store, err = NewFileStore(path, zap.NewNop())
+	require.NoError(t, err)
+	defer store.Close() //nolint:errcheck
+	used, err := store.Get(context.Background(), "client", key)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), used)
+}
+
+func TestFileStore_FlushPrunesByManagerClock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quota.json")
+	ft := &fakeTime{current: time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)}
+	m := newTestManager(t, path, ft, Config{Default: Limits{Daily: 10}})
+	_, err := m.Consume(context.Background(), "client", 3)
+	require.NoError(t, err)
+	require.NoError(t, m.Close(), "the counter is in the manager's current day and survives the flush")
+
+	m = newTestManager(t, path, ft, Config{Default: Limits{Daily: 10}})
+	usage, err := m.Usage(context.Background(), "client")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), usage.Periods[PeriodDaily].Used)
+
+	ft.current = ft.current.Add(24 * time.Hour)
+	_, err = m.Consume(context.Background(), "client", 1)
+	require.NoError(t, err)
+	require.NoError(t, m.Close(), "the previous day has ended by the manager's clock and is pruned")
+
+	store, err := NewFileStore(path, zap.NewNop())
+	require.NoError(t, err)
+	defer store.Close() //nolint:errcheck
+	used, err := store.Get(context.Background(), "client", PeriodDaily.key(ft.current.Add(-24*time.Hour)))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), used)
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig(`{"store": {"type": "file", "file_path": "/tmp/q.json"}, "default": {"monthly": 1000000}, "overrides": {"key-1": {"daily": 10}}}`)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000000), cfg.Default.Monthly)
+	assert.Equal(t, int64(10), cfg.Overrides["key-1"].Daily)
+
+	cfg, err = ParseConfig("")
+	require.NoError(t, err)
+	assert.Nil(t, cfg)
+
+	_, err = NewStore(StoreConfig{Type: "memcached"}, zap.NewNop())
+	assert.Error(t, err)
+}
@@ -0,0 +1,44 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shaibs3/Torq/internal/redact"
+	"go.uber.org/zap"
+)
+
+// Store persists per-client usage counters keyed by period
+type Store interface {
+	// Consume atomically adds n to the client's counter in every window, unless that
+	// would exceed any window's limit, in which case nothing changes and
+	// ErrQuotaExceeded is returned. It returns the new counter of each window.
+	Consume(ctx context.Context, clientID string, n int64, windows []Window) ([]int64, error)
+	// Refund subtracts n from the client's counter for every period key, stopping at zero
+	Refund(ctx context.Context, clientID string, n int64, periodKeys []string) error
+	// Get returns the client's counter for a period key
+	Get(ctx context.Context, clientID string, periodKey string) (int64, error)
+	// Close flushes pending writes and releases resources
+	Close() error
+}
+
+const (
+	StoreTypeFile     = "file"
+	StoreTypePostgres = "postgres"
+)
+
+// NewStore creates the store selected by config
+func NewStore(config StoreConfig, logger *zap.Logger) (Store, error) {
+	switch config.Type {
+	case StoreTypeFile:
+		return NewFileStore(config.FilePath, logger)
+	case StoreTypePostgres:
+		connStr, err := redact.Resolve(config.ConnStr, config.ConnStrFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read quota store conn_str_file: %w", err)
+		}
+		return NewPostgresStore(connStr, logger)
+	default:
+		return nil, fmt.Errorf("unsupported quota store type: %s", config.Type)
+	}
+}
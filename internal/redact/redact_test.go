@@ -0,0 +1,91 @@
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSecret(t *testing.T) {
+	s := Secret("hunter2")
+
+	assert.Equal(t, "hunter2", s.Value())
+	assert.Equal(t, Placeholder, s.String())
+	assert.Equal(t, Placeholder, fmt.Sprintf("%v", s))
+	assert.NotContains(t, fmt.Sprintf("%#v", s), "hunter2")
+
+	data, err := json.Marshal(struct{ Password Secret }{s})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Password": "[REDACTED]"}`, string(data))
+
+	assert.Equal(t, "", Secret("").String(), "an unset secret shows as empty")
+}
+
+func TestMaskURL(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"postgres://torq:s3cret@db:5432/ipdb?sslmode=disable", "postgres://torq:[REDACTED]@db:5432/ipdb?sslmode=disable"},
+		{"postgres://torq@db/ipdb", "postgres://torq@db/ipdb"},
+		{"host=db user=torq password=s3cret dbname=ipdb", "host=db user=torq password=[REDACTED] dbname=ipdb"},
+		{"host=db password='s3 cret' dbname=ipdb", "host=db password=[REDACTED] dbname=ipdb"},
+		{"/var/data/ip.csv", "/var/data/ip.csv"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, MaskURL(tt.in), tt.in)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	in := `{"dbtype": "postgres", "extra_details": {"conn_str": "postgres://u:pw@db/ipdb", "conn_str_file": "/run/secrets/pg"},
+		"jwt": {"client_secret": "abc", "issuer": "https://issuer"}, "redis_password": "pw", "keys": [{"api_key": "k1"}]}`
+
+	got := JSON(in)
+
+	assert.NotContains(t, got, "pw@")
+	assert.NotContains(t, got, "abc")
+	assert.NotContains(t, got, "k1")
+	assert.JSONEq(t, `{"dbtype": "postgres", "extra_details": {"conn_str": "postgres://u:[REDACTED]@db/ipdb", "conn_str_file": "/run/secrets/pg"},
+		"jwt": {"client_secret": "[REDACTED]", "issuer": "https://issuer"}, "redis_password": "[REDACTED]", "keys": [{"api_key": "[REDACTED]"}]}`, got)
+	assert.Equal(t, Placeholder, JSON(`{"password": "not json`), "unparsable input is hidden entirely")
+}
+
+func TestFields(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	zap.New(core).Info("config",
+		URLField("conn_str", "postgres://u:pw@db/ipdb"),
+		JSONField("config", `{"password": "pw"}`),
+		MapField("extra_details", map[string]any{"token": "pw"}),
+		SecretField("redis_password", Secret("pw")),
+	)
+
+	entry := logs.All()[0]
+	for key, value := range entry.ContextMap() {
+		assert.NotContains(t, fmt.Sprint(value), "pw@", key)
+		assert.NotContains(t, fmt.Sprint(value), ":pw", key)
+		assert.NotContains(t, fmt.Sprint(value), "\"pw\"", key)
+	}
+	assert.Equal(t, Placeholder, entry.ContextMap()["redis_password"])
+}
+
+func TestResolve(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conn_str")
+	require.NoError(t, os.WriteFile(path, []byte("postgres://u:pw@db/ipdb\n"), 0o600))
+
+	value, err := Resolve("inline", path)
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://u:pw@db/ipdb", value, "the file wins and its trailing newline is dropped")
+
+	value, err = Resolve("inline", "")
+	require.NoError(t, err)
+	assert.Equal(t, "inline", value)
+
+	_, err = Resolve("", filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,25 @@
+package redact
+
+import (
+	"go.uber.org/zap"
+)
+
+// URLField logs a connection string with its password masked
+func URLField(key, raw string) zap.Field {
+	return zap.String(key, MaskURL(raw))
+}
+
+// JSONField logs a JSON configuration document with its secrets removed
+func JSONField(key, configJSON string) zap.Field {
+	return zap.String(key, JSON(configJSON))
+}
+
+// MapField logs a decoded configuration object with its secrets removed
+func MapField(key string, m map[string]any) zap.Field {
+	return zap.Any(key, Map(m))
+}
+
+// SecretField logs whether a secret is set without revealing it
+func SecretField(key string, s Secret) zap.Field {
+	return zap.Stringer(key, s)
+}
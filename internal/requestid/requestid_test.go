@@ -0,0 +1,23 @@
+package requestid
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValid(t *testing.T) {
+	assert.True(t, Valid(New()))
+	assert.True(t, Valid("9f86d081-884c-7d65:trace/1+a="))
+	assert.False(t, Valid(""))
+	assert.False(t, Valid("has space"))
+	assert.False(t, Valid("line\nbreak"))
+	assert.False(t, Valid(strings.Repeat("a", maxLength+1)))
+}
+
+func TestContext(t *testing.T) {
+	assert.Empty(t, FromContext(context.Background()))
+	assert.Equal(t, "abc", FromContext(NewContext(context.Background(), "abc")))
+}
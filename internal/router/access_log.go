@@ -0,0 +1,126 @@
+package router
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/shaibs3/Torq/internal/identity"
+	"github.com/shaibs3/Torq/internal/logger"
+	"github.com/shaibs3/Torq/internal/requestid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// AccessLogFormat selects how completed requests are logged
+type AccessLogFormat string
+
+const (
+	// AccessLogStructured logs one entry per request with a field per attribute
+	AccessLogStructured AccessLogFormat = "structured"
+	// AccessLogCombined logs the NCSA combined log line as the message
+	AccessLogCombined AccessLogFormat = "combined"
+	// AccessLogOff disables access logging
+	AccessLogOff AccessLogFormat = "off"
+)
+
+// IsValid checks if the access log format is supported
+func (f AccessLogFormat) IsValid() bool {
+	switch f {
+	case AccessLogStructured, AccessLogCombined, AccessLogOff:
+		return true
+	default:
+		return false
+	}
+}
+
+// WithAccessLog selects the access log format; structured is the default
+func WithAccessLog(format AccessLogFormat) Option {
+	return func(r *Router) {
+		r.accessLogFormat = format
+	}
+}
+
+// requestIDMiddleware accepts a well-formed X-Request-ID from the client or generates
+// one, echoes it in the response and stores it in the context with a request-scoped
+// logger. The logger also carries the trace and span IDs of the server span.
+func (router *Router) requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+		w.Header().Set(requestid.Header, id)
+
+		ctx := requestid.NewContext(r.Context(), id)
+		fields := []zap.Field{zap.String("request_id", id)}
+		if span := trace.SpanFromContext(ctx); span.SpanContext().IsValid() {
+			sc := span.SpanContext()
+			fields = append(fields, zap.String("trace_id", sc.TraceID().String()), zap.String("span_id", sc.SpanID().String()))
+			span.SetAttributes(attribute.StringSlice("http.request.header.x-request-id", []string{id}))
+		}
+		ctx = logger.NewContext(ctx, router.logger, fields...)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// accessLogMiddleware logs every completed request, including those rejected by
+// authentication and rate limiting. It must run inside identityMiddleware so the
+// caller resolved by later middleware is visible once the request completes.
+func (router *Router) accessLogMiddleware(accessLogger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if router.accessLogFormat == AccessLogOff {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			wrappedWriter := &ResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+			next.ServeHTTP(wrappedWriter, r)
+
+			duration := time.Since(start)
+			var clientID, authMethod string
+			if caller, ok := identity.FromContext(r.Context()); ok {
+				clientID = caller.ID
+				authMethod = caller.Method
+			}
+
+			// The request-scoped fields add the request, trace and span IDs
+			log := logger.WithContext(r.Context(), accessLogger)
+			if router.accessLogFormat == AccessLogCombined {
+				log.Info(combinedLogLine(r, wrappedWriter, clientID, start), zap.Duration("duration", duration))
+				return
+			}
+
+			log.Info("request completed",
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.String("proto", r.Proto),
+				zap.Int("status_code", wrappedWriter.statusCode),
+				zap.Int64("bytes", wrappedWriter.bytesWritten),
+				zap.Duration("duration", duration),
+				zap.String("remote_addr", r.RemoteAddr),
+				zap.String("user_agent", r.UserAgent()),
+				zap.String("client_id", clientID),
+				zap.String("auth_method", authMethod),
+			)
+		})
+	}
+}
+
+// combinedLogLine formats a request in the NCSA combined log format, with the client ID as the user
+func combinedLogLine(r *http.Request, w *ResponseWriter, clientID string, start time.Time) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if clientID == "" {
+		clientID = "-"
+	}
+	return fmt.Sprintf("%s - %s [%s] %q %d %d %q %q",
+		host, clientID, start.Format("02/Jan/2006:15:04:05 -0700"),
+		r.Method+" "+r.URL.RequestURI()+" "+r.Proto,
+		w.statusCode, w.bytesWritten, r.Referer(), r.UserAgent())
+}
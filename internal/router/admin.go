@@ -0,0 +1,215 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/shaibs3/Torq/internal/logger"
+	"github.com/shaibs3/Torq/internal/lookup"
+	"go.uber.org/zap"
+)
+
+// maxAdminBodyBytes bounds the size of admin request bodies
+const maxAdminBodyBytes = 1 << 16
+
+// AdminStatus describes the running instance
+type AdminStatus struct {
+	Service   string    `json:"service"`
+	StartedAt time.Time `json:"started_at"`
+	Uptime    string    `json:"uptime"`
+}
+
+// ReloadResult reports which settings a configuration reload applied and which changed
+// settings only take effect after a restart
+type ReloadResult struct {
+	// ConfigFile is the file re-read, empty when there is none
+	ConfigFile string `json:"config_file"`
+	// Note explains a reload that could not change much, e.g. without a config file
+	Note            string   `json:"note,omitempty"`
+	Applied         []string `json:"applied"`
+	RestartRequired []string `json:"restart_required"`
+}
+
+// CachePurgeResult reports how many cached lookups were dropped
+type CachePurgeResult struct {
+	Enabled bool `json:"enabled"`
+	Purged  int  `json:"purged"`
+}
+
+// LogLevel is the body of the log level endpoints
+type LogLevel struct {
+	Level string `json:"level"`
+}
+
+// RateLimitParams are the default rate limit policy's parameters. Token bucket and
+// GCRA use RPS and Burst; the sliding window algorithms admit Limit requests per Window.
+type RateLimitParams struct {
+	Algorithm string `json:"algorithm"`
+	RPS       int    `json:"rps"`
+	Burst     int    `json:"burst"`
+	Limit     int    `json:"limit"`
+	Window    string `json:"window"`
+}
+
+// ErrInvalidSetting is wrapped by Controller errors caused by the requested value
+var ErrInvalidSetting = errors.New("invalid setting")
+
+// Controller inspects and changes the running instance on behalf of the admin API
+type Controller interface {
+	// Reload re-reads the configuration and applies what can change at runtime
+	Reload(ctx context.Context) (*ReloadResult, error)
+	// Dataset describes the data served by the IP database provider
+	Dataset(ctx context.Context) (lookup.Dataset, error)
+	// PurgeCache empties the lookup cache
+	PurgeCache() CachePurgeResult
+	// EffectiveConfig returns the settings in effect, with secrets redacted
+	EffectiveConfig() map[string]any
+	LogLevel() string
+	SetLogLevel(level string) error
+	RateLimit() RateLimitParams
+	SetRateLimit(params RateLimitParams) error
+}
+
+// WithController serves the admin endpoints that inspect and change the instance
+func WithController(controller Controller) Option {
+	return func(r *Router) {
+		r.controller = controller
+	}
+}
+
+// adminAuthMiddleware refuses admin requests when no authentication is configured,
+// so the admin API is never reachable anonymously
+func (router *Router) adminAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if router.auth == nil && router.jwt == nil {
+			writeJSONError(w, http.StatusForbidden, "forbidden", "the admin API requires AUTH_CONFIG")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// controllerMiddleware answers 501 on the admin routes that need a Controller when none is set
+func (router *Router) controllerMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if router.controller == nil {
+			writeJSONError(w, http.StatusNotImplemented, "not_implemented", "this instance cannot be controlled at runtime")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminStatusHandler reports when the instance started
+func (router *Router) adminStatusHandler(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, AdminStatus{
+		Service:   "torq",
+		StartedAt: router.startedAt.UTC(),
+		Uptime:    time.Since(router.startedAt).Round(time.Second).String(),
+	})
+}
+
+// adminReloadHandler re-reads the configuration and reports what changed
+func (router *Router) adminReloadHandler(w http.ResponseWriter, r *http.Request) {
+	result, err := router.controller.Reload(r.Context())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "reload_failed", err.Error())
+		return
+	}
+	writeAdminJSON(w, result)
+}
+
+// adminDatasetHandler describes the data served by the provider
+func (router *Router) adminDatasetHandler(w http.ResponseWriter, r *http.Request) {
+	dataset, err := router.controller.Dataset(r.Context())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "dataset_unavailable", err.Error())
+		return
+	}
+	writeAdminJSON(w, dataset)
+}
+
+// adminCachePurgeHandler empties the lookup cache
+func (router *Router) adminCachePurgeHandler(w http.ResponseWriter, r *http.Request) {
+	result := router.controller.PurgeCache()
+	logger.WithContext(r.Context(), router.logger).Info("lookup cache purged via admin API", zap.Int("purged", result.Purged))
+	writeAdminJSON(w, result)
+}
+
+// adminConfigHandler returns the effective configuration with secrets redacted
+func (router *Router) adminConfigHandler(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, router.controller.EffectiveConfig())
+}
+
+// adminGetLogLevelHandler returns the current log level
+func (router *Router) adminGetLogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, LogLevel{Level: router.controller.LogLevel()})
+}
+
+// adminSetLogLevelHandler changes the log level until the next reload or restart
+func (router *Router) adminSetLogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	var body LogLevel
+	if !decodeAdminBody(w, r, &body) {
+		return
+	}
+	if err := router.controller.SetLogLevel(body.Level); err != nil {
+		writeControllerError(w, err)
+		return
+	}
+	logger.WithContext(r.Context(), router.logger).Info("log level changed via admin API", zap.String("level", body.Level))
+	writeAdminJSON(w, LogLevel{Level: router.controller.LogLevel()})
+}
+
+// adminGetRateLimitHandler returns the default rate limit policy's parameters
+func (router *Router) adminGetRateLimitHandler(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, router.controller.RateLimit())
+}
+
+// adminUpdateRateLimitHandler changes the default rate limit policy's parameters until
+// the next reload or restart. Fields missing from the body keep their current values.
+func (router *Router) adminUpdateRateLimitHandler(w http.ResponseWriter, r *http.Request) {
+	params := router.controller.RateLimit()
+	if !decodeAdminBody(w, r, &params) {
+		return
+	}
+	if err := router.controller.SetRateLimit(params); err != nil {
+		writeControllerError(w, err)
+		return
+	}
+	logger.WithContext(r.Context(), router.logger).Info("default rate limit changed via admin API",
+		zap.Int("rps", params.RPS),
+		zap.Int("burst", params.Burst),
+		zap.Int("limit", params.Limit),
+		zap.String("window", params.Window))
+	writeAdminJSON(w, router.controller.RateLimit())
+}
+
+// decodeAdminBody decodes a JSON request body into v, rejecting unknown fields. It
+// writes a 400 response and returns false when the body is invalid.
+func decodeAdminBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", "invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// writeControllerError answers 400 for rejected values and 500 otherwise
+func writeControllerError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrInvalidSetting) {
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+}
+
+func writeAdminJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(v)
+}
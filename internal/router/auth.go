@@ -0,0 +1,107 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/shaibs3/Torq/internal/auth"
+	"github.com/shaibs3/Torq/internal/identity"
+	"github.com/shaibs3/Torq/internal/limiter"
+	"github.com/shaibs3/Torq/internal/logger"
+	"go.uber.org/zap"
+)
+
+// WithAuth requires an API key with the route's scope on every non-exempt route
+func WithAuth(authenticator *auth.Authenticator) Option {
+	return func(r *Router) {
+		r.auth = authenticator
+	}
+}
+
+// WithJWT accepts JWT bearer tokens, alongside API keys when WithAuth is also set
+func WithJWT(validator *auth.JWTValidator) Option {
+	return func(r *Router) {
+		r.jwt = validator
+	}
+}
+
+// requiredScope returns the scope a request needs, or "" for routes open to any authenticated caller
+func requiredScope(r *http.Request) auth.Scope {
+	switch {
+	case r.URL.Path == "/v1/find-country/batch":
+		return auth.ScopeBatch
+	case r.URL.Path == "/v1/find-country":
+		return auth.ScopeLookup
+	case strings.HasPrefix(r.URL.Path, "/admin/"):
+		return auth.ScopeAdmin
+	default:
+		return ""
+	}
+}
+
+// authMiddleware authenticates the caller by API key or JWT and replaces the
+// IP-based identity with the key ID or token subject. Health, metrics and docs
+// endpoints stay anonymous. Errors follow RFC 6750.
+func (router *Router) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (router.auth == nil && router.jwt == nil) || isAuthExempt(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, err := router.authenticate(r)
+		if err != nil {
+			if errors.Is(err, auth.ErrMissingKey) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="torq"`)
+				writeJSONError(w, http.StatusUnauthorized, "unauthorized", "missing API key or bearer token")
+				return
+			}
+			logger.WithContext(r.Context(), router.logger).Warn("credential rejected", zap.String("path", r.URL.Path), zap.String("remote_addr", r.RemoteAddr), zap.Error(err))
+			w.Header().Set("WWW-Authenticate", `Bearer realm="torq", error="invalid_token"`)
+			writeJSONError(w, http.StatusUnauthorized, "invalid_token", err.Error())
+			return
+		}
+
+		if scope := requiredScope(r); scope != "" && !principal.HasScope(scope) {
+			logger.WithContext(r.Context(), router.logger).Warn("caller lacks scope",
+				zap.String("client_id", principal.ID),
+				zap.String("auth_method", principal.Method),
+				zap.String("scope", string(scope)),
+				zap.String("path", r.URL.Path))
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="torq", error="insufficient_scope", scope=%q`, scope))
+			writeJSONError(w, http.StatusForbidden, "insufficient_scope", "the "+string(scope)+" scope is required")
+			return
+		}
+
+		scopes := make([]string, len(principal.Scopes))
+		for i, scope := range principal.Scopes {
+			scopes[i] = string(scope)
+		}
+		caller, ok := identity.FromContext(r.Context())
+		if !ok {
+			caller = &identity.Identity{}
+			r = r.WithContext(identity.NewContext(r.Context(), caller))
+		}
+		// Update the identity in place so outer middleware, such as the access log, sees the authenticated caller
+		caller.ID = principal.ID
+		caller.Method = principal.Method
+		caller.Scopes = scopes
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticate resolves the request's API key or bearer token
+func (router *Router) authenticate(r *http.Request) (*auth.Principal, error) {
+	return auth.Verify(auth.KeyFromRequest(r), r.Header.Get(auth.APIKeyHeader) != "", router.auth, router.jwt)
+}
+
+func isAuthExempt(r *http.Request) bool {
+	for _, path := range limiter.DefaultExemptPaths {
+		if r.URL.Path == path {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/shaibs3/Torq/internal/finder"
+	"github.com/shaibs3/Torq/internal/limiter"
+)
+
+// CostFuncs returns the cost functions that rate limit policies can refer to by name
+func CostFuncs() map[string]limiter.CostFunc {
+	return map[string]limiter.CostFunc{
+		"request":   func(*http.Request) int { return 1 },
+		"batch_ips": batchIPsCost,
+	}
+}
+
+// batchIPsCost charges one token per IP address in a batch request body. The decoded
+// body is kept in the request for the handler; malformed bodies cost a single token
+// and are rejected by the handler.
+func batchIPsCost(r *http.Request) int {
+	req, err := finder.ParseBatchRequest(r)
+	if err != nil {
+		return 1
+	}
+	return len(req.IPs)
+}
@@ -12,6 +12,7 @@ type HTTPMetrics struct {
 	ResponseStatus      metric.Int64Counter
 	ActiveRequests      metric.Int64UpDownCounter
 	RateLimitedRequests metric.Int64Counter
+	RateLimitTokens     metric.Int64Counter
 }
 
 func NewHTTPMetrics(meter metric.Meter, logger *zap.Logger) *HTTPMetrics {
@@ -69,6 +70,15 @@ func NewHTTPMetrics(meter metric.Meter, logger *zap.Logger) *HTTPMetrics {
 		logger.Error("failed to create rate limited requests metric", zap.Error(err))
 	}
 
+	rateLimitTokens, err := meter.Int64Counter(
+		"http_rate_limit_tokens_consumed_total",
+		metric.WithDescription("Total number of rate limit tokens consumed by allowed requests"),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		logger.Error("failed to create rate limit tokens metric", zap.Error(err))
+	}
+
 	return &HTTPMetrics{
 		RequestDuration:     requestDuration,
 		RequestCount:        requestCount,
@@ -76,5 +86,6 @@ func NewHTTPMetrics(meter metric.Meter, logger *zap.Logger) *HTTPMetrics {
 		ResponseStatus:      responseStatus,
 		ActiveRequests:      activeRequests,
 		RateLimitedRequests: rateLimitedRequests,
+		RateLimitTokens:     rateLimitTokens,
 	}
 }
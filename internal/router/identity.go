@@ -0,0 +1,30 @@
+package router
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/shaibs3/Torq/internal/certs"
+	"github.com/shaibs3/Torq/internal/identity"
+)
+
+// identityMiddleware attaches the caller's identity: the name in its verified TLS
+// client certificate, or else its remote IP address. When authentication is
+// enabled, authMiddleware replaces it with the API key ID or token subject.
+func (router *Router) identityMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(identity.NewContext(r.Context(), identify(r))))
+	})
+}
+
+func identify(r *http.Request) *identity.Identity {
+	if peer := certs.PeerName(r.TLS); peer != "" {
+		return &identity.Identity{ID: peer, Method: "mtls", Certificate: peer}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return &identity.Identity{ID: "ip-" + host, Method: "ip"}
+}
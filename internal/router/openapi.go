@@ -0,0 +1,23 @@
+package router
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec is the OpenAPI 3 document describing every route registered in setupRoutes
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// OpenAPISpec returns the raw OpenAPI document served at /openapi.json
+func OpenAPISpec() []byte {
+	return openAPISpec
+}
+
+// openAPIHandler serves the embedded OpenAPI document
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(openAPISpec)
+}
@@ -0,0 +1,68 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type openAPIDocument struct {
+	OpenAPI string                                `json:"openapi"`
+	Paths   map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+func TestOpenAPISpec_CoversAllRoutes(t *testing.T) {
+	var doc openAPIDocument
+	require.NoError(t, json.Unmarshal(OpenAPISpec(), &doc))
+	assert.True(t, strings.HasPrefix(doc.OpenAPI, "3."))
+
+	r := newTestRouter(t, allowAllLimiter{})
+	r.setupOpsRoutes()
+	r.setupAdminRoutes()
+	routes := 0
+	walk := func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		// The Go runtime profiler is not part of the API
+		if strings.HasPrefix(path, "/debug/pprof/") {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		require.NoError(t, err, "route %s has no methods", path)
+
+		operations, ok := doc.Paths[path]
+		if !assert.True(t, ok, "route %s is missing from openapi.json", path) {
+			return nil
+		}
+		for _, method := range methods {
+			_, ok := operations[strings.ToLower(method)]
+			assert.True(t, ok, "operation %s %s is missing from openapi.json", method, path)
+		}
+		routes++
+		return nil
+	}
+	for _, m := range []*mux.Router{r.router, r.ops, r.admin} {
+		require.NoError(t, m.Walk(walk))
+	}
+	assert.Positive(t, routes)
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	r := newTestRouter(t, allowAllLimiter{})
+
+	req := httptest.NewRequest("GET", "/openapi.json", nil)
+	w := httptest.NewRecorder()
+	r.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.True(t, json.Valid(w.Body.Bytes()))
+}
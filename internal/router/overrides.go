@@ -0,0 +1,125 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/shaibs3/Torq/internal/override"
+)
+
+const (
+	// defaultAuditLimit is the number of audit entries returned when no limit is given
+	defaultAuditLimit = 100
+	// maxAuditLimit bounds the number of audit entries returned at once
+	maxAuditLimit = 1000
+)
+
+// WithOverrides serves the admin endpoints that manage local IP overrides
+func WithOverrides(overrides *override.Provider) Option {
+	return func(r *Router) {
+		r.overrides = overrides
+	}
+}
+
+// overridesMiddleware answers 501 on the override routes when overrides are disabled
+func (router *Router) overridesMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if router.overrides == nil {
+			writeJSONError(w, http.StatusNotImplemented, "not_implemented", "overrides are disabled; set OVERRIDES_FILE")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminListOverridesHandler returns every override in creation order
+func (router *Router) adminListOverridesHandler(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, router.overrides.List())
+}
+
+// adminCreateOverrideHandler adds an override
+func (router *Router) adminCreateOverrideHandler(w http.ResponseWriter, r *http.Request) {
+	var spec override.Spec
+	if !decodeAdminBody(w, r, &spec) {
+		return
+	}
+	created, err := router.overrides.Create(r.Context(), spec)
+	if err != nil {
+		writeOverrideError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/admin/v1/overrides/"+created.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(created)
+}
+
+// adminGetOverrideHandler returns one override
+func (router *Router) adminGetOverrideHandler(w http.ResponseWriter, r *http.Request) {
+	o, err := router.overrides.Get(mux.Vars(r)["id"])
+	if err != nil {
+		writeOverrideError(w, err)
+		return
+	}
+	writeAdminJSON(w, o)
+}
+
+// adminUpdateOverrideHandler replaces an override's range, location and note
+func (router *Router) adminUpdateOverrideHandler(w http.ResponseWriter, r *http.Request) {
+	var spec override.Spec
+	if !decodeAdminBody(w, r, &spec) {
+		return
+	}
+	updated, err := router.overrides.Update(r.Context(), mux.Vars(r)["id"], spec)
+	if err != nil {
+		writeOverrideError(w, err)
+		return
+	}
+	writeAdminJSON(w, updated)
+}
+
+// adminDeleteOverrideHandler removes an override
+func (router *Router) adminDeleteOverrideHandler(w http.ResponseWriter, r *http.Request) {
+	if err := router.overrides.Delete(r.Context(), mux.Vars(r)["id"]); err != nil {
+		writeOverrideError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// adminOverrideAuditHandler returns the most recent override changes first
+func (router *Router) adminOverrideAuditHandler(w http.ResponseWriter, r *http.Request) {
+	limit := defaultAuditLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxAuditLimit {
+			writeJSONError(w, http.StatusBadRequest, "invalid_request", "limit must be between 1 and "+strconv.Itoa(maxAuditLimit))
+			return
+		}
+		limit = n
+	}
+	entries, err := router.overrides.Audit(limit)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	writeAdminJSON(w, entries)
+}
+
+// writeOverrideError maps override errors to 400, 404 and 409, and anything else,
+// such as a failure to save the file, to 500
+func writeOverrideError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, override.ErrInvalid):
+		writeJSONError(w, http.StatusBadRequest, "invalid_request", err.Error())
+	case errors.Is(err, override.ErrNotFound):
+		writeJSONError(w, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, override.ErrConflict):
+		writeJSONError(w, http.StatusConflict, "conflict", err.Error())
+	default:
+		writeJSONError(w, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+}
@@ -0,0 +1,73 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/shaibs3/Torq/internal/identity"
+	"github.com/shaibs3/Torq/internal/quota"
+	"go.uber.org/zap"
+)
+
+// quotaMiddleware charges lookups against the caller's daily and monthly quotas.
+// Batch requests cost one lookup per IP. Requests the handler rejects with a 4xx,
+// such as invalid or unknown IPs, are refunded. Exhausted quotas are rejected with
+// 403, which unlike 429 will not clear by retrying shortly.
+func (router *Router) quotaMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if router.quotas == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var cost int
+		switch r.URL.Path {
+		case "/v1/find-country":
+			cost = 1
+		case "/v1/find-country/batch":
+			cost = batchIPsCost(r)
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		caller, ok := identity.FromContext(r.Context())
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		usage, err := router.quotas.Consume(r.Context(), caller.ID, int64(cost))
+		if errors.Is(err, quota.ErrQuotaExceeded) {
+			writeJSONErrorBody(w, http.StatusForbidden, jsonError{
+				Error:       "quota_exceeded",
+				Description: "the client's lookup quota is used up until the period resets",
+				Usage:       usage,
+			})
+			return
+		}
+		if err != nil {
+			// Quota accounting must not take the API down; admit the request
+			router.logger.Error("failed to consume quota", zap.String("client_id", caller.ID), zap.Error(err))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		wrappedWriter := &ResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(wrappedWriter, r)
+		if wrappedWriter.statusCode >= 400 && wrappedWriter.statusCode < 500 {
+			if err := router.quotas.Refund(r.Context(), usage, int64(cost)); err != nil {
+				router.logger.Error("failed to refund quota", zap.String("client_id", caller.ID), zap.Error(err))
+			}
+		}
+	})
+}
+
+// usageHandler reports the caller's quota consumption
+func (router *Router) usageHandler(w http.ResponseWriter, r *http.Request) {
+	if router.quotas == nil {
+		writeJSONError(w, http.StatusNotFound, "quotas_disabled", "quotas are not enabled")
+		return
+	}
+	router.quotas.UsageHandler(w, r)
+}
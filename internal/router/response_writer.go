@@ -4,7 +4,8 @@ import "net/http"
 
 type ResponseWriter struct {
 	http.ResponseWriter
-	statusCode int
+	statusCode   int
+	bytesWritten int64
 }
 
 func (rw *ResponseWriter) WriteHeader(code int) {
@@ -14,5 +15,11 @@ func (rw *ResponseWriter) WriteHeader(code int) {
 
 func (rw *ResponseWriter) Write(b []byte) (int, error) {
 	size, err := rw.ResponseWriter.Write(b)
+	rw.bytesWritten += int64(size)
 	return size, err
 }
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *ResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
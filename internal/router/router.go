@@ -1,45 +1,85 @@
 package router
 
 import (
+	"encoding/json"
+	"fmt"
+	"math"
 	"net/http"
+	"net/http/pprof"
 	"strconv"
 	"time"
 
 	"github.com/shaibs3/Torq/internal/telemetry"
 
+	"github.com/shaibs3/Torq/internal/auth"
 	"github.com/shaibs3/Torq/internal/finder"
+	"github.com/shaibs3/Torq/internal/identity"
 	"github.com/shaibs3/Torq/internal/limiter"
+	"github.com/shaibs3/Torq/internal/override"
+	"github.com/shaibs3/Torq/internal/quota"
 	"github.com/shaibs3/Torq/internal/service_health"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 // Router handles all routing logic and middleware setup
 type Router struct {
 	router        *mux.Router
-	rateLimiter   limiter.RateLimiter
+	ops           *mux.Router
+	admin         *mux.Router
+	startedAt     time.Time
+	policies      *limiter.PolicySet
 	logger        *zap.Logger
 	routerMetrics *HTTPMetrics
+	quotas        *quota.Manager
+	auth          *auth.Authenticator
+	jwt           *auth.JWTValidator
+	controller    Controller
+	overrides     *override.Provider
+
+	tracer          trace.Tracer
+	accessLogFormat AccessLogFormat
 }
 
-// NewRouter creates a new router instance
-func NewRouter(rateLimiter limiter.RateLimiter, telemetry *telemetry.Telemetry, logger *zap.Logger) *Router {
+// Option configures optional Router features
+type Option func(*Router)
+
+// WithQuotas enforces daily/monthly quotas on lookup routes and serves /v1/usage
+func WithQuotas(quotas *quota.Manager) Option {
+	return func(r *Router) {
+		r.quotas = quotas
+	}
+}
+
+// NewRouter creates a new router instance that rate limits requests according to the given policies
+func NewRouter(policies *limiter.PolicySet, telemetry *telemetry.Telemetry, logger *zap.Logger, opts ...Option) *Router {
 	httpMetrics := NewHTTPMetrics(telemetry.Meter, logger.Named("metrics"))
 
 	r := &Router{
 		router:        mux.NewRouter(),
-		rateLimiter:   rateLimiter,
+		ops:           mux.NewRouter(),
+		admin:         mux.NewRouter(),
+		startedAt:     time.Now(),
+		policies:      policies,
 		logger:        logger.Named("router"),
 		routerMetrics: httpMetrics,
+
+		tracer:          telemetry.Tracer(),
+		accessLogFormat: AccessLogStructured,
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
 	return r
 }
 
-// CreateServer creates and configures a complete HTTP server with all routes and middleware
+// CreateServer creates and configures the public API server with all routes and middleware
 func (router *Router) CreateServer(port string, ipFinder *finder.IpFinder) *http.Server {
 	router.logger.Info("creating HTTP server", zap.String("port", port))
 
@@ -49,16 +89,51 @@ func (router *Router) CreateServer(port string, ipFinder *finder.IpFinder) *http
 	// Setup middleware
 	handler := router.setupMiddleware()
 
-	// Create server
+	return router.newServer("public", port, handler, 10*time.Second)
+}
+
+// CreateOpsServer creates the internal server for health checks, metrics and profiling.
+// It is neither authenticated nor rate limited, so it must not be exposed publicly.
+func (router *Router) CreateOpsServer(port string) *http.Server {
+	router.logger.Info("creating ops HTTP server", zap.String("port", port))
+
+	router.setupOpsRoutes()
+
+	// Apply middlewares in order: tracing -> request ID -> identity -> access log -> metrics -> router
+	metricsHandler := router.metricsMiddleware(router.ops)
+	loggedRouter := router.accessLogMiddleware(router.logger.Named("access"))(metricsHandler)
+	handler := router.tracingMiddleware(router.requestIDMiddleware(router.identityMiddleware(loggedRouter)))
+
+	// CPU profiles and traces stream for 30 seconds by default
+	return router.newServer("ops", port, handler, 60*time.Second)
+}
+
+// CreateAdminServer creates the admin API server. Every admin route requires the admin scope.
+func (router *Router) CreateAdminServer(port string) *http.Server {
+	router.logger.Info("creating admin HTTP server", zap.String("port", port))
+
+	router.setupAdminRoutes()
+
+	// Apply middlewares in order: tracing -> request ID -> identity -> access log -> admin auth -> metrics -> router
+	metricsHandler := router.metricsMiddleware(router.admin)
+	authenticatedRouter := router.adminAuthMiddleware(router.authMiddleware(metricsHandler))
+	loggedRouter := router.accessLogMiddleware(router.logger.Named("access"))(authenticatedRouter)
+	handler := router.tracingMiddleware(router.requestIDMiddleware(router.identityMiddleware(loggedRouter)))
+
+	return router.newServer("admin", port, handler, 10*time.Second)
+}
+
+func (router *Router) newServer(name, port string, handler http.Handler, writeTimeout time.Duration) *http.Server {
 	srv := &http.Server{
 		Addr:         port,
 		Handler:      handler,
 		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		WriteTimeout: writeTimeout,
 		IdleTimeout:  30 * time.Second,
 	}
 
 	router.logger.Info("server configuration",
+		zap.String("server", name),
 		zap.String("addr", srv.Addr),
 		zap.Duration("read_timeout", srv.ReadTimeout),
 		zap.Duration("write_timeout", srv.WriteTimeout),
@@ -67,117 +142,223 @@ func (router *Router) CreateServer(port string, ipFinder *finder.IpFinder) *http
 	return srv
 }
 
-// setupRoutes configures all application routes (private method)
+// setupRoutes configures the public API routes (private method)
 func (router *Router) setupRoutes(ipFinder *finder.IpFinder) {
 	router.logger.Info("setting up application routes")
 
-	// Health check endpoints
-	router.router.HandleFunc("/health/live", service_health.LivenessHandler(router.logger)).Methods("GET", "HEAD")
-	router.router.HandleFunc("/health/ready", service_health.ReadinessHandler(router.logger)).Methods("GET", "HEAD")
-
-	// Metrics endpoint
-	router.router.Handle("/metrics", promhttp.Handler()).Methods("GET")
+	// API documentation
+	router.router.HandleFunc("/openapi.json", openAPIHandler).Methods("GET")
 
 	// API endpoints
 	router.router.HandleFunc("/v1/find-country", ipFinder.FindIpHandler).Methods("GET")
+	router.router.HandleFunc("/v1/find-country/batch", ipFinder.FindIpBatchHandler).Methods("POST")
+	router.router.HandleFunc("/v1/usage", router.usageHandler).Methods("GET")
+	router.router.Use(routeSpanMiddleware)
 
 	router.logger.Info("routes configured successfully")
 }
 
-// setupMiddleware configures rate limiting and metrics middleware (private method)
+// setupOpsRoutes configures health, metrics and profiling routes (private method)
+func (router *Router) setupOpsRoutes() {
+	// Health check endpoints
+	router.ops.HandleFunc("/health/live", service_health.LivenessHandler(router.logger)).Methods("GET", "HEAD")
+	router.ops.HandleFunc("/health/ready", service_health.ReadinessHandler(router.logger)).Methods("GET", "HEAD")
+
+	// Metrics endpoint. OpenMetrics is offered so scrapers that request it receive trace exemplars.
+	router.ops.Handle("/metrics", promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}))).Methods("GET")
+
+	// Profiling endpoints
+	router.ops.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	router.ops.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	router.ops.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	router.ops.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	router.ops.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+	router.ops.Use(routeSpanMiddleware)
+}
+
+// setupAdminRoutes configures the admin API routes (private method)
+func (router *Router) setupAdminRoutes() {
+	router.admin.HandleFunc("/admin/v1/status", router.adminStatusHandler).Methods("GET")
+
+	// Inspecting and changing the instance needs a Controller
+	control := router.admin.NewRoute().Subrouter()
+	control.HandleFunc("/admin/v1/reload", router.adminReloadHandler).Methods("POST")
+	control.HandleFunc("/admin/v1/dataset", router.adminDatasetHandler).Methods("GET")
+	control.HandleFunc("/admin/v1/cache/purge", router.adminCachePurgeHandler).Methods("POST")
+	control.HandleFunc("/admin/v1/config", router.adminConfigHandler).Methods("GET")
+	control.HandleFunc("/admin/v1/log-level", router.adminGetLogLevelHandler).Methods("GET")
+	control.HandleFunc("/admin/v1/log-level", router.adminSetLogLevelHandler).Methods("PUT")
+	control.HandleFunc("/admin/v1/rate-limit", router.adminGetRateLimitHandler).Methods("GET")
+	control.HandleFunc("/admin/v1/rate-limit", router.adminUpdateRateLimitHandler).Methods("PATCH")
+	control.Use(router.controllerMiddleware)
+
+	// Overrides need OVERRIDES_FILE; the audit route is registered before {id} so it is not taken for an ID
+	overrides := router.admin.NewRoute().Subrouter()
+	overrides.HandleFunc("/admin/v1/overrides", router.adminListOverridesHandler).Methods("GET")
+	overrides.HandleFunc("/admin/v1/overrides", router.adminCreateOverrideHandler).Methods("POST")
+	overrides.HandleFunc("/admin/v1/overrides/audit", router.adminOverrideAuditHandler).Methods("GET")
+	overrides.HandleFunc("/admin/v1/overrides/{id}", router.adminGetOverrideHandler).Methods("GET")
+	overrides.HandleFunc("/admin/v1/overrides/{id}", router.adminUpdateOverrideHandler).Methods("PUT")
+	overrides.HandleFunc("/admin/v1/overrides/{id}", router.adminDeleteOverrideHandler).Methods("DELETE")
+	overrides.Use(router.overridesMiddleware)
+
+	router.admin.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSONError(w, http.StatusNotFound, "not_found", "no admin route "+r.URL.Path)
+	})
+	router.admin.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed", r.Method+" is not allowed on "+r.URL.Path)
+	})
+	router.admin.Use(routeSpanMiddleware)
+}
+
+// setupMiddleware configures the public API middleware stack (private method)
 func (router *Router) setupMiddleware() http.Handler {
 	router.logger.Info("setting up middleware")
 
-	// Apply middlewares in order: metrics -> rate limiting -> router
-	metricsHandler := router.metricsMiddleware(router.logger.Named("metrics"))(router.router)
+	// Apply middlewares in order: tracing -> request ID -> identity -> access log -> auth -> rate limiting -> metrics -> quota -> router
+	quotaHandler := router.quotaMiddleware(router.router)
+	metricsHandler := router.metricsMiddleware(quotaHandler)
 	rateLimitedRouter := router.rateLimitMiddleware(metricsHandler)
+	authenticatedRouter := router.authMiddleware(rateLimitedRouter)
+	loggedRouter := router.accessLogMiddleware(router.logger.Named("access"))(authenticatedRouter)
+	identifiedRouter := router.identityMiddleware(loggedRouter)
+	handler := router.tracingMiddleware(router.requestIDMiddleware(identifiedRouter))
 
 	router.logger.Info("middleware configured successfully")
-	return rateLimitedRouter
+	return handler
 }
 
 // MetricsMiddleware creates middleware for comprehensive HTTP metrics
-func (router *Router) metricsMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			start := time.Now()
-
-			// Increment active requests
-			if router.routerMetrics.ActiveRequests != nil {
-				router.routerMetrics.ActiveRequests.Add(r.Context(), 1)
-				defer router.routerMetrics.ActiveRequests.Add(r.Context(), -1)
-			}
+func (router *Router) metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
 
-			// Create response writer wrapper to capture status code
-			wrappedWriter := &ResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		// Increment active requests
+		if router.routerMetrics.ActiveRequests != nil {
+			router.routerMetrics.ActiveRequests.Add(r.Context(), 1)
+			defer router.routerMetrics.ActiveRequests.Add(r.Context(), -1)
+		}
 
-			// Call next handler
-			next.ServeHTTP(wrappedWriter, r)
+		// Create response writer wrapper to capture status code
+		wrappedWriter := &ResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 
-			// Record metrics
-			duration := time.Since(start)
+		// Call next handler
+		next.ServeHTTP(wrappedWriter, r)
 
-			attrs := []attribute.KeyValue{
-				attribute.String("method", r.Method),
-				attribute.String("path", r.URL.Path),
-				attribute.Int("status_code", wrappedWriter.statusCode),
-			}
+		// Record metrics
+		duration := time.Since(start)
 
-			// Record request duration
-			if router.routerMetrics.RequestDuration != nil {
-				router.routerMetrics.RequestDuration.Record(r.Context(), duration.Seconds(), metric.WithAttributes(attrs...))
-			}
+		attrs := []attribute.KeyValue{
+			attribute.String("method", r.Method),
+			attribute.String("path", r.URL.Path),
+			attribute.Int("status_code", wrappedWriter.statusCode),
+		}
 
-			// Record request count
-			if router.routerMetrics.RequestCount != nil {
-				router.routerMetrics.RequestCount.Add(r.Context(), 1, metric.WithAttributes(attrs...))
-			}
+		// Record request duration
+		if router.routerMetrics.RequestDuration != nil {
+			router.routerMetrics.RequestDuration.Record(r.Context(), duration.Seconds(), metric.WithAttributes(attrs...))
+		}
 
-			// Record error requests (4xx, 5xx status codes)
-			if router.routerMetrics.ErrorRequests != nil && (wrappedWriter.statusCode >= 400) {
-				errorAttrs := []attribute.KeyValue{
-					attribute.String("method", r.Method),
-					attribute.String("path", r.URL.Path),
-					attribute.String("status_code", strconv.Itoa(wrappedWriter.statusCode)),
-				}
-				router.routerMetrics.ErrorRequests.Add(r.Context(), 1, metric.WithAttributes(errorAttrs...))
-			}
+		// Record request count
+		if router.routerMetrics.RequestCount != nil {
+			router.routerMetrics.RequestCount.Add(r.Context(), 1, metric.WithAttributes(attrs...))
+		}
 
-			// Record response status
-			if router.routerMetrics.ResponseStatus != nil {
-				statusAttrs := []attribute.KeyValue{
-					attribute.String("status_code", strconv.Itoa(wrappedWriter.statusCode)),
-				}
-				router.routerMetrics.ResponseStatus.Add(r.Context(), 1, metric.WithAttributes(statusAttrs...))
+		// Record error requests (4xx, 5xx status codes)
+		if router.routerMetrics.ErrorRequests != nil && (wrappedWriter.statusCode >= 400) {
+			errorAttrs := []attribute.KeyValue{
+				attribute.String("method", r.Method),
+				attribute.String("path", r.URL.Path),
+				attribute.String("status_code", strconv.Itoa(wrappedWriter.statusCode)),
 			}
+			router.routerMetrics.ErrorRequests.Add(r.Context(), 1, metric.WithAttributes(errorAttrs...))
+		}
 
-			logger.Info("request completed",
-				zap.String("method", r.Method),
-				zap.String("path", r.URL.Path),
-				zap.Int("status_code", wrappedWriter.statusCode),
-				zap.Duration("duration", duration),
-				zap.String("remote_addr", r.RemoteAddr),
-			)
-		})
-	}
+		// Record response status
+		if router.routerMetrics.ResponseStatus != nil {
+			statusAttrs := []attribute.KeyValue{
+				attribute.String("status_code", strconv.Itoa(wrappedWriter.statusCode)),
+			}
+			router.routerMetrics.ResponseStatus.Add(r.Context(), 1, metric.WithAttributes(statusAttrs...))
+		}
+	})
 }
 
 func (router *Router) rateLimitMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Skip rate limiting for health check and metrics endpoints
-		// in normal app i would have created diffrent http servers listening on different ports for app logic, metrics and health endpoints
-		if r.URL.Path == "/metrics" || r.URL.Path == "/health/live" || r.URL.Path == "/health/ready" {
+		// Health check and metrics endpoints are served by the ops server; the docs endpoint is exempted by the default policy
+		policy := router.policies.Match(r)
+		if policy == nil || policy.IsExempt(r) {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		if !router.rateLimiter.Allow() {
+		var clientID string
+		if caller, ok := identity.FromContext(r.Context()); ok {
+			clientID = caller.ID
+		}
+
+		cost := policy.CostOf(r)
+		decision := policy.LimiterFor(clientID).DecideN(cost)
+		setRateLimitHeaders(w.Header(), decision)
+
+		policyAttrs := metric.WithAttributes(attribute.String("policy", policy.Name))
+		if !decision.Allowed && cost > decision.Limit {
+			// Retrying cannot help a request that costs more than the limiter ever holds
+			writeJSONError(w, http.StatusRequestEntityTooLarge, "batch_too_large",
+				fmt.Sprintf("request costs %d tokens but the %s rate limit admits at most %d at once; split it into smaller batches", cost, policy.Name, decision.Limit))
+			return
+		}
+		if !decision.Allowed {
 			if router.routerMetrics != nil && router.routerMetrics.RateLimitedRequests != nil {
-				router.routerMetrics.RateLimitedRequests.Add(r.Context(), 1)
+				router.routerMetrics.RateLimitedRequests.Add(r.Context(), 1, policyAttrs)
 			}
-			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			retryAfter := ceilSeconds(decision.RetryAfter)
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			writeJSONError(w, http.StatusTooManyRequests, "rate_limited",
+				fmt.Sprintf("the %s rate limit is exhausted; retry in %d seconds", policy.Name, retryAfter))
 			return
 		}
+
+		if router.routerMetrics != nil && router.routerMetrics.RateLimitTokens != nil {
+			router.routerMetrics.RateLimitTokens.Add(r.Context(), int64(cost), policyAttrs)
+		}
 		next.ServeHTTP(w, r)
 	})
 }
+
+// setRateLimitHeaders writes the IETF draft RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers
+func setRateLimitHeaders(h http.Header, decision limiter.Decision) {
+	h.Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.Reset)))
+}
+
+// ceilSeconds rounds a duration up to whole seconds, as required by delta-seconds header values
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
+
+// jsonError is the body of every JSON error response: a machine-readable code and a
+// description, in the RFC 6750 style, with the caller's usage when a quota is exceeded
+type jsonError struct {
+	Error       string       `json:"error"`
+	Description string       `json:"error_description"`
+	Usage       *quota.Usage `json:"usage,omitempty"`
+}
+
+// writeJSONError writes an error body with a machine-readable code and a description.
+// Authentication, rate limit, quota, admin and override errors all use it.
+func writeJSONError(w http.ResponseWriter, status int, code, description string) {
+	writeJSONErrorBody(w, status, jsonError{Error: code, Description: description})
+}
+
+func writeJSONErrorBody(w http.ResponseWriter, status int, body jsonError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,659 @@
+package router
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/shaibs3/Torq/internal/auth"
+	"github.com/shaibs3/Torq/internal/finder"
+	"github.com/shaibs3/Torq/internal/identity"
+	"github.com/shaibs3/Torq/internal/limiter"
+	"github.com/shaibs3/Torq/internal/logger"
+	"github.com/shaibs3/Torq/internal/lookup"
+	"github.com/shaibs3/Torq/internal/override"
+	"github.com/shaibs3/Torq/internal/quota"
+	"github.com/shaibs3/Torq/internal/requestid"
+	"github.com/shaibs3/Torq/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// allowAllLimiter never rejects a request
+type allowAllLimiter struct{}
+
+func (allowAllLimiter) Allow() bool { return true }
+
+func (allowAllLimiter) Decide() limiter.Decision {
+	return limiter.Decision{Allowed: true, Limit: 1, Remaining: 1}
+}
+
+func (l allowAllLimiter) DecideN(int) limiter.Decision { return l.Decide() }
+
+// emptyProvider never finds an IP
+type emptyProvider struct{}
+
+func (emptyProvider) Lookup(ctx context.Context, ip string) (string, string, error) {
+	return "", "", fmt.Errorf("IP not found")
+}
+
+func newTestRouter(t *testing.T, rl limiter.RateLimiter, extra ...*limiter.Policy) *Router {
+	t.Helper()
+	tel := &telemetry.Telemetry{Meter: noop.NewMeterProvider().Meter("test")}
+	policies := limiter.NewPolicySet(append(extra, limiter.NewDefaultPolicy(rl))...)
+	r := NewRouter(policies, tel, zap.NewNop())
+	r.setupRoutes(finder.NewIpFinder(emptyProvider{}))
+	return r
+}
+
+func TestRateLimitMiddleware_Headers(t *testing.T) {
+	now := time.Unix(0, 0)
+	rl := limiter.NewBurstRateLimiterWithTimeProvider(2, 2, zap.NewNop(), func() time.Time { return now })
+	r := newTestRouter(t, rl)
+	handler := r.setupMiddleware()
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	w := serve("/v1/find-country?ip=1.2.3.4")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Reset"))
+	assert.Empty(t, w.Header().Get("Retry-After"))
+
+	serve("/v1/find-country?ip=1.2.3.4")
+
+	w = serve("/v1/find-country?ip=1.2.3.4")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"error": "rate_limited", "error_description": "the default rate limit is exhausted; retry in 1 seconds"}`, w.Body.String())
+
+	// Exempt endpoints carry no rate limit headers
+	w = serve("/openapi.json")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+}
+
+func TestRateLimitMiddleware_Policies(t *testing.T) {
+	now := time.Unix(0, 0)
+	clock := func() time.Time { return now }
+	defaultLimiter := limiter.NewBurstRateLimiterWithTimeProvider(1, 1, zap.NewNop(), clock)
+	batchLimiter := limiter.NewBurstRateLimiterWithTimeProvider(5, 5, zap.NewNop(), clock)
+
+	batch := &limiter.Policy{
+		Name:       "batch",
+		PathPrefix: "/v1/find-country/batch",
+		Methods:    []string{"POST"},
+		Limiter:    batchLimiter,
+		Cost:       CostFuncs()["batch_ips"],
+	}
+	handler := newTestRouter(t, defaultLimiter, batch).setupMiddleware()
+
+	postBatch := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", "/v1/find-country/batch", strings.NewReader(body)))
+		return w
+	}
+
+	// Three IPs cost three of the five batch tokens and the body still reaches the handler
+	w := postBatch(`{"ips": ["1.2.3.4", "5.6.7.8", "9.9.9.9"]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Remaining"))
+	assert.Contains(t, w.Body.String(), "9.9.9.9")
+
+	w = postBatch(`{"ips": ["1.2.3.4", "5.6.7.8", "9.9.9.9"]}`)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	// A batch larger than the burst could never be admitted, so retrying is not suggested
+	w = postBatch(`{"ips": ["1.1.1.1", "2.2.2.2", "3.3.3.3", "4.4.4.4", "5.5.5.5", "6.6.6.6"]}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Empty(t, w.Header().Get("Retry-After"))
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "batch_too_large", body["error"])
+	assert.Contains(t, body["error_description"], "request costs 6 tokens but the batch rate limit admits at most 5 at once")
+
+	// The default budget is untouched by batch traffic
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/v1/find-country?ip=1.2.3.4", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+}
+
+func TestQuotaMiddleware(t *testing.T) {
+	store, err := quota.NewFileStore(filepath.Join(t.TempDir(), "quota.json"), zap.NewNop())
+	require.NoError(t, err)
+	quotas := quota.NewManager(quota.Config{Default: quota.Limits{Daily: 3}}, store, zap.NewNop())
+	defer quotas.Close() //nolint:errcheck
+
+	tel := &telemetry.Telemetry{Meter: noop.NewMeterProvider().Meter("test")}
+	r := NewRouter(limiter.NewPolicySet(limiter.NewDefaultPolicy(allowAllLimiter{})), tel, zap.NewNop(), WithQuotas(quotas))
+	r.setupRoutes(finder.NewIpFinder(emptyProvider{}))
+	handler := r.setupMiddleware()
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		req.RemoteAddr = "10.0.0.1:1234"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(httptest.NewRequest("POST", "/v1/find-country/batch", strings.NewReader(`{"ips": ["1.2.3.4", "5.6.7.8"]}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(httptest.NewRequest("POST", "/v1/find-country/batch", strings.NewReader(`{"ips": ["1.2.3.4", "5.6.7.8"]}`)))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var rejected struct {
+		Error string       `json:"error"`
+		Usage *quota.Usage `json:"usage"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rejected))
+	assert.Equal(t, "quota_exceeded", rejected.Error)
+	require.NotNil(t, rejected.Usage)
+
+	w = serve(httptest.NewRequest("GET", "/v1/find-country?ip=1.2.3.4", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code, "the last lookup still fits")
+	w = serve(httptest.NewRequest("GET", "/v1/find-country?ip=bogus", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(httptest.NewRequest("GET", "/v1/usage", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var usage quota.Usage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &usage))
+	assert.Equal(t, int64(2), usage.Periods[quota.PeriodDaily].Used, "lookups rejected with a 4xx are refunded")
+	assert.Equal(t, int64(1), usage.Periods[quota.PeriodDaily].Remaining)
+
+	// Other callers have their own quota
+	req := httptest.NewRequest("GET", "/v1/find-country?ip=1.2.3.4", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf(`{"keys": [
+		{"id": "lookup-only", "key_hash": %q, "scopes": ["lookup"], "enabled": true},
+		{"id": "disabled", "key_hash": %q, "scopes": ["lookup", "batch"], "enabled": false}
+	]}`, auth.HashKey("lookup-secret"), auth.HashKey("disabled-secret"))), 0o600))
+	store, err := auth.NewFileKeyStore(path)
+	require.NoError(t, err)
+	authenticator, err := auth.NewAuthenticator(store, zap.NewNop())
+	require.NoError(t, err)
+	defer authenticator.Close() //nolint:errcheck
+
+	// A per-client policy proves the key ID reaches the rate limiter
+	factory, err := limiter.NewFactory(limiter.FactoryConfig{}, zap.NewNop())
+	require.NoError(t, err)
+	policies, err := limiter.BuildPolicies([]limiter.PolicyConfig{
+		{Name: "lookup", PathPrefix: "/v1/find-country", RPS: 1, Burst: 1, PerClient: true},
+	}, CostFuncs(), factory, zap.NewNop())
+	require.NoError(t, err)
+
+	tel := &telemetry.Telemetry{Meter: noop.NewMeterProvider().Meter("test")}
+	r := NewRouter(limiter.NewPolicySet(append(policies, limiter.NewDefaultPolicy(allowAllLimiter{}))...), tel, zap.NewNop(), WithAuth(authenticator))
+	r.setupRoutes(finder.NewIpFinder(emptyProvider{}))
+	handler := r.setupMiddleware()
+
+	serve := func(req *http.Request, key string) *httptest.ResponseRecorder {
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(httptest.NewRequest("GET", "/openapi.json", nil), "")
+	assert.Equal(t, http.StatusOK, w.Code, "docs stay anonymous")
+
+	w = serve(httptest.NewRequest("GET", "/v1/find-country?ip=1.2.3.4", nil), "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+
+	w = serve(httptest.NewRequest("GET", "/v1/find-country?ip=1.2.3.4", nil), "disabled-secret")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "disabled")
+
+	w = serve(httptest.NewRequest("POST", "/v1/find-country/batch", strings.NewReader(`{"ips": ["1.2.3.4"]}`)), "lookup-secret")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "batch scope")
+
+	w = serve(httptest.NewRequest("GET", "/v1/find-country?ip=1.2.3.4", nil), "lookup-secret")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = serve(httptest.NewRequest("GET", "/v1/find-country?ip=1.2.3.4", nil), "lookup-secret")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "the key has its own bucket")
+}
+
+func TestAuthMiddleware_JWT(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(jwksPath, []byte(fmt.Sprintf(`{"keys": [{"kty": "OKP", "crv": "Ed25519", "kid": "k1", "x": %q}]}`,
+		base64.RawURLEncoding.EncodeToString(pub))), 0o600))
+
+	validator, err := auth.NewJWTValidator(auth.JWTConfig{JWKSFile: jwksPath, Issuer: "idp", Audience: "torq"}, zap.NewNop())
+	require.NoError(t, err)
+	defer validator.Close() //nolint:errcheck
+
+	sign := func(scope string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{
+			"iss": "idp", "aud": "torq", "sub": "svc-geo", "scope": scope,
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		token.Header["kid"] = "k1"
+		signed, err := token.SignedString(priv)
+		require.NoError(t, err)
+		return signed
+	}
+
+	tel := &telemetry.Telemetry{Meter: noop.NewMeterProvider().Meter("test")}
+	r := NewRouter(limiter.NewPolicySet(limiter.NewDefaultPolicy(allowAllLimiter{})), tel, zap.NewNop(), WithJWT(validator))
+	r.setupRoutes(finder.NewIpFinder(emptyProvider{}))
+	var caller *identity.Identity
+	handler := r.identityMiddleware(r.authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		caller, _ = identity.FromContext(req.Context())
+	})))
+
+	serve := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("/v1/find-country?ip=1.2.3.4", sign("lookup"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, caller)
+	assert.Equal(t, "svc-geo", caller.ID)
+	assert.Equal(t, "jwt", caller.Method)
+
+	w = serve("/v1/find-country/batch", sign("lookup"))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), `error="insufficient_scope"`)
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "insufficient_scope", body["error"])
+
+	w = serve("/v1/find-country?ip=1.2.3.4", sign("lookup")+"x")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "invalid_token", body["error"])
+
+	w = serve("/v1/find-country?ip=1.2.3.4", "opaque-api-key")
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "API keys are rejected when only JWT is enabled")
+}
+
+func TestOpsAndAdminServers(t *testing.T) {
+	r := newTestRouter(t, allowAllLimiter{})
+	ops := r.CreateOpsServer(":0").Handler
+	admin := r.CreateAdminServer(":0").Handler
+	public := r.setupMiddleware()
+
+	serve := func(h http.Handler, path string) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Code
+	}
+
+	for _, path := range []string{"/health/live", "/health/ready", "/metrics", "/debug/pprof/"} {
+		assert.Equal(t, http.StatusOK, serve(ops, path), path)
+		assert.Equal(t, http.StatusNotFound, serve(public, path), "%s is not public", path)
+	}
+	assert.Equal(t, http.StatusNotFound, serve(ops, "/v1/find-country?ip=1.2.3.4"))
+	assert.Equal(t, http.StatusForbidden, serve(admin, "/admin/v1/status"), "admin needs authentication to be configured")
+}
+
+func TestAdminServer_RequiresAdminScope(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf(`{"keys": [
+		{"id": "ops", "key_hash": %q, "scopes": ["admin"], "enabled": true},
+		{"id": "app", "key_hash": %q, "scopes": ["lookup"], "enabled": true}
+	]}`, auth.HashKey("admin-secret"), auth.HashKey("lookup-secret"))), 0o600))
+	store, err := auth.NewFileKeyStore(path)
+	require.NoError(t, err)
+	authenticator, err := auth.NewAuthenticator(store, zap.NewNop())
+	require.NoError(t, err)
+	defer authenticator.Close() //nolint:errcheck
+
+	tel := &telemetry.Telemetry{Meter: noop.NewMeterProvider().Meter("test")}
+	r := NewRouter(limiter.NewPolicySet(limiter.NewDefaultPolicy(allowAllLimiter{})), tel, zap.NewNop(), WithAuth(authenticator))
+	admin := r.CreateAdminServer(":0").Handler
+
+	serve := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/admin/v1/status", nil)
+		req.Header.Set(auth.APIKeyHeader, key)
+		w := httptest.NewRecorder()
+		admin.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusForbidden, serve("lookup-secret").Code)
+	w := serve("admin-secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var status AdminStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, "torq", status.Service)
+}
+
+// fakeController records the changes requested through the admin API
+type fakeController struct {
+	reloadErr error
+	level     string
+	rateLimit RateLimitParams
+}
+
+func (c *fakeController) Reload(ctx context.Context) (*ReloadResult, error) {
+	if c.reloadErr != nil {
+		return nil, c.reloadErr
+	}
+	return &ReloadResult{Applied: []string{"RPS_LIMIT"}, RestartRequired: []string{}}, nil
+}
+
+func (c *fakeController) Dataset(ctx context.Context) (lookup.Dataset, error) {
+	return lookup.Dataset{Type: lookup.DbType("csv"), Records: 3, Version: "abc123"}, nil
+}
+
+func (c *fakeController) PurgeCache() CachePurgeResult {
+	return CachePurgeResult{Enabled: true, Purged: 2}
+}
+
+func (c *fakeController) EffectiveConfig() map[string]any {
+	return map[string]any{"LOG_LEVEL": c.level, "REDIS_PASSWORD": "[REDACTED]"}
+}
+
+func (c *fakeController) LogLevel() string { return c.level }
+
+func (c *fakeController) SetLogLevel(level string) error {
+	if level != "debug" && level != "info" {
+		return fmt.Errorf("%w: unknown log level %q", ErrInvalidSetting, level)
+	}
+	c.level = level
+	return nil
+}
+
+func (c *fakeController) RateLimit() RateLimitParams { return c.rateLimit }
+
+func (c *fakeController) SetRateLimit(params RateLimitParams) error {
+	if params.RPS <= 0 {
+		return fmt.Errorf("%w: rps must be positive", ErrInvalidSetting)
+	}
+	c.rateLimit = params
+	return nil
+}
+
+// newAdminTestServer returns the admin handler of a router authenticating the
+// "admin-secret" API key
+func newAdminTestServer(t *testing.T, opts ...Option) http.Handler {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf(`{"keys": [
+		{"id": "ops", "key_hash": %q, "scopes": ["admin"], "enabled": true}
+	]}`, auth.HashKey("admin-secret"))), 0o600))
+	store, err := auth.NewFileKeyStore(path)
+	require.NoError(t, err)
+	authenticator, err := auth.NewAuthenticator(store, zap.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = authenticator.Close() })
+
+	tel := &telemetry.Telemetry{Meter: noop.NewMeterProvider().Meter("test")}
+	r := NewRouter(limiter.NewPolicySet(limiter.NewDefaultPolicy(allowAllLimiter{})), tel, zap.NewNop(),
+		append([]Option{WithAuth(authenticator)}, opts...)...)
+	return r.CreateAdminServer(":0").Handler
+}
+
+func serveAdmin(h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(auth.APIKeyHeader, "admin-secret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminReload(t *testing.T) {
+	controller := &fakeController{}
+	admin := newAdminTestServer(t, WithController(controller))
+
+	w := serveAdmin(admin, "POST", "/admin/v1/reload", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var result ReloadResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, []string{"RPS_LIMIT"}, result.Applied)
+
+	controller.reloadErr = fmt.Errorf("invalid configuration")
+	w = serveAdmin(admin, "POST", "/admin/v1/reload", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{"error": "reload_failed", "error_description": "invalid configuration"}`, w.Body.String())
+}
+
+func TestAdminController(t *testing.T) {
+	controller := &fakeController{
+		level:     "info",
+		rateLimit: RateLimitParams{Algorithm: "token_bucket", RPS: 10, Burst: 10, Limit: 10, Window: "1s"},
+	}
+	admin := newAdminTestServer(t, WithController(controller))
+
+	w := serveAdmin(admin, "GET", "/admin/v1/dataset", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"type": "csv", "records": 3, "version": "abc123", "loaded_at": "0001-01-01T00:00:00Z"}`, w.Body.String())
+
+	w = serveAdmin(admin, "POST", "/admin/v1/cache/purge", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"enabled": true, "purged": 2}`, w.Body.String())
+
+	w = serveAdmin(admin, "GET", "/admin/v1/config", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"LOG_LEVEL": "info", "REDIS_PASSWORD": "[REDACTED]"}`, w.Body.String())
+
+	// Log level
+	w = serveAdmin(admin, "PUT", "/admin/v1/log-level", `{"level": "debug"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"level": "debug"}`, w.Body.String())
+	w = serveAdmin(admin, "GET", "/admin/v1/log-level", "")
+	assert.JSONEq(t, `{"level": "debug"}`, w.Body.String())
+	w = serveAdmin(admin, "PUT", "/admin/v1/log-level", `{"level": "loud"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serveAdmin(admin, "PUT", "/admin/v1/log-level", `{"lvl": "debug"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "unknown fields are rejected")
+	assert.Equal(t, "debug", controller.level)
+
+	// Rate limit; fields missing from the body keep their values
+	w = serveAdmin(admin, "PATCH", "/admin/v1/rate-limit", `{"rps": 20, "burst": 40}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"algorithm": "token_bucket", "rps": 20, "burst": 40, "limit": 10, "window": "1s"}`, w.Body.String())
+	w = serveAdmin(admin, "PATCH", "/admin/v1/rate-limit", `{"rps": 0}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serveAdmin(admin, "GET", "/admin/v1/rate-limit", "")
+	assert.JSONEq(t, `{"algorithm": "token_bucket", "rps": 20, "burst": 40, "limit": 10, "window": "1s"}`, w.Body.String())
+}
+
+func TestAdminController_NotConfigured(t *testing.T) {
+	admin := newAdminTestServer(t)
+
+	for _, path := range []string{"/admin/v1/dataset", "/admin/v1/config", "/admin/v1/log-level", "/admin/v1/rate-limit"} {
+		w := serveAdmin(admin, "GET", path, "")
+		assert.Equal(t, http.StatusNotImplemented, w.Code, path)
+	}
+	assert.Equal(t, http.StatusOK, serveAdmin(admin, "GET", "/admin/v1/status", "").Code)
+}
+
+func TestAdminServer_UnknownRoutes(t *testing.T) {
+	admin := newAdminTestServer(t)
+
+	for _, tt := range []struct {
+		method, path string
+		status       int
+		code         string
+	}{
+		{method: "GET", path: "/admin/v1/nope", status: http.StatusNotFound, code: "not_found"},
+		{method: "DELETE", path: "/admin/v1/status", status: http.StatusMethodNotAllowed, code: "method_not_allowed"},
+	} {
+		w := serveAdmin(admin, tt.method, tt.path, "")
+		assert.Equal(t, tt.status, w.Code, tt.path)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		var body map[string]string
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, tt.code, body["error"])
+	}
+}
+
+func TestAdminOverrides(t *testing.T) {
+	overrides, err := override.NewProvider(emptyProvider{}, filepath.Join(t.TempDir(), "overrides.json"), "", zap.NewNop())
+	require.NoError(t, err)
+	admin := newAdminTestServer(t, WithOverrides(overrides))
+
+	w := serveAdmin(admin, "POST", "/admin/v1/overrides", `{"cidr": "203.0.113.0/24", "city": "Berlin", "country": "Germany"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created override.Override
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "/admin/v1/overrides/"+created.ID, w.Header().Get("Location"))
+
+	w = serveAdmin(admin, "POST", "/admin/v1/overrides", `{"cidr": "203.0.113.0/24", "country": "France"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = serveAdmin(admin, "POST", "/admin/v1/overrides", `{"cidr": "203.0.113.0/99", "country": "France"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveAdmin(admin, "PUT", "/admin/v1/overrides/"+created.ID, `{"cidr": "203.0.113.0/24", "city": "Munich", "country": "Germany"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = serveAdmin(admin, "GET", "/admin/v1/overrides/"+created.ID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"city":"Munich"`)
+
+	w = serveAdmin(admin, "GET", "/admin/v1/overrides", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var list []override.Override
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Len(t, list, 1)
+
+	assert.Equal(t, http.StatusNoContent, serveAdmin(admin, "DELETE", "/admin/v1/overrides/"+created.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, serveAdmin(admin, "GET", "/admin/v1/overrides/"+created.ID, "").Code)
+
+	// The audit trail names the API key that made each change
+	w = serveAdmin(admin, "GET", "/admin/v1/overrides/audit?limit=2", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var entries []override.AuditEntry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	require.Len(t, entries, 2)
+	assert.Equal(t, override.ActionDelete, entries[0].Action)
+	assert.Equal(t, override.ActionUpdate, entries[1].Action)
+	assert.Equal(t, "ops", entries[0].Actor)
+	assert.Equal(t, http.StatusBadRequest, serveAdmin(admin, "GET", "/admin/v1/overrides/audit?limit=0", "").Code)
+
+	// Without overrides the routes answer 501
+	assert.Equal(t, http.StatusNotImplemented, serveAdmin(newAdminTestServer(t), "GET", "/admin/v1/overrides", "").Code)
+}
+
+// loggingProvider logs every lookup through the request-scoped logger
+type loggingProvider struct {
+	logger *zap.Logger
+}
+
+func (p loggingProvider) Lookup(ctx context.Context, ip string) (string, string, error) {
+	logger.WithContext(ctx, p.logger).Info("looking up IP", zap.String("ip", ip))
+	return "Paris", "France", nil
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	handler := newTestRouter(t, allowAllLimiter{}).setupMiddleware()
+
+	serve := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/v1/find-country?ip=1.2.3.4", nil)
+		if id != "" {
+			req.Header.Set(requestid.Header, id)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("")
+	assert.Len(t, w.Header().Get(requestid.Header), 32, "a request ID is generated")
+	assert.NotEqual(t, w.Header().Get(requestid.Header), serve("").Header().Get(requestid.Header))
+
+	w = serve("client-trace-42")
+	assert.Equal(t, "client-trace-42", w.Header().Get(requestid.Header), "a well-formed ID is echoed")
+
+	w = serve("bad id\twith spaces")
+	assert.Len(t, w.Header().Get(requestid.Header), 32, "a malformed ID is replaced")
+}
+
+func TestAccessLog(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	tel := &telemetry.Telemetry{Meter: noop.NewMeterProvider().Meter("test")}
+	policies := limiter.NewPolicySet(limiter.NewDefaultPolicy(allowAllLimiter{}))
+	r := NewRouter(policies, tel, zap.New(core))
+	r.setupRoutes(finder.NewIpFinder(loggingProvider{logger: zap.New(core).Named("provider")}))
+	handler := r.setupMiddleware()
+
+	req := httptest.NewRequest("GET", "/v1/find-country?ip=1.2.3.4", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set(requestid.Header, "req-1")
+	req.Header.Set("User-Agent", "torq-test/1.0")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	lookups := logs.FilterMessage("looking up IP").All()
+	require.Len(t, lookups, 1)
+	assert.Equal(t, "req-1", lookups[0].ContextMap()["request_id"], "providers log through the request-scoped logger")
+
+	entries := logs.Filter(isAccessLog).All()
+	require.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "request completed", entries[0].Message)
+	assert.Equal(t, "req-1", fields["request_id"])
+	assert.Equal(t, int64(http.StatusOK), fields["status_code"])
+	assert.Equal(t, int64(w.Body.Len()), fields["bytes"])
+	assert.Equal(t, "torq-test/1.0", fields["user_agent"])
+	assert.Equal(t, "ip-10.0.0.1", fields["client_id"])
+	assert.Equal(t, "ip", fields["auth_method"])
+}
+
+func TestAccessLog_Formats(t *testing.T) {
+	serve := func(format AccessLogFormat) *observer.ObservedLogs {
+		core, logs := observer.New(zap.InfoLevel)
+		tel := &telemetry.Telemetry{Meter: noop.NewMeterProvider().Meter("test")}
+		policies := limiter.NewPolicySet(limiter.NewDefaultPolicy(allowAllLimiter{}))
+		r := NewRouter(policies, tel, zap.New(core), WithAccessLog(format))
+		r.setupRoutes(finder.NewIpFinder(emptyProvider{}))
+
+		req := httptest.NewRequest("GET", "/v1/find-country?ip=1.2.3.4", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("User-Agent", "torq-test/1.0")
+		r.setupMiddleware().ServeHTTP(httptest.NewRecorder(), req)
+		return logs.Filter(isAccessLog)
+	}
+
+	entries := serve(AccessLogCombined).All()
+	require.Len(t, entries, 1)
+	assert.Regexp(t, `^10\.0\.0\.1 - ip-10\.0\.0\.1 \[[^]]+\] "GET /v1/find-country\?ip=1\.2\.3\.4 HTTP/1\.1" 404 \d+ "" "torq-test/1\.0"$`, entries[0].Message)
+
+	assert.Zero(t, serve(AccessLogOff).Len())
+}
+
+func isAccessLog(entry observer.LoggedEntry) bool {
+	return entry.LoggerName == "router.access"
+}
@@ -0,0 +1,88 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shaibs3/Torq/internal/finder"
+	"github.com/shaibs3/Torq/internal/limiter"
+	"github.com/shaibs3/Torq/internal/lookup"
+	"github.com/shaibs3/Torq/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/exemplar"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)).Tracer("test")
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader), sdkmetric.WithExemplarFilter(exemplar.TraceBasedFilter)).Meter("test")
+	core, logs := observer.New(zap.DebugLevel)
+
+	tel := &telemetry.Telemetry{Meter: meter}
+	r := NewRouter(limiter.NewPolicySet(limiter.NewDefaultPolicy(allowAllLimiter{})), tel, zap.New(core))
+	r.tracer = tracer
+	provider := lookup.NewTracedProvider(loggingProvider{logger: zap.New(core)}, lookup.DbTypeCSV, tracer)
+	r.setupRoutes(finder.NewIpFinder(provider))
+	handler := r.setupMiddleware()
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest("GET", "/v1/find-country?ip=1.2.3.4", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 2)
+	lookupSpan, serverSpan := ended[0], ended[1]
+
+	assert.Equal(t, "GET /v1/find-country", serverSpan.Name())
+	assert.Equal(t, traceID, serverSpan.SpanContext().TraceID().String(), "the caller's trace is continued")
+	assert.Equal(t, "00f067aa0ba902b7", serverSpan.Parent().SpanID().String())
+	assert.Contains(t, serverSpan.Attributes(), attribute.Int("http.response.status_code", http.StatusOK))
+	assert.Contains(t, serverSpan.Attributes(), attribute.String("http.route", "/v1/find-country"))
+
+	assert.Equal(t, "DbProvider.Lookup", lookupSpan.Name())
+	assert.Equal(t, serverSpan.SpanContext().SpanID(), lookupSpan.Parent().SpanID())
+	assert.Contains(t, lookupSpan.Attributes(), attribute.Bool("torq.lookup.found", true))
+
+	lookups := logs.FilterMessage("looking up IP").All()
+	require.Len(t, lookups, 1)
+	assert.Equal(t, traceID, lookups[0].ContextMap()["trace_id"], "log lines carry the trace ID")
+	accessLogs := logs.Filter(isAccessLog).All()
+	require.Len(t, accessLogs, 1)
+	assert.Equal(t, traceID, accessLogs[0].ContextMap()["trace_id"])
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	var exemplars []metricdata.Exemplar[float64]
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if hist, ok := m.Data.(metricdata.Histogram[float64]); ok && m.Name == "http_request_duration_seconds" {
+				for _, dp := range hist.DataPoints {
+					exemplars = append(exemplars, dp.Exemplars...)
+				}
+			}
+		}
+	}
+	require.NotEmpty(t, exemplars, "latency measurements carry the trace as an exemplar")
+	assert.Equal(t, traceID, exemplarTraceID(exemplars[0]))
+}
+
+func exemplarTraceID(e metricdata.Exemplar[float64]) string {
+	var id trace.TraceID
+	copy(id[:], e.TraceID)
+	return id.String()
+}
@@ -0,0 +1,130 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/prometheus"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/exemplar"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// DefaultMetricsInterval is how often metrics are pushed to an OTLP collector
+const DefaultMetricsInterval = 60 * time.Second
+
+// MetricsExporter selects how metrics leave the process
+type MetricsExporter string
+
+const (
+	// MetricsExporterPrometheus serves metrics for scraping on the ops listener's /metrics
+	MetricsExporterPrometheus MetricsExporter = "prometheus"
+	// MetricsExporterOTLPGRPC pushes metrics to an OTLP collector over gRPC (default localhost:4317)
+	MetricsExporterOTLPGRPC MetricsExporter = "otlp-grpc"
+	// MetricsExporterOTLPHTTP pushes metrics to an OTLP collector over HTTP (default localhost:4318)
+	MetricsExporterOTLPHTTP MetricsExporter = "otlp-http"
+)
+
+// IsValid checks if the metrics exporter is supported
+func (e MetricsExporter) IsValid() bool {
+	switch e {
+	case MetricsExporterPrometheus, MetricsExporterOTLPGRPC, MetricsExporterOTLPHTTP:
+		return true
+	default:
+		return false
+	}
+}
+
+// MetricsConfig configures metric export. The OTLP exporters also honour the standard
+// OTEL_EXPORTER_OTLP_* environment variables for headers, TLS and timeouts.
+type MetricsConfig struct {
+	// Exporters lists every enabled exporter; empty disables metric export
+	Exporters []MetricsExporter
+	// Endpoint is the OTLP collector's host:port; empty uses the exporter's default
+	Endpoint string
+	// Insecure disables TLS to the OTLP collector
+	Insecure bool
+	// Interval is how often metrics are pushed to the OTLP collector
+	Interval time.Duration
+}
+
+// ParseMetricsExporters parses a comma-separated exporter list such as "prometheus,otlp-grpc".
+// "none" or an empty list disables metric export.
+func ParseMetricsExporters(list string) ([]MetricsExporter, error) {
+	var exporters []MetricsExporter
+	seen := make(map[MetricsExporter]bool)
+	for _, name := range strings.Split(list, ",") {
+		exporter := MetricsExporter(strings.TrimSpace(name))
+		if exporter == "" || exporter == "none" || seen[exporter] {
+			continue
+		}
+		if !exporter.IsValid() {
+			return nil, fmt.Errorf("unsupported metrics exporter: %s", exporter)
+		}
+		seen[exporter] = true
+		exporters = append(exporters, exporter)
+	}
+	return exporters, nil
+}
+
+// newMeterProvider creates a meter provider with a reader per configured exporter.
+// Measurements taken inside a sampled span carry its trace ID as an exemplar.
+func newMeterProvider(ctx context.Context, config MetricsConfig, res *resource.Resource) (*sdkmetric.MeterProvider, error) {
+	interval := config.Interval
+	if interval <= 0 {
+		interval = DefaultMetricsInterval
+	}
+
+	opts := []sdkmetric.Option{
+		sdkmetric.WithResource(res),
+		sdkmetric.WithExemplarFilter(exemplar.TraceBasedFilter),
+	}
+	for _, exporter := range config.Exporters {
+		var reader sdkmetric.Reader
+		switch exporter {
+		case MetricsExporterPrometheus:
+			// Instrument names already carry their unit and _total suffix, so the
+			// exporter must not append them again
+			promExporter, err := prometheus.New(prometheus.WithoutUnits(), prometheus.WithoutCounterSuffixes())
+			if err != nil {
+				return nil, fmt.Errorf("failed to create prometheus metrics exporter: %w", err)
+			}
+			reader = promExporter
+		case MetricsExporterOTLPGRPC:
+			var grpcOpts []otlpmetricgrpc.Option
+			if config.Endpoint != "" {
+				grpcOpts = append(grpcOpts, otlpmetricgrpc.WithEndpoint(config.Endpoint))
+			}
+			if config.Insecure {
+				grpcOpts = append(grpcOpts, otlpmetricgrpc.WithInsecure())
+			}
+			otlpExporter, err := otlpmetricgrpc.New(ctx, grpcOpts...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create %s metrics exporter: %w", exporter, err)
+			}
+			reader = sdkmetric.NewPeriodicReader(otlpExporter, sdkmetric.WithInterval(interval))
+		case MetricsExporterOTLPHTTP:
+			var httpOpts []otlpmetrichttp.Option
+			if config.Endpoint != "" {
+				httpOpts = append(httpOpts, otlpmetrichttp.WithEndpoint(config.Endpoint))
+			}
+			if config.Insecure {
+				httpOpts = append(httpOpts, otlpmetrichttp.WithInsecure())
+			}
+			otlpExporter, err := otlpmetrichttp.New(ctx, httpOpts...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create %s metrics exporter: %w", exporter, err)
+			}
+			reader = sdkmetric.NewPeriodicReader(otlpExporter, sdkmetric.WithInterval(interval))
+		default:
+			return nil, fmt.Errorf("unsupported metrics exporter: %s", exporter)
+		}
+		opts = append(opts, sdkmetric.WithReader(reader))
+	}
+
+	return sdkmetric.NewMeterProvider(opts...), nil
+}
@@ -0,0 +1,53 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// BuildInfo identifies the running binary; it is set from the linker flags in cmd/main.go
+type BuildInfo struct {
+	Version string
+	Commit  string
+}
+
+// newResource describes this process to metric and trace backends. Attributes from
+// OTEL_RESOURCE_ATTRIBUTES and OTEL_SERVICE_NAME take precedence.
+func newResource(ctx context.Context, config Config) (*resource.Resource, error) {
+	instanceID := config.InstanceID
+	if instanceID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine service instance ID: %w", err)
+		}
+		instanceID = hostname
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("service.name", serviceName),
+		attribute.String("service.instance.id", instanceID),
+	}
+	if config.Build.Version != "" {
+		attrs = append(attrs, attribute.String("service.version", config.Build.Version))
+	}
+	if config.Build.Commit != "" {
+		attrs = append(attrs, attribute.String("vcs.ref.head.revision", config.Build.Commit))
+	}
+	if config.Environment != "" {
+		attrs = append(attrs, attribute.String("deployment.environment.name", config.Environment))
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(attrs...),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build telemetry resource: %w", err)
+	}
+	return res, nil
+}
@@ -1,42 +1,108 @@
 package telemetry
 
 import (
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/prometheus"
+	"context"
+	"errors"
+
 	"go.opentelemetry.io/otel/metric"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 	"go.uber.org/zap"
 )
 
-// Telemetry handles OpenTelemetry initialization and metrics
+// serviceName identifies Torq in metrics and traces
+const serviceName = "torq"
+
+// Config configures metric and span export and the resource that describes this process
+type Config struct {
+	Build       BuildInfo
+	Environment string
+	// InstanceID distinguishes replicas; it defaults to the hostname
+	InstanceID string
+	Metrics    MetricsConfig
+	Tracing    TracingConfig
+}
+
+// Telemetry handles OpenTelemetry initialization, metrics and tracing
 type Telemetry struct {
-	Meter  metric.Meter
-	logger *zap.Logger
+	Meter          metric.Meter
+	meterProvider  *sdkmetric.MeterProvider
+	tracer         trace.Tracer
+	tracerProvider *sdktrace.TracerProvider
+	logger         *zap.Logger
 }
 
-// New initializes OpenTelemetry with Prometheus exporter
-func NewTelemetry(logger *zap.Logger) (*Telemetry, error) {
+// NewTelemetry initializes OpenTelemetry with the configured metric and trace exporters.
+// The providers are owned by the returned Telemetry and are not installed globally.
+func NewTelemetry(logger *zap.Logger, config Config) (*Telemetry, error) {
 	logger = logger.Named("telemetry")
+	ctx := context.Background()
 
-	// Initialize Prometheus exporter
-	exporter, err := prometheus.New()
+	res, err := newResource(ctx, config)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create meter provider
-	provider := sdkmetric.NewMeterProvider(
-		sdkmetric.WithReader(exporter),
-	)
-	otel.SetMeterProvider(provider)
+	meterProvider, err := newMeterProvider(ctx, config.Metrics, res)
+	if err != nil {
+		return nil, err
+	}
+
+	exporters := make([]string, len(config.Metrics.Exporters))
+	for i, exporter := range config.Metrics.Exporters {
+		exporters[i] = string(exporter)
+	}
+	logger.Info("OpenTelemetry metrics initialized",
+		zap.Strings("exporters", exporters),
+		zap.String("endpoint", config.Metrics.Endpoint),
+		zap.Duration("interval", config.Metrics.Interval))
+
+	tel := &Telemetry{
+		Meter:         meterProvider.Meter(serviceName),
+		meterProvider: meterProvider,
+		logger:        logger,
+	}
 
-	logger.Info("OpenTelemetry metrics initialized with Prometheus exporter")
+	if config.Tracing.Enabled() {
+		tracerProvider, err := newTracerProvider(ctx, config.Tracing, res)
+		if err != nil {
+			_ = meterProvider.Shutdown(ctx)
+			return nil, err
+		}
+		tel.tracerProvider = tracerProvider
+		tel.tracer = tracerProvider.Tracer(serviceName)
+		logger.Info("OpenTelemetry tracing initialized",
+			zap.String("exporter", string(config.Tracing.Exporter)),
+			zap.String("endpoint", config.Tracing.Endpoint),
+			zap.Float64("sample_ratio", config.Tracing.SampleRatio))
+	}
 
-	// Initialize HTTP metrics
-	meter := otel.GetMeterProvider().Meter("torq")
+	return tel, nil
+}
 
-	return &Telemetry{
-		Meter:  meter,
-		logger: logger,
-	}, nil
+// Tracer returns the tracer for creating spans. It never returns nil: without a trace
+// exporter the tracer is a no-op that still propagates incoming trace context.
+func (t *Telemetry) Tracer() trace.Tracer {
+	if t == nil || t.tracer == nil {
+		return noop.NewTracerProvider().Tracer(serviceName)
+	}
+	return t.tracer
+}
+
+// Shutdown pushes buffered metrics and spans to their exporters and stops the providers
+func (t *Telemetry) Shutdown(ctx context.Context) error {
+	var errs []error
+	if t.meterProvider != nil {
+		if err := t.meterProvider.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if t.tracerProvider != nil {
+		if err := t.tracerProvider.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
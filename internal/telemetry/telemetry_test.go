@@ -0,0 +1,81 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+func TestParseMetricsExporters(t *testing.T) {
+	exporters, err := ParseMetricsExporters("prometheus, otlp-grpc,prometheus")
+	require.NoError(t, err)
+	assert.Equal(t, []MetricsExporter{MetricsExporterPrometheus, MetricsExporterOTLPGRPC}, exporters)
+
+	exporters, err = ParseMetricsExporters("none")
+	require.NoError(t, err)
+	assert.Empty(t, exporters)
+
+	_, err = ParseMetricsExporters("prometheus,statsd")
+	assert.ErrorContains(t, err, "statsd")
+}
+
+func TestNewResource(t *testing.T) {
+	res, err := newResource(context.Background(), Config{
+		Build:       BuildInfo{Version: "1.2.3", Commit: "abc123"},
+		Environment: "staging",
+		InstanceID:  "torq-0",
+	})
+	require.NoError(t, err)
+
+	attrs := res.Set()
+	for key, want := range map[attribute.Key]string{
+		"service.name":                "torq",
+		"service.version":             "1.2.3",
+		"vcs.ref.head.revision":       "abc123",
+		"deployment.environment.name": "staging",
+		"service.instance.id":         "torq-0",
+	} {
+		got, ok := attrs.Value(key)
+		assert.True(t, ok, key)
+		assert.Equal(t, want, got.AsString(), key)
+	}
+}
+
+func TestNewTelemetry_OTLPPushFlushesOnShutdown(t *testing.T) {
+	var pushes atomic.Int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/metrics" {
+			pushes.Add(1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	tel, err := NewTelemetry(zap.NewNop(), Config{
+		InstanceID: "test",
+		Metrics: MetricsConfig{
+			Exporters: []MetricsExporter{MetricsExporterOTLPHTTP},
+			Endpoint:  strings.TrimPrefix(collector.URL, "http://"),
+			Insecure:  true,
+			Interval:  time.Hour,
+		},
+	})
+	require.NoError(t, err)
+
+	counter, err := tel.Meter.Int64Counter("test_total")
+	require.NoError(t, err)
+	counter.Add(context.Background(), 1)
+	assert.Zero(t, pushes.Load(), "nothing is pushed before the interval elapses")
+
+	require.NoError(t, tel.Shutdown(context.Background()))
+	assert.Equal(t, int32(1), pushes.Load(), "shutdown pushes buffered metrics")
+}
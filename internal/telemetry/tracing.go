@@ -0,0 +1,99 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// TraceExporter selects where spans are sent
+type TraceExporter string
+
+const (
+	// TraceExporterNone disables span export. Incoming trace context is still propagated to logs.
+	TraceExporterNone TraceExporter = "none"
+	// TraceExporterOTLPGRPC sends spans to an OTLP collector over gRPC (default localhost:4317)
+	TraceExporterOTLPGRPC TraceExporter = "otlp-grpc"
+	// TraceExporterOTLPHTTP sends spans to an OTLP collector over HTTP (default localhost:4318)
+	TraceExporterOTLPHTTP TraceExporter = "otlp-http"
+	// TraceExporterStdout writes spans to stdout as JSON, for local debugging
+	TraceExporterStdout TraceExporter = "stdout"
+)
+
+// IsValid checks if the trace exporter is supported
+func (e TraceExporter) IsValid() bool {
+	switch e {
+	case TraceExporterNone, TraceExporterOTLPGRPC, TraceExporterOTLPHTTP, TraceExporterStdout:
+		return true
+	default:
+		return false
+	}
+}
+
+// TracingConfig configures span export. The OTLP exporters also honour the standard
+// OTEL_EXPORTER_OTLP_* environment variables for headers, TLS and timeouts.
+type TracingConfig struct {
+	Exporter TraceExporter
+	// Endpoint is the collector's host:port; empty uses the exporter's default
+	Endpoint string
+	// Insecure disables TLS to the collector
+	Insecure bool
+	// SampleRatio is the fraction of new traces that are sampled. Requests with an
+	// incoming traceparent follow the caller's sampling decision.
+	SampleRatio float64
+}
+
+// Enabled reports whether spans are exported
+func (c TracingConfig) Enabled() bool {
+	return c.Exporter != "" && c.Exporter != TraceExporterNone
+}
+
+// newTracerProvider creates a tracer provider that batches spans to the configured exporter
+func newTracerProvider(ctx context.Context, config TracingConfig, res *resource.Resource) (*sdktrace.TracerProvider, error) {
+	if !config.Exporter.IsValid() {
+		return nil, fmt.Errorf("unsupported trace exporter: %s", config.Exporter)
+	}
+	if config.SampleRatio < 0 || config.SampleRatio > 1 {
+		return nil, fmt.Errorf("trace sample ratio must be between 0 and 1, got %v", config.SampleRatio)
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch config.Exporter {
+	case TraceExporterOTLPGRPC:
+		var opts []otlptracegrpc.Option
+		if config.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, opts...)
+	case TraceExporterOTLPHTTP:
+		var opts []otlptracehttp.Option
+		if config.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	case TraceExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", config.Exporter, err)
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	), nil
+}
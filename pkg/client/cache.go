@@ -0,0 +1,66 @@
+package client
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// cache keeps recent locations in memory. Once size entries are cached the least
+// recently used one is evicted, and entries expire after ttl. Addresses that were not
+// found are not cached, so new data is found as soon as the server loads it.
+type cache struct {
+	size    int
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // most recently used first
+}
+
+type cacheEntry struct {
+	ip       string
+	location Location
+	expires  time.Time
+}
+
+func newCache(size int, ttl time.Duration) *cache {
+	return &cache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+func (c *cache) get(ip string, now time.Time) (Location, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[ip]
+	if !ok {
+		return Location{}, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if now.After(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, ip)
+		return Location{}, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.location, true
+}
+
+func (c *cache) put(ip string, location Location, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[ip]; ok {
+		elem.Value = &cacheEntry{ip: ip, location: location, expires: now.Add(c.ttl)}
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[ip] = c.order.PushFront(&cacheEntry{ip: ip, location: location, expires: now.Add(c.ttl)})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).ip)
+	}
+}
@@ -0,0 +1,240 @@
+// Package client is a Go client for the Torq IP geolocation API. It looks up single
+// addresses and batches, retries rate-limited and unavailable responses honouring
+// Retry-After, optionally caches locations, and reports rejected requests as *Error.
+//
+//	c, err := client.New("https://torq.internal", client.WithAPIKey(key), client.WithCache(10000, time.Hour))
+//	loc, err := c.Lookup(ctx, "1.2.3.4")
+//	if errors.Is(err, client.ErrNotFound) {
+//		...
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout bounds each attempt of a request made with the default HTTP client
+const DefaultTimeout = 10 * time.Second
+
+// Client calls the Torq API. It is safe for concurrent use.
+type Client struct {
+	baseURL      *url.URL
+	httpClient   *http.Client
+	header       http.Header
+	retry        RetryPolicy
+	cache        *cache
+	timeProvider func() time.Time
+	sleep        func(ctx context.Context, d time.Duration) error
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sends requests through httpClient, e.g. for custom TLS or proxies
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAPIKey authenticates requests with an API key in the X-API-Key header
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.header.Set("X-API-Key", key)
+	}
+}
+
+// WithBearerToken authenticates requests with a JWT in the Authorization header
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// WithUserAgent replaces the default User-Agent header
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.header.Set("User-Agent", userAgent)
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithCache keeps up to size locations in memory for ttl. Addresses that were not found
+// are not cached. A size or ttl of zero disables the cache, which is the default.
+func WithCache(size int, ttl time.Duration) Option {
+	return func(c *Client) {
+		c.cache = nil
+		if size > 0 && ttl > 0 {
+			c.cache = newCache(size, ttl)
+		}
+	}
+}
+
+// New creates a client of the API served at baseURL, e.g. https://torq.internal
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: must be an absolute http or https URL", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawQuery, u.Fragment = "", ""
+
+	c := &Client{
+		baseURL:      u,
+		httpClient:   &http.Client{Timeout: DefaultTimeout},
+		header:       http.Header{"User-Agent": {"torq-go-client"}, "Accept": {"application/json"}},
+		retry:        DefaultRetryPolicy,
+		timeProvider: time.Now,
+		sleep:        sleepContext,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Lookup locates ip. An address that is not in the dataset returns an error matching
+// ErrNotFound, and an invalid one an error matching ErrInvalidRequest.
+func (c *Client) Lookup(ctx context.Context, ip string) (*Location, error) {
+	if c.cache != nil {
+		if location, ok := c.cache.get(ip, c.timeProvider()); ok {
+			return &location, nil
+		}
+	}
+
+	var location Location
+	query := url.Values{"ip": {ip}}
+	if err := c.do(ctx, http.MethodGet, "/v1/find-country", query, nil, &location); err != nil {
+		return nil, err
+	}
+	if c.cache != nil {
+		c.cache.put(ip, location, c.timeProvider())
+	}
+	return &location, nil
+}
+
+// LookupBatch locates ips, returning one result per address in the same order. Lists
+// longer than MaxBatchSize are sent in several requests; if one fails, the error is
+// returned without results.
+func (c *Client) LookupBatch(ctx context.Context, ips []string) ([]BatchResult, error) {
+	results := make([]BatchResult, len(ips))
+	// misses are the indexes of the addresses to ask the server for
+	misses := make([]int, 0, len(ips))
+	for i, ip := range ips {
+		if c.cache != nil {
+			if location, ok := c.cache.get(ip, c.timeProvider()); ok {
+				results[i] = BatchResult{Location: location}
+				continue
+			}
+		}
+		misses = append(misses, i)
+	}
+
+	for len(misses) > 0 {
+		chunk := misses[:min(len(misses), MaxBatchSize)]
+		misses = misses[len(chunk):]
+
+		req := batchRequest{IPs: make([]string, len(chunk))}
+		for j, i := range chunk {
+			req.IPs[j] = ips[i]
+		}
+		body, err := json.Marshal(req)
+		if err != nil {
+			return nil, err
+		}
+		var answers []BatchResult
+		if err := c.do(ctx, http.MethodPost, "/v1/find-country/batch", nil, body, &answers); err != nil {
+			return nil, err
+		}
+		if len(answers) != len(chunk) {
+			return nil, fmt.Errorf("torq: batch of %d addresses answered with %d results", len(chunk), len(answers))
+		}
+		for j, i := range chunk {
+			results[i] = answers[j]
+			if c.cache != nil && answers[j].Found() {
+				c.cache.put(ips[i], answers[j].Location, c.timeProvider())
+			}
+		}
+	}
+	return results, nil
+}
+
+// Usage reports the caller's quota consumption. Servers without quotas return an
+// error matching ErrNotFound.
+func (c *Client) Usage(ctx context.Context) (*Usage, error) {
+	var usage Usage
+	if err := c.do(ctx, http.MethodGet, "/v1/usage", nil, nil, &usage); err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// do sends a request, retrying it as the retry policy allows, and decodes a 200 OK
+// response into out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body []byte, out any) error {
+	u := *c.baseURL
+	u.Path += path
+	u.RawQuery = query.Encode()
+
+	for attempt := 1; ; attempt++ {
+		err := c.attempt(ctx, method, u.String(), body, out)
+		if err == nil {
+			return nil
+		}
+		delay, retry := c.retry.delay(attempt, err)
+		if !retry || ctx.Err() != nil {
+			return err
+		}
+		if sleepErr := c.sleep(ctx, delay); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+// attempt sends a request once
+func (c *Client) attempt(ctx context.Context, method, target string, body []byte, out any) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	for name, values := range c.header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		return parseError(resp, c.timeProvider())
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("torq: failed to decode response: %w", err)
+	}
+	return nil
+}
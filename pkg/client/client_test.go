@@ -0,0 +1,385 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shaibs3/Torq/internal/auth"
+	"github.com/shaibs3/Torq/internal/finder"
+	"github.com/shaibs3/Torq/internal/limiter"
+	"github.com/shaibs3/Torq/internal/router"
+	"github.com/shaibs3/Torq/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.uber.org/zap"
+)
+
+// testProvider finds the addresses of 10.0.0.0/8 in Paris and no others
+type testProvider struct{}
+
+func (testProvider) Lookup(ctx context.Context, ip string) (string, string, error) {
+	if strings.HasPrefix(ip, "10.") {
+		return "Paris", "France", nil
+	}
+	return "", "", fmt.Errorf("IP not found")
+}
+
+// allowAllLimiter never rejects a request
+type allowAllLimiter struct{}
+
+func (allowAllLimiter) Allow() bool { return true }
+
+func (allowAllLimiter) Decide() limiter.Decision {
+	return limiter.Decision{Allowed: true, Limit: 1, Remaining: 1}
+}
+
+func (l allowAllLimiter) DecideN(int) limiter.Decision { return l.Decide() }
+
+// serve starts the real router in front of testProvider and counts the requests it receives
+func serve(t *testing.T, rl limiter.RateLimiter, opts ...router.Option) (string, *atomic.Int64) {
+	t.Helper()
+	return servePolicies(t, limiter.NewPolicySet(limiter.NewDefaultPolicy(rl)), opts...)
+}
+
+// servePolicies is serve with rate limit policies
+func servePolicies(t *testing.T, policies *limiter.PolicySet, opts ...router.Option) (string, *atomic.Int64) {
+	t.Helper()
+	tel := &telemetry.Telemetry{Meter: noop.NewMeterProvider().Meter("test")}
+	opts = append(opts, router.WithAccessLog(router.AccessLogOff))
+	r := router.NewRouter(policies, tel, zap.NewNop(), opts...)
+	handler := r.CreateServer(":0", finder.NewIpFinder(testProvider{})).Handler
+
+	var requests atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		handler.ServeHTTP(w, req)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, &requests
+}
+
+// noSleep records the waits of a client instead of sleeping
+func noSleep(c *Client) *[]time.Duration {
+	var waits []time.Duration
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	return &waits
+}
+
+func TestNew(t *testing.T) {
+	for _, baseURL := range []string{"", "localhost:8080", "ftp://torq", "/v1"} {
+		_, err := New(baseURL)
+		assert.Error(t, err, baseURL)
+	}
+	c, err := New("http://torq.internal/api/?x=1")
+	require.NoError(t, err)
+	assert.Equal(t, "http://torq.internal/api", c.baseURL.String())
+}
+
+func TestLookup(t *testing.T) {
+	baseURL, _ := serve(t, allowAllLimiter{})
+	c, err := New(baseURL)
+	require.NoError(t, err)
+
+	location, err := c.Lookup(context.Background(), "10.1.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, &Location{IP: "10.1.2.3", City: "Paris", Country: "France"}, location)
+
+	_, err = c.Lookup(context.Background(), "8.8.8.8")
+	assert.ErrorIs(t, err, ErrNotFound)
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "not_found", apiErr.Code)
+	assert.Equal(t, "IP not found", apiErr.Description)
+	assert.NotEmpty(t, apiErr.RequestID)
+	assert.Equal(t, "torq: 404 Not Found: not_found: IP not found", err.Error())
+
+	_, err = c.Lookup(context.Background(), "not-an-ip")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	assert.NotErrorIs(t, err, ErrNotFound)
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "invalid_ip", apiErr.Code)
+}
+
+func TestLookupBatch(t *testing.T) {
+	baseURL, requests := serve(t, allowAllLimiter{})
+	c, err := New(baseURL)
+	require.NoError(t, err)
+
+	ips := make([]string, MaxBatchSize+500)
+	for i := range ips {
+		ips[i] = fmt.Sprintf("10.0.%d.%d", i/256, i%256)
+	}
+	ips[3], ips[MaxBatchSize+1] = "8.8.8.8", "bogus"
+
+	results, err := c.LookupBatch(context.Background(), ips)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, requests.Load(), "long lists are split into batches")
+	require.Len(t, results, len(ips))
+	for i, result := range results {
+		assert.Equal(t, ips[i], result.IP, "results are in request order")
+	}
+	assert.Equal(t, BatchResult{Location: Location{IP: "10.0.0.0", City: "Paris", Country: "France"}}, results[0])
+	assert.False(t, results[3].Found())
+	assert.Equal(t, "IP not found", results[3].Error)
+	assert.Contains(t, results[MaxBatchSize+1].Error, "invalid IP address")
+
+	results, err = c.LookupBatch(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	assert.EqualValues(t, 2, requests.Load(), "an empty list sends no request")
+}
+
+func TestRetry_RateLimited(t *testing.T) {
+	var mu sync.Mutex
+	now := time.Unix(0, 0)
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	baseURL, requests := serve(t, limiter.NewBurstRateLimiterWithTimeProvider(1, 1, zap.NewNop(), clock))
+
+	c, err := New(baseURL)
+	require.NoError(t, err)
+	var waits []time.Duration
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		mu.Lock()
+		now = now.Add(d)
+		mu.Unlock()
+		return nil
+	}
+
+	_, err = c.Lookup(context.Background(), "10.0.0.1")
+	require.NoError(t, err)
+	_, err = c.Lookup(context.Background(), "10.0.0.2")
+	require.NoError(t, err, "the rejected lookup is retried after Retry-After")
+	assert.Equal(t, []time.Duration{time.Second}, waits)
+	assert.EqualValues(t, 3, requests.Load())
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		c, err := New(baseURL, WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+		require.NoError(t, err)
+		waits := noSleep(c)
+		_, err = c.Lookup(context.Background(), "10.0.0.3")
+		assert.ErrorIs(t, err, ErrRateLimited)
+		var apiErr *Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, time.Second, apiErr.RetryAfter)
+		assert.Equal(t, "rate_limited", apiErr.Code)
+		assert.Empty(t, *waits)
+	})
+
+	t.Run("Retry-After beyond MaxDelay", func(t *testing.T) {
+		c, err := New(baseURL, WithRetryPolicy(RetryPolicy{MaxAttempts: 5, MaxDelay: 500 * time.Millisecond}))
+		require.NoError(t, err)
+		waits := noSleep(c)
+		_, err = c.Lookup(context.Background(), "10.0.0.4")
+		assert.ErrorIs(t, err, ErrRateLimited)
+		assert.Empty(t, *waits, "the client does not wait longer than MaxDelay")
+	})
+}
+
+func TestLookupBatch_LargerThanBurst(t *testing.T) {
+	batch := &limiter.Policy{
+		Name:       "batch",
+		PathPrefix: "/v1/find-country/batch",
+		Limiter:    limiter.NewBurstRateLimiter(1, 2, zap.NewNop()),
+		Cost:       router.CostFuncs()["batch_ips"],
+	}
+	baseURL, requests := servePolicies(t, limiter.NewPolicySet(limiter.NewDefaultPolicy(allowAllLimiter{}), batch))
+	c, err := New(baseURL)
+	require.NoError(t, err)
+	waits := noSleep(c)
+
+	_, err = c.LookupBatch(context.Background(), []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	assert.ErrorContains(t, err, "split it into smaller batches")
+	assert.Empty(t, *waits, "a batch that can never be admitted is not retried")
+	assert.EqualValues(t, 1, requests.Load())
+}
+
+func TestRetry_Unavailable(t *testing.T) {
+	var requests atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch requests.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", time.Unix(0, 0).Add(2*time.Second).UTC().Format(http.TimeFormat))
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			_, _ = w.Write([]byte(`{"ip": "10.0.0.1", "city": "Paris", "country": "France"}`))
+		}
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL, WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: 5 * time.Second}))
+	require.NoError(t, err)
+	c.timeProvider = func() time.Time { return time.Unix(0, 0) }
+	waits := noSleep(c)
+
+	location, err := c.Lookup(context.Background(), "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "Paris", location.City)
+	require.Len(t, *waits, 2)
+	assert.Equal(t, 2*time.Second, (*waits)[0], "an HTTP-date Retry-After is honoured")
+	assert.InDelta(t, 1500*time.Millisecond, (*waits)[1], float64(500*time.Millisecond), "without Retry-After the client backs off")
+}
+
+func TestRetry_ContextCancelled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = c.Lookup(ctx, "10.0.0.1")
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Less(t, time.Since(start), time.Second, "the wait ends with the context")
+}
+
+func TestAuth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf(`{"keys": [
+		{"id": "lookup-only", "key_hash": %q, "scopes": ["lookup"], "enabled": true}
+	]}`, auth.HashKey("lookup-secret"))), 0o600))
+	store, err := auth.NewFileKeyStore(path)
+	require.NoError(t, err)
+	authenticator, err := auth.NewAuthenticator(store, zap.NewNop())
+	require.NoError(t, err)
+	defer authenticator.Close() //nolint:errcheck
+	baseURL, _ := serve(t, allowAllLimiter{}, router.WithAuth(authenticator))
+
+	anonymous, err := New(baseURL)
+	require.NoError(t, err)
+	_, err = anonymous.Lookup(context.Background(), "10.0.0.1")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "unauthorized", apiErr.Code)
+	assert.Equal(t, "missing API key or bearer token", apiErr.Description)
+
+	for _, opt := range []Option{WithAPIKey("lookup-secret"), WithBearerToken("lookup-secret")} {
+		c, err := New(baseURL, opt)
+		require.NoError(t, err)
+		_, err = c.Lookup(context.Background(), "10.0.0.1")
+		require.NoError(t, err)
+		_, err = c.LookupBatch(context.Background(), []string{"10.0.0.1"})
+		assert.ErrorIs(t, err, ErrForbidden, "the key lacks the batch scope")
+		assert.NotErrorIs(t, err, ErrQuotaExceeded)
+	}
+}
+
+func TestQuotaExceeded(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error": "quota_exceeded", "usage": {"client_id": "key-1", "periods": {
+			"daily": {"used": 100, "limit": 100, "remaining": 0, "resets_at": "2024-01-02T00:00:00Z"}}}}`))
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL)
+	require.NoError(t, err)
+	_, err = c.Lookup(context.Background(), "10.0.0.1")
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.NotErrorIs(t, err, ErrForbidden)
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	require.NotNil(t, apiErr.Usage)
+	assert.Equal(t, "key-1", apiErr.Usage.ClientID)
+	assert.EqualValues(t, 100, apiErr.Usage.Periods["daily"].Limit)
+}
+
+func TestUsage_QuotasDisabled(t *testing.T) {
+	baseURL, _ := serve(t, allowAllLimiter{})
+	c, err := New(baseURL)
+	require.NoError(t, err)
+	_, err = c.Usage(context.Background())
+	assert.ErrorIs(t, err, ErrNotFound)
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "quotas_disabled", apiErr.Code)
+}
+
+func TestCache(t *testing.T) {
+	baseURL, requests := serve(t, allowAllLimiter{})
+	c, err := New(baseURL, WithCache(2, time.Minute))
+	require.NoError(t, err)
+	now := time.Unix(0, 0)
+	c.timeProvider = func() time.Time { return now }
+
+	for range 3 {
+		_, err := c.Lookup(context.Background(), "10.0.0.1")
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 1, requests.Load())
+
+	for range 2 {
+		_, err := c.Lookup(context.Background(), "8.8.8.8")
+		assert.True(t, errors.Is(err, ErrNotFound))
+	}
+	assert.EqualValues(t, 3, requests.Load(), "addresses that were not found are not cached")
+
+	results, err := c.LookupBatch(context.Background(), []string{"10.0.0.1", "10.0.0.2"})
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.2", results[1].IP)
+	assert.EqualValues(t, 4, requests.Load())
+	_, err = c.LookupBatch(context.Background(), []string{"10.0.0.2", "10.0.0.1"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, requests.Load(), "a batch of cached addresses sends no request")
+
+	now = now.Add(2 * time.Minute)
+	_, err = c.Lookup(context.Background(), "10.0.0.1")
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, requests.Load(), "entries expire after the ttl")
+}
+
+func TestParseError_PlainText(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusBadGateway,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader("upstream connect error\n")),
+	}
+	apiErr := parseError(resp, time.Now())
+	assert.ErrorIs(t, apiErr, ErrUnavailable)
+	assert.Empty(t, apiErr.Code, "a proxy's text is not an error code")
+	assert.Equal(t, "upstream connect error", apiErr.Description)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"3":                             3 * time.Second,
+		"-1":                            0,
+		"soon":                          0,
+		"Mon, 01 Jan 2024 00:00:30 GMT": 30 * time.Second,
+		"Sun, 31 Dec 2023 23:59:00 GMT": 0,
+	}
+	for value, want := range tests {
+		assert.Equal(t, want, parseRetryAfter(value, now), value)
+	}
+}
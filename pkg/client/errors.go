@@ -0,0 +1,127 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors matched by an *Error with errors.Is, by response status
+var (
+	// ErrInvalidRequest matches 400 and 413 responses, such as an invalid IP address or
+	// a batch larger than the server's rate limit admits at once
+	ErrInvalidRequest = errors.New("invalid request")
+	// ErrUnauthorized matches 401 responses: a missing, unknown or disabled credential
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden matches 403 responses other than an exceeded quota, such as a missing scope
+	ErrForbidden = errors.New("forbidden")
+	// ErrQuotaExceeded matches 403 responses of a client whose quota is used up
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrNotFound matches 404 responses: the IP address is not in the dataset
+	ErrNotFound = errors.New("not found")
+	// ErrRateLimited matches 429 responses that were still rejected after the retries
+	ErrRateLimited = errors.New("rate limited")
+	// ErrUnavailable matches 502, 503 and 504 responses
+	ErrUnavailable = errors.New("service unavailable")
+)
+
+// quotaExceededCode is the error field of a 403 response of a client over quota
+const quotaExceededCode = "quota_exceeded"
+
+// maxErrorBodyBytes bounds how much of an error response is read
+const maxErrorBodyBytes = 64 << 10
+
+// Error is a response the server rejected, with the fields of its error body
+type Error struct {
+	StatusCode int
+	// Code is the body's machine-readable error field, e.g. "not_found", "invalid_token"
+	// or "rate_limited". It is empty when the response has no JSON error body.
+	Code string
+	// Description is the body's human-readable error_description field. Responses
+	// without a JSON error body, such as those of a proxy in front of the server,
+	// carry their text here instead.
+	Description string
+	// RequestID is the X-Request-ID of the response, to quote when reporting a problem
+	RequestID string
+	// RetryAfter is how long the server asked the client to wait, from Retry-After
+	RetryAfter time.Duration
+	// Usage is the client's quota consumption, set when the quota is exceeded
+	Usage *Usage
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("torq: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Code != "" {
+		msg += ": " + e.Code
+	}
+	if e.Description != "" {
+		msg += ": " + e.Description
+	}
+	return msg
+}
+
+// Is matches the sentinel errors of the response status
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrInvalidRequest:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusRequestEntityTooLarge
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden && e.Code != quotaExceededCode
+	case ErrQuotaExceeded:
+		return e.StatusCode == http.StatusForbidden && e.Code == quotaExceededCode
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrUnavailable:
+		return e.StatusCode == http.StatusBadGateway || e.StatusCode == http.StatusServiceUnavailable ||
+			e.StatusCode == http.StatusGatewayTimeout
+	}
+	return false
+}
+
+// errorBody is the JSON error body of the API
+type errorBody struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+	Usage       *Usage `json:"usage"`
+}
+
+// parseError reads the error of a response that is not 200 OK
+func parseError(resp *http.Response, now time.Time) *Error {
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("X-Request-ID"),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), now),
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+	var parsed errorBody
+	if err := json.Unmarshal(body, &parsed); err == nil && parsed.Error != "" {
+		e.Code, e.Description, e.Usage = parsed.Error, parsed.Description, parsed.Usage
+	} else {
+		// Proxies in front of the server may answer in plain text
+		e.Description = strings.TrimSpace(string(body))
+	}
+	return e
+}
+
+// parseRetryAfter reads a Retry-After header in delta-seconds or HTTP-date form
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+	return 0
+}
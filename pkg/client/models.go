@@ -0,0 +1,48 @@
+package client
+
+import "time"
+
+// MaxBatchSize is the number of IP addresses the server accepts in one batch request.
+// LookupBatch splits longer lists into requests of this size.
+const MaxBatchSize = 1000
+
+// Location is where the server places an IP address
+type Location struct {
+	IP      string `json:"ip"`
+	City    string `json:"city"`
+	Country string `json:"country"`
+	// Override is set when a local override, rather than the IP database, answered
+	Override bool `json:"override,omitempty"`
+}
+
+// BatchResult is the answer for one address of a batch. Addresses that are invalid or
+// not found carry an Error instead of failing the whole batch.
+type BatchResult struct {
+	Location
+	Error string `json:"error,omitempty"`
+}
+
+// Found reports whether the address was located
+func (r BatchResult) Found() bool {
+	return r.Error == ""
+}
+
+// batchRequest is the body of a batch request
+type batchRequest struct {
+	IPs []string `json:"ips"`
+}
+
+// PeriodUsage is a client's consumption against the quota of one period
+type PeriodUsage struct {
+	Used      int64     `json:"used"`
+	Limit     int64     `json:"limit"`
+	Remaining int64     `json:"remaining"`
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+// Usage reports a client's consumption for every quota period with a limit, keyed by
+// period name such as "daily" or "monthly"
+type Usage struct {
+	ClientID string                 `json:"client_id"`
+	Periods  map[string]PeriodUsage `json:"periods"`
+}
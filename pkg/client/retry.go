@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/url"
+	"time"
+)
+
+// RetryPolicy decides how rate-limited (429), unavailable (502, 503 and 504) and
+// failed requests are retried. The wait before a retry is the response's Retry-After
+// when it has one, and otherwise an exponential backoff with jitter.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first; 1 disables retries
+	MaxAttempts int
+	// BaseDelay is the backoff before the first retry, doubled for each later one
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff. A Retry-After longer than MaxDelay is not waited for;
+	// the error is returned with its RetryAfter set.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy makes up to three attempts and waits at most 10s between them
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: 10 * time.Second}
+
+// delay reports whether the attempt that failed with err should be retried, and after
+// how long. Errors without a response, such as refused connections and attempts that
+// timed out, are retried with backoff; the caller checks its own context.
+func (p RetryPolicy) delay(attempt int, err error) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts {
+		return 0, false
+	}
+	var apiErr *Error
+	var urlErr *url.Error
+	switch {
+	case errors.As(err, &apiErr):
+		if !errors.Is(apiErr, ErrRateLimited) && !errors.Is(apiErr, ErrUnavailable) {
+			return 0, false
+		}
+		if apiErr.RetryAfter > 0 {
+			return apiErr.RetryAfter, apiErr.RetryAfter <= p.MaxDelay
+		}
+	case !errors.As(err, &urlErr):
+		// A response that could not be decoded would fail again
+		return 0, false
+	}
+	return p.backoff(attempt), true
+}
+
+// backoff waits between half and all of BaseDelay doubled for each retry, capped at MaxDelay
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	d = min(d, p.MaxDelay)
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// sleepContext waits for d, or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}